@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"go.uber.org/zap"
+)
+
+// runActivityReport counts active users from the recorded seat activity
+func runActivityReport(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("activity-report", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First date to include (YYYY-MM-DD, default: 28 days ago)")
+	to := flags.String("to", "", "Last date to include (YYYY-MM-DD, default: today)")
+	granularity := flags.String("granularity", "day", "Period to count active users over: day or week")
+	by := flags.String("by", "team", "Report active users by team or by user")
+	teams := flags.String("teams", "membership", "Attribute activity to the teams users are members of (membership) or to the seat's assigning team (assigning)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	periodGranularity, err := analysis.ParseGranularity(*granularity)
+	if err != nil {
+		return err
+	}
+
+	if *by != "team" && *by != "user" {
+		return fmt.Errorf("unsupported grouping: %s", *by)
+	}
+
+	if *teams != "membership" && *teams != "assigning" {
+		return fmt.Errorf("unsupported team attribution: %s", *teams)
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 28)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	activity, err := repo.GetSeatActivity(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load seat activity: %w", err)
+	}
+
+	var resolver *analysis.TeamResolver
+	if *teams == "membership" {
+		// The memberships in effect on the first days come from before the range
+		memberships, err := latestSnapshotsBefore(ctx, fromDate, repo.GetTeamMemberships,
+			func(snapshot *models.TeamMemberships) string { return snapshot.Date })
+		if err != nil {
+			return fmt.Errorf("failed to load team memberships: %w", err)
+		}
+		inRange, err := repo.GetTeamMemberships(ctx, fromDate, toDate)
+		if err != nil {
+			return fmt.Errorf("failed to load team memberships: %w", err)
+		}
+		memberships = append(memberships, inRange...)
+		if len(memberships) == 0 {
+			logger.Warn("No team memberships stored for the date range, attributing activity to assigning teams")
+		}
+		resolver = analysis.NewTeamResolver(memberships)
+	}
+
+	teamCounts, users := analysis.CountActiveUsers(activity, periodGranularity, resolver)
+
+	if *by == "user" {
+		table := reports.NewTable("login", "organization", "assigning_team", "teams", "active_"+*granularity+"s", "last_activity_at", "editors")
+		for _, user := range users {
+			table.AddRow(user.Login, user.Organization, user.Team, strings.Join(user.Teams, ";"), strconv.Itoa(user.ActivePeriods),
+				user.LastActivityAt.Format("2006-01-02T15:04:05Z"), strings.Join(user.Editors, ";"))
+		}
+		return reports.Write(os.Stdout, outputFormat, table, users)
+	}
+
+	table := reports.NewTable("period", "team", "active_users")
+	for _, count := range teamCounts {
+		table.AddRow(count.Period, count.Team, strconv.Itoa(count.ActiveUsers))
+	}
+	return reports.Write(os.Stdout, outputFormat, table, teamCounts)
+}
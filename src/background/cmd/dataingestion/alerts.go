@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/alerting"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// newAlertEngine creates the alerting engine from the configured rules file
+func newAlertEngine(cfg *config.Config, repo repositories.Repository, logger *zap.Logger) (*alerting.Engine, error) {
+	if cfg.AlertRulesFile == "" {
+		return nil, fmt.Errorf("ALERT_RULES_FILE is not set")
+	}
+
+	alertCfg, err := alerting.LoadConfig(cfg.AlertRulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return alerting.NewEngine(alertCfg, repo, analysis.SeatUtilizationOptions{
+		InactiveDays: cfg.SeatInactiveDays,
+		Prices:       cfg.SeatPrices,
+	}, logger)
+}
+
+// runAlerts dispatches the alerts subcommands
+func runAlerts(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	action := "list"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "list":
+		return runAlertsList(ctx, cfg, logger, args)
+	case "evaluate":
+		return runAlertsEvaluate(ctx, cfg, logger, args)
+	case "test":
+		return runAlertsTest(ctx, cfg, logger, args)
+	case "sink":
+		return runAlertsSink(ctx, args)
+	default:
+		return fmt.Errorf("unknown alerts action: %s (expected list, evaluate, test or sink)", action)
+	}
+}
+
+// runAlertsList lists the stored alerts
+func runAlertsList(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("alerts list", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First day alerts started firing (YYYY-MM-DD, default: 28 days ago)")
+	to := flags.String("to", "", "Last day alerts started firing (YYYY-MM-DD, default: today)")
+	status := flags.String("status", "", "Only list alerts with this status: firing or resolved")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 28)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	alerts, err := repo.GetAlerts(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load alerts: %w", err)
+	}
+
+	filtered := []models.Alert{}
+	table := reports.NewTable("fired_at", "resolved_at", "rule", "severity", "status", "subject", "value", "threshold", "notifications", "message")
+	for _, alert := range alerts {
+		if *status != "" && string(alert.Status) != *status {
+			continue
+		}
+
+		filtered = append(filtered, alert)
+		resolvedAt := ""
+		if alert.ResolvedAt != nil {
+			resolvedAt = alert.ResolvedAt.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		table.AddRow(alert.FiredAt.UTC().Format("2006-01-02T15:04:05Z"), resolvedAt, alert.Rule, alert.Severity,
+			string(alert.Status), alert.Subject, strconv.FormatFloat(alert.Value, 'f', -1, 64),
+			strconv.FormatFloat(alert.Threshold, 'f', -1, 64), strconv.Itoa(alert.Notifications), alert.Message)
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, filtered)
+}
+
+// runAlertsEvaluate evaluates the data rules once against the stored data and
+// sends the resulting notifications
+func runAlertsEvaluate(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("alerts evaluate", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	engine, err := newAlertEngine(cfg, repo, logger)
+	if err != nil {
+		return err
+	}
+	return engine.Evaluate(ctx)
+}
+
+// runAlertsTest sends a sample notification to the configured channels
+func runAlertsTest(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("alerts test", flag.ContinueOnError)
+	channel := flags.String("channel", "", "Channel to notify (default: every channel)")
+	status := flags.String("status", "firing", "Status of the sample notification: firing or resolved")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *status != string(models.AlertFiring) && *status != string(models.AlertResolved) {
+		return fmt.Errorf("unsupported status: %s", *status)
+	}
+
+	// Test notifications never touch the repository
+	engine, err := newAlertEngine(cfg, nil, logger)
+	if err != nil {
+		return err
+	}
+	if err := engine.SendTest(ctx, *channel, models.AlertStatus(*status)); err != nil {
+		return err
+	}
+
+	logger.Info("Test notification sent", zap.String("channel", *channel), zap.String("status", *status))
+	return nil
+}
+
+// runAlertsSink runs a local HTTP endpoint that prints every payload it
+// receives, to try channels without a real webhook
+func runAlertsSink(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("alerts sink", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:8089", "Address to listen on")
+	status := flags.Int("status", http.StatusOK, "HTTP status to answer with, e.g. 500 to try failed deliveries")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			fmt.Printf("%s %s %s\n", time.Now().UTC().Format(time.RFC3339), r.Method, r.URL.Path)
+
+			var payload interface{}
+			if err := json.Unmarshal(body, &payload); err == nil {
+				pretty, _ := json.MarshalIndent(payload, "", "  ")
+				body = pretty
+			}
+			fmt.Println(string(body))
+			w.WriteHeader(*status)
+		}),
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", *addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/anomaly"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"go.uber.org/zap"
+)
+
+// newAnomalyDetector creates the anomaly detector from the configuration
+func newAnomalyDetector(cfg *config.Config) *anomaly.Detector {
+	opts := anomaly.DefaultOptions()
+	opts.BaselineWeeks = cfg.AnomalyBaselineWeeks
+	opts.WarningScore = cfg.AnomalyWarningScore
+	opts.CriticalScore = cfg.AnomalyCriticalScore
+	opts.MinChange = cfg.AnomalyMinChange
+	return anomaly.NewDetector(opts)
+}
+
+// runAnomalies lists the stored anomalies, or detects them again over a date range
+func runAnomalies(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("anomalies", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First date to include (YYYY-MM-DD, default: 28 days ago)")
+	to := flags.String("to", "", "Last date to include (YYYY-MM-DD, default: today)")
+	severity := flags.String("severity", "", "Only list anomalies of this severity: warning or critical")
+	direction := flags.String("direction", "", "Only list anomalies in this direction: drop or spike")
+	team := flags.String("team", "", "Only list anomalies of this team")
+	detect := flags.Bool("detect", false, "Detect anomalies over the date range from the stored metrics and save them, e.g. to backfill")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	if *severity != "" && *severity != anomaly.SeverityWarning && *severity != anomaly.SeverityCritical {
+		return fmt.Errorf("unsupported severity: %s", *severity)
+	}
+	if *direction != "" && *direction != anomaly.DirectionDrop && *direction != anomaly.DirectionSpike {
+		return fmt.Errorf("unsupported direction: %s", *direction)
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 28)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	var anomalies []models.Anomaly
+	if *detect {
+		anomalies, err = newAnomalyDetector(cfg).DetectStored(ctx, repo, fromDate, toDate, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if len(anomalies) > 0 {
+			if err := repo.SaveAnomalies(ctx, anomalies); err != nil {
+				return fmt.Errorf("failed to save anomalies: %w", err)
+			}
+		}
+	} else {
+		anomalies, err = repo.GetAnomalies(ctx, fromDate, toDate)
+		if err != nil {
+			return fmt.Errorf("failed to load anomalies: %w", err)
+		}
+	}
+
+	filtered := []models.Anomaly{}
+	table := reports.NewTable("date", "scope", "team", "editor", "metric", "direction", "severity", "value", "baseline", "score")
+	for _, a := range anomalies {
+		if *severity != "" && a.Severity != *severity {
+			continue
+		}
+		if *direction != "" && a.Direction != *direction {
+			continue
+		}
+		if *team != "" && a.Team != *team {
+			continue
+		}
+
+		filtered = append(filtered, a)
+		scope := a.Organization
+		if scope == "" {
+			scope = a.Enterprise
+		}
+		table.AddRow(a.Date, scope, a.Team, a.Editor, a.Metric, a.Direction, a.Severity,
+			strconv.FormatFloat(a.Value, 'f', -1, 64), strconv.FormatFloat(a.Baseline, 'f', -1, 64),
+			strconv.FormatFloat(a.Score, 'f', 2, 64))
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, filtered)
+}
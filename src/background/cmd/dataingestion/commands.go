@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// command describes a CLI subcommand of the data ingestion binary
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error
+}
+
+// commands lists the available subcommands. Running the binary without a
+// subcommand starts the ingestion service.
+var commands = []command{
+	{
+		name:        "seats-report",
+		description: "Report seat utilization and the monthly cost of idle seats",
+		run:         runSeatsReport,
+	},
+	{
+		name:        "seat-events",
+		description: "List seat assignment changes (added, removed, plan and team changes)",
+		run:         runSeatEvents,
+	},
+	{
+		name:        "cost-report",
+		description: "Report daily or monthly seat spend by organization and team",
+		run:         runCostReport,
+	},
+	{
+		name:        "activity-report",
+		description: "Report daily or weekly active users per team and per person from seat activity",
+		run:         runActivityReport,
+	},
+	{
+		name:        "export",
+		description: "Export stored metrics, usage, seats or seat events as CSV or NDJSON",
+		run:         runExport,
+	},
+	{
+		name:        "reprocess",
+		description: "Rebuild metrics and usage from archived GitHub API responses",
+		run:         runReprocess,
+	},
+	{
+		name:        "data-quality",
+		description: "Report data-quality rule violations and quarantined documents",
+		run:         runDataQuality,
+	},
+	{
+		name:        "reconcile",
+		description: "Compare team metrics with their organization/enterprise totals per day and surface",
+		run:         runReconcile,
+	},
+	{
+		name:        "anomalies",
+		description: "List anomalies detected in the daily metrics, or detect them over a date range",
+		run:         runAnomalies,
+	},
+	{
+		name:        "alerts",
+		description: "List, evaluate or test alerts: alerts [list|evaluate|test|sink] [flags]",
+		run:         runAlerts,
+	},
+	{
+		name:        "digest",
+		description: "Preview or send the weekly adoption digest: digest [preview|send|sink] [flags]",
+		run:         runDigest,
+	},
+	{
+		name:        "rollups",
+		description: "Write the weekly and monthly rollups of the stored metrics, e.g. to backfill them",
+		run:         runRollups,
+	},
+	{
+		name:        "runs",
+		description: "List ingestion runs or show the latest run of each job: runs [list|latest] [flags]",
+		run:         runRuns,
+	},
+	{
+		name:        "consistency",
+		description: "Compare the documents stored in each backend of fan-out storage",
+		run:         runConsistency,
+	},
+	{
+		name:        "migrate-data",
+		description: "Copy metrics, usage and seats between repositories: migrate-data -from sqlite:<path> -to cosmos[:<endpoint>]",
+		run:         runMigrateData,
+	},
+}
+
+// runCommand dispatches to the named subcommand
+func runCommand(ctx context.Context, cfg *config.Config, logger *zap.Logger, name string, args []string) error {
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return nil
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(ctx, cfg, logger, args)
+		}
+	}
+
+	printUsage()
+	return fmt.Errorf("unknown command: %s", name)
+}
+
+// printUsage prints the list of available subcommands
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: dataingestion [command] [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Without a command the ingestion service is started.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.description)
+	}
+}
+
+// openRepository creates and initializes the configured repository for use by a command
+func openRepository(cfg *config.Config, logger *zap.Logger) (repositories.Repository, error) {
+	repo, err := repositories.CreateRepository(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	if repo == nil {
+		return nil, fmt.Errorf("no repository configured")
+	}
+	return repo, nil
+}
+
+// dateLayout is the layout of the dates used to key stored documents
+const dateLayout = "2006-01-02"
+
+// resolveDateRange validates a from/to date range, defaulting to the given
+// number of days ending today
+func resolveDateRange(from, to string, defaultDays int) (string, string, error) {
+	if to == "" {
+		to = time.Now().UTC().Format(dateLayout)
+	}
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+	}
+
+	if from == "" {
+		from = toDate.AddDate(0, 0, -(defaultDays - 1)).Format(dateLayout)
+	}
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+
+	if fromDate.After(toDate) {
+		return "", "", fmt.Errorf("from date %s is after to date %s", from, to)
+	}
+
+	return from, to, nil
+}
+
+const (
+	// snapshotLookbackDays bounds how far before a date range the snapshot in
+	// effect at its start is searched for
+	snapshotLookbackDays = 366
+	// snapshotLookbackWindow is the number of days loaded at a time while
+	// searching for that snapshot
+	snapshotLookbackWindow = 31
+)
+
+// latestSnapshotsBefore returns the snapshots of the latest date before a
+// date, such as the seats or team memberships in effect when a date range
+// starts. Earlier dates are loaded window by window, up to a year back.
+func latestSnapshotsBefore[T any](ctx context.Context, date string, load func(ctx context.Context, from, to string) ([]T, error),
+	snapshotDate func(snapshot *T) string) ([]T, error) {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+
+	for searched := 0; searched < snapshotLookbackDays; searched += snapshotLookbackWindow {
+		from := day.AddDate(0, 0, -(searched + snapshotLookbackWindow)).Format(dateLayout)
+		to := day.AddDate(0, 0, -(searched + 1)).Format(dateLayout)
+		snapshots, err := load(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		latestDate := ""
+		for i := range snapshots {
+			latestDate = max(latestDate, snapshotDate(&snapshots[i]))
+		}
+		if latestDate == "" {
+			continue
+		}
+
+		var latest []T
+		for i := range snapshots {
+			if snapshotDate(&snapshots[i]) == latestDate {
+				latest = append(latest, snapshots[i])
+			}
+		}
+		return latest, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// documentKind lists the IDs of the stored documents of a kind
+type documentKind struct {
+	name string
+	ids  func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error)
+}
+
+// documentKinds lists the document kinds compared across storage backends
+var documentKinds = []documentKind{
+	{"metrics", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetMetrics(ctx, from, to)
+		return documentIDs(documents, func(m *models.Metrics) (string, string) { return m.ID, m.GetID() }), err
+	}},
+	{"usage", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetUsage(ctx, from, to)
+		return documentIDs(documents, func(u *models.CopilotUsage) (string, string) { return u.ID, u.GetID() }), err
+	}},
+	{"seats", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetSeatsHistory(ctx, from, to)
+		return documentIDs(documents, func(s *models.CopilotAssignedSeats) (string, string) { return s.ID, s.GetID() }), err
+	}},
+	{"team_memberships", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetTeamMemberships(ctx, from, to)
+		return documentIDs(documents, func(t *models.TeamMemberships) (string, string) { return t.ID, t.GetID() }), err
+	}},
+	{"seat_events", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetSeatEvents(ctx, from, to)
+		return documentIDs(documents, func(e *models.SeatEvent) (string, string) { return e.ID, e.GetID() }), err
+	}},
+	{"seat_activity", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetSeatActivity(ctx, from, to)
+		return documentIDs(documents, func(a *models.SeatActivity) (string, string) { return a.ID, a.GetID() }), err
+	}},
+	{"metrics_markers", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetMetricsMarkers(ctx, from, to)
+		return documentIDs(documents, func(m *models.MetricsMarker) (string, string) { return m.ID, m.GetID() }), err
+	}},
+	{"anomalies", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetAnomalies(ctx, from, to)
+		return documentIDs(documents, func(a *models.Anomaly) (string, string) { return a.ID, a.GetID() }), err
+	}},
+	{"alerts", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetAlerts(ctx, from, to)
+		return documentIDs(documents, func(a *models.Alert) (string, string) { return a.ID, a.GetID() }), err
+	}},
+	{"ingestion_runs", func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+		documents, err := repo.GetIngestionRuns(ctx, from, to)
+		return documentIDs(documents, func(r *models.IngestionRun) (string, string) { return r.ID, r.GetID() }), err
+	}},
+}
+
+// documentIDs returns the stored ID of each document, or the generated one
+// for documents stored without it
+func documentIDs[T any](documents []T, id func(document *T) (stored, generated string)) []string {
+	ids := make([]string, len(documents))
+	for i := range documents {
+		stored, generated := id(&documents[i])
+		if stored == "" {
+			stored = generated
+		}
+		ids[i] = stored
+	}
+	return ids
+}
+
+// consistencyEntry is the comparison of the documents of a kind stored in a backend
+type consistencyEntry struct {
+	Kind      string   `json:"kind"`
+	Backend   string   `json:"backend"`
+	Documents int      `json:"documents"`
+	Missing   []string `json:"missing"` // Documents stored in another backend only
+}
+
+// runConsistency compares the documents stored in each backend of fan-out storage
+func runConsistency(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("consistency", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First date to compare (YYYY-MM-DD, default: 7 days ago)")
+	to := flags.String("to", "", "Last date to compare (YYYY-MM-DD, default: today)")
+	kindsFlag := flags.String("kinds", "", "Comma-separated document kinds to compare (default: all)")
+	sample := flags.Int("sample", 5, "Number of missing document IDs listed per backend in table and CSV output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	kinds, err := selectDocumentKinds(*kindsFlag)
+	if err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 7)
+	if err != nil {
+		return err
+	}
+
+	if cfg.StorageType != config.StorageFanout {
+		return fmt.Errorf("the consistency check compares fan-out storage backends, set STORAGE_TYPE=fanout")
+	}
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	backends := repo.(*repositories.FanoutRepository).Backends()
+
+	entries := []consistencyEntry{}
+	table := reports.NewTable("kind", "backend", "documents", "missing", "missing_ids")
+	inconsistent := 0
+	for _, kind := range kinds {
+		stored := make([]map[string]bool, len(backends))
+		union := make(map[string]bool)
+		for i, backend := range backends {
+			ids, err := kind.ids(ctx, backend.Repository, fromDate, toDate)
+			if err != nil {
+				return fmt.Errorf("failed to load %s from %s: %w", kind.name, backend.Name, err)
+			}
+			stored[i] = make(map[string]bool, len(ids))
+			for _, id := range ids {
+				stored[i][id] = true
+				union[id] = true
+			}
+		}
+
+		for i, backend := range backends {
+			entry := consistencyEntry{Kind: kind.name, Backend: backend.Name, Documents: len(stored[i]), Missing: []string{}}
+			for id := range union {
+				if !stored[i][id] {
+					entry.Missing = append(entry.Missing, id)
+				}
+			}
+			sort.Strings(entry.Missing)
+			inconsistent += len(entry.Missing)
+
+			entries = append(entries, entry)
+			table.AddRow(entry.Kind, entry.Backend, strconv.Itoa(entry.Documents), strconv.Itoa(len(entry.Missing)),
+				strings.Join(entry.Missing[:min(*sample, len(entry.Missing))], " "))
+		}
+	}
+
+	if err := reports.Write(os.Stdout, outputFormat, table, entries); err != nil {
+		return err
+	}
+	if inconsistent > 0 {
+		return fmt.Errorf("found %d documents missing from a storage backend", inconsistent)
+	}
+	return nil
+}
+
+// selectDocumentKinds returns the document kinds named in a comma-separated list, or all of them
+func selectDocumentKinds(list string) ([]documentKind, error) {
+	if list == "" {
+		return documentKinds, nil
+	}
+
+	var kinds []documentKind
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, kind := range documentKinds {
+			if kind.name == name {
+				kinds = append(kinds, kind)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported document kind: %s", name)
+		}
+	}
+	return kinds, nil
+}
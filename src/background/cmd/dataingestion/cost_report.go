@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"go.uber.org/zap"
+)
+
+// runCostReport computes seat spend from the stored seats history
+func runCostReport(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("cost-report", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First date to include (YYYY-MM-DD, default: 30 days ago)")
+	to := flags.String("to", "", "Last date to include (YYYY-MM-DD, default: today)")
+	period := flags.String("period", "monthly", "Period to report: daily or monthly")
+	level := flags.String("level", "", "Only report one level: total, organization or team")
+	proration := flags.String("proration", cfg.SeatProration, "Proration rule: daily or full_month")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	prorationRule, err := analysis.ParseProrationRule(*proration)
+	if err != nil {
+		return err
+	}
+
+	if *period != "daily" && *period != "monthly" {
+		return fmt.Errorf("unsupported period: %s", *period)
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 30)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	// The snapshot in effect on the first days comes from before the range
+	history, err := latestSnapshotsBefore(ctx, fromDate, repo.GetSeatsHistory,
+		func(snapshot *models.CopilotAssignedSeats) string { return snapshot.Date })
+	if err != nil {
+		return fmt.Errorf("failed to load seats history: %w", err)
+	}
+	inRange, err := repo.GetSeatsHistory(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load seats history: %w", err)
+	}
+	history = append(history, inRange...)
+
+	metrics, err := repo.GetMetrics(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load metrics: %w", err)
+	}
+
+	report, err := analysis.ComputeSeatCosts(history, metrics, fromDate, toDate, analysis.CostOptions{
+		Prices:    cfg.SeatPrices,
+		Proration: prorationRule,
+	})
+	if err != nil {
+		return err
+	}
+
+	entries := report.Monthly
+	if *period == "daily" {
+		entries = report.Daily
+	}
+
+	filtered := []analysis.CostEntry{}
+	table := reports.NewTable("period", "level", "organization", "team", "seats", "spend", "engaged_users", "cost_per_engaged_user")
+	for _, entry := range entries {
+		if *level != "" && string(entry.Level) != *level {
+			continue
+		}
+
+		filtered = append(filtered, entry)
+		costPerUser := ""
+		if entry.CostPerEngagedUser != nil {
+			costPerUser = formatAmount(*entry.CostPerEngagedUser)
+		}
+		table.AddRow(entry.Period, string(entry.Level), entry.Organization, entry.Team,
+			strconv.Itoa(entry.Seats), formatAmount(entry.Spend),
+			strconv.FormatFloat(entry.EngagedUsers, 'f', 1, 64), costPerUser)
+	}
+
+	if outputFormat == reports.FormatJSON {
+		if *period == "daily" {
+			report.Daily, report.Monthly = filtered, nil
+		} else {
+			report.Daily, report.Monthly = nil, filtered
+		}
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, report)
+}
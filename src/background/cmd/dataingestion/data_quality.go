@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/validation"
+	"go.uber.org/zap"
+)
+
+// newValidator creates the data-quality validator from the configuration.
+// Severities are checked when the configuration is loaded.
+func newValidator(cfg *config.Config) *validation.Validator {
+	opts := validation.Options{
+		DisabledRules:      make(map[string]bool),
+		Severities:         make(map[string]validation.Severity),
+		QuarantineSeverity: validation.Severity(cfg.ValidationQuarantine),
+	}
+	for _, rule := range cfg.ValidationDisabled {
+		opts.DisabledRules[rule] = true
+	}
+	for rule, severity := range cfg.ValidationSeverities {
+		opts.Severities[rule] = validation.Severity(severity)
+	}
+	return validation.NewValidator(opts)
+}
+
+// dataQualitySummary counts the violations of a rule
+type dataQualitySummary struct {
+	Rule        string `json:"rule"`
+	Severity    string `json:"severity"`
+	Violations  int    `json:"violations"`
+	Documents   int    `json:"documents"`
+	Quarantined int    `json:"quarantined"`
+}
+
+// runDataQuality reports the data-quality violations and quarantined
+// documents stored for a date range
+func runDataQuality(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("data-quality", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First date to include (YYYY-MM-DD, default: 7 days ago)")
+	to := flags.String("to", "", "Last date to include (YYYY-MM-DD, default: today)")
+	view := flags.String("view", "summary", "Report to show: summary, violations or quarantined")
+	severity := flags.String("severity", "info", "Only include violations of at least this severity: info, warning or error")
+	rule := flags.String("rule", "", "Only include violations of this rule")
+	listRules := flags.Bool("rules", false, "List the data-quality rules and their configured severity")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	if *listRules {
+		return writeRules(cfg, outputFormat)
+	}
+
+	minSeverity, err := validation.ParseSeverity(*severity)
+	if err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 7)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	if *view == "quarantined" {
+		documents, err := repo.GetQuarantinedDocuments(ctx, fromDate, toDate)
+		if err != nil {
+			return fmt.Errorf("failed to load quarantined documents: %w", err)
+		}
+
+		filtered := []models.QuarantinedDocument{}
+		table := reports.NewTable("date", "quarantined_at", "kind", "document_id", "organization", "team", "rules")
+		for _, document := range documents {
+			if *rule != "" && !containsString(document.Rules, *rule) {
+				continue
+			}
+			filtered = append(filtered, document)
+			table.AddRow(document.Date, document.QuarantinedAt.UTC().Format("2006-01-02T15:04:05Z"), document.DocumentKind,
+				document.DocumentID, document.Organization, document.Team, strings.Join(document.Rules, " "))
+		}
+		return reports.Write(os.Stdout, outputFormat, table, filtered)
+	}
+
+	violations, err := repo.GetViolations(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load violations: %w", err)
+	}
+
+	filtered := []models.Violation{}
+	for _, violation := range violations {
+		if !validation.Severity(violation.Severity).AtLeast(minSeverity) {
+			continue
+		}
+		if *rule != "" && violation.Rule != *rule {
+			continue
+		}
+		filtered = append(filtered, violation)
+	}
+
+	switch *view {
+	case "violations":
+		table := reports.NewTable("date", "rule", "severity", "kind", "document_id", "organization", "team", "quarantined", "message")
+		for _, violation := range filtered {
+			table.AddRow(violation.Date, violation.Rule, violation.Severity, violation.DocumentKind, violation.DocumentID,
+				violation.Organization, violation.Team, strconv.FormatBool(violation.Quarantined), violation.Message)
+		}
+		return reports.Write(os.Stdout, outputFormat, table, filtered)
+	case "summary":
+		summaries := summarizeViolations(filtered)
+		table := reports.NewTable("rule", "severity", "violations", "documents", "quarantined")
+		for _, summary := range summaries {
+			table.AddRow(summary.Rule, summary.Severity, strconv.Itoa(summary.Violations),
+				strconv.Itoa(summary.Documents), strconv.Itoa(summary.Quarantined))
+		}
+		return reports.Write(os.Stdout, outputFormat, table, summaries)
+	default:
+		return fmt.Errorf("unsupported view: %s", *view)
+	}
+}
+
+// summarizeViolations counts violations by rule and severity, most serious first
+func summarizeViolations(violations []models.Violation) []dataQualitySummary {
+	type documentKey struct{ rule, severity, document string }
+
+	byRule := make(map[string]*dataQualitySummary)
+	documents := make(map[documentKey]bool)
+	quarantined := make(map[documentKey]bool)
+	for _, violation := range violations {
+		key := violation.Rule + "/" + violation.Severity
+		summary, exists := byRule[key]
+		if !exists {
+			summary = &dataQualitySummary{Rule: violation.Rule, Severity: violation.Severity}
+			byRule[key] = summary
+		}
+		summary.Violations++
+
+		document := documentKey{violation.Rule, violation.Severity, violation.DocumentID}
+		if !documents[document] {
+			documents[document] = true
+			summary.Documents++
+		}
+		if violation.Quarantined && !quarantined[document] {
+			quarantined[document] = true
+			summary.Quarantined++
+		}
+	}
+
+	summaries := make([]dataQualitySummary, 0, len(byRule))
+	for _, summary := range byRule {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := validation.Severity(summaries[i].Severity), validation.Severity(summaries[j].Severity)
+		if a != b {
+			return a.AtLeast(b)
+		}
+		return summaries[i].Rule < summaries[j].Rule
+	})
+	return summaries
+}
+
+// writeRules lists the built-in rules with the configured severity and state
+func writeRules(cfg *config.Config, format reports.Format) error {
+	disabled := make(map[string]bool)
+	for _, rule := range cfg.ValidationDisabled {
+		disabled[rule] = true
+	}
+
+	type ruleInfo struct {
+		Name        string `json:"name"`
+		Kind        string `json:"kind"`
+		Severity    string `json:"severity"`
+		Enabled     bool   `json:"enabled"`
+		Description string `json:"description"`
+	}
+
+	infos := []ruleInfo{}
+	table := reports.NewTable("rule", "kind", "severity", "enabled", "description")
+	for _, rule := range validation.Rules {
+		severity := string(rule.Severity)
+		if override, exists := cfg.ValidationSeverities[rule.Name]; exists {
+			severity = override
+		}
+		info := ruleInfo{
+			Name:        rule.Name,
+			Kind:        rule.Kind,
+			Severity:    severity,
+			Enabled:     cfg.EnableValidation && !disabled[rule.Name],
+			Description: rule.Description,
+		}
+		infos = append(infos, info)
+		table.AddRow(info.Name, info.Kind, info.Severity, strconv.FormatBool(info.Enabled), info.Description)
+	}
+
+	return reports.Write(os.Stdout, format, table, infos)
+}
+
+// containsString reports whether a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/digest"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// digestRecipients returns the configured digest recipients
+func digestRecipients(cfg *config.Config) digest.Recipients {
+	return digest.Recipients{All: cfg.DigestRecipients, Teams: cfg.DigestTeamRecipients}
+}
+
+// digestOptions returns the digest options of the configuration
+func digestOptions(cfg *config.Config) digest.Options {
+	return digest.Options{
+		SeatOptions: analysis.SeatUtilizationOptions{
+			InactiveDays: cfg.SeatInactiveDays,
+			Prices:       cfg.SeatPrices,
+		},
+	}
+}
+
+// newDigestSender creates the digest sender from the SMTP configuration
+func newDigestSender(cfg *config.Config, repo repositories.Repository, recipients digest.Recipients, logger *zap.Logger) (*digest.Sender, error) {
+	mailer, err := digest.NewMailer(digest.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+		Security: cfg.SMTPSecurity,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digest.NewSender(repo, mailer, recipients, digestOptions(cfg), logger), nil
+}
+
+// runDigest dispatches the digest subcommands
+func runDigest(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	action := "preview"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "preview":
+		return runDigestPreview(ctx, cfg, logger, args)
+	case "send":
+		return runDigestSend(ctx, cfg, logger, args)
+	case "sink":
+		return runDigestSink(ctx, args)
+	default:
+		return fmt.Errorf("unknown digest action: %s (expected preview, send or sink)", action)
+	}
+}
+
+// defaultDigestWeek returns the last day of the last complete week
+func defaultDigestWeek() string {
+	_, to := digest.LastCompleteWeek(time.Now().UTC())
+	return to
+}
+
+// runDigestPreview prints the digest instead of sending it
+func runDigestPreview(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("digest preview", flag.ContinueOnError)
+	format := flags.String("format", "text", "Output format: text, html or json")
+	to := flags.String("to", defaultDigestWeek(), "Last day of the week (YYYY-MM-DD, default: last Sunday)")
+	team := flags.String("team", "", "Only include the sections of this team")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	result, err := digest.Build(ctx, repo, *to, digestOptions(cfg), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if *team != "" {
+		result = result.ForTeam(*team)
+	}
+
+	var output string
+	switch *format {
+	case "text":
+		output, err = result.Text()
+	case "html":
+		output, err = result.HTML()
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(result, "", "  ")
+		output = string(data) + "\n"
+	default:
+		return fmt.Errorf("unsupported format: %s", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(os.Stdout, output)
+	return err
+}
+
+// runDigestSend sends the digest now, to the configured recipients or to
+// the given addresses
+func runDigestSend(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("digest send", flag.ContinueOnError)
+	to := flags.String("to", defaultDigestWeek(), "Last day of the week (YYYY-MM-DD, default: last Sunday)")
+	recipientsFlag := flags.String("recipients", "", "Comma-separated addresses that receive the full digest instead of the configured recipients")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	recipients := digestRecipients(cfg)
+	if *recipientsFlag != "" {
+		recipients = digest.Recipients{}
+		for _, address := range strings.Split(*recipientsFlag, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				recipients.All = append(recipients.All, address)
+			}
+		}
+	}
+	if recipients.IsEmpty() {
+		return fmt.Errorf("no digest recipients, set DIGEST_RECIPIENTS or DIGEST_TEAM_RECIPIENTS, or pass -recipients")
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	sender, err := newDigestSender(cfg, repo, recipients, logger)
+	if err != nil {
+		return err
+	}
+	return sender.Send(ctx, *to, time.Now().UTC())
+}
+
+// runDigestSink runs a local SMTP server that accepts every message and
+// prints it, to try the digest without a real mail server
+func runDigestSink(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("digest sink", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:2525", "Address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening for SMTP on %s\n", *addr)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go serveSMTPSink(conn)
+	}
+}
+
+// serveSMTPSink speaks just enough SMTP to receive a message and print it
+func serveSMTPSink(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	var from string
+	var to []string
+	reply("220 localhost digest sink")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			from, to = strings.TrimPrefix(line[4:], " FROM:"), nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, strings.TrimPrefix(line[4:], " TO:"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var message strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" || dataLine == ".\n" {
+					break
+				}
+				message.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			fmt.Printf("--- %s message from %s to %s\n%s\n", time.Now().UTC().Format(time.RFC3339), from, strings.Join(to, ", "), message.String())
+			reply("250 OK")
+		case "RSET":
+			from, to = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
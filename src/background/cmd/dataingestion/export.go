@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/export"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// runExport streams a stored dataset to a CSV or NDJSON file, or writes it as
+// date partitioned Parquet files to a directory or S3 bucket
+func runExport(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	datasetName := flags.String("dataset", "metrics", "Dataset to export: metrics, usage, seats or seat_events")
+	format := flags.String("format", "csv", "Output format: csv, ndjson or parquet")
+	output := flags.String("output", "", "Output file (default: stdout); for parquet, a directory or s3://bucket/prefix")
+	from := flags.String("from", "", "First date to include (YYYY-MM-DD, default: 28 days ago)")
+	to := flags.String("to", "", "Last date to include (YYYY-MM-DD, default: today)")
+	enterprise := flags.String("enterprise", "", "Only export data of this enterprise")
+	organization := flags.String("organization", "", "Only export data of this organization")
+	team := flags.String("team", "", "Only export data of this team")
+	excludeTeams := flags.Bool("exclude-teams", false, "Only export organization/enterprise level metrics and usage")
+	schema := flags.Bool("schema", false, "Print the column schema of the dataset instead of exporting")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	dataset, err := export.ParseDataset(*datasetName)
+	if err != nil {
+		return err
+	}
+
+	if *schema {
+		table := reports.NewTable("column", "type", "description")
+		for _, column := range export.Columns(dataset) {
+			table.AddRow(column.Name, string(column.Type), column.Description)
+		}
+		return reports.Write(os.Stdout, reports.FormatTable, table, nil)
+	}
+
+	exportFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 28)
+	if err != nil {
+		return err
+	}
+
+	filter := export.Filter{
+		From:         fromDate,
+		To:           toDate,
+		Enterprise:   *enterprise,
+		Organization: *organization,
+		Team:         *team,
+		ExcludeTeams: *excludeTeams,
+	}
+
+	if exportFormat == export.FormatParquet && *output == "" {
+		return fmt.Errorf("parquet export requires -output")
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	if exportFormat == export.FormatParquet {
+		store, err := objectstore.Open(*output, repositories.S3ConfigFrom(cfg), logger)
+		if err != nil {
+			return err
+		}
+
+		rows, files, err := export.ExportParquet(ctx, repo, dataset, filter, store)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Export completed",
+			zap.String("dataset", string(dataset)),
+			zap.String("format", string(exportFormat)),
+			zap.String("location", store.Location()),
+			zap.Int("rows", rows),
+			zap.Int("files", files))
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		file, err = os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		// Closes the file on failure; on success it is closed below to check the error
+		defer file.Close()
+		out = file
+	}
+
+	writer, err := export.NewRowWriter(out, exportFormat, export.Columns(dataset))
+	if err != nil {
+		return err
+	}
+
+	count, err := export.Export(ctx, repo, dataset, filter, writer)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+
+	logger.Info("Export completed",
+		zap.String("dataset", string(dataset)),
+		zap.String("format", string(exportFormat)),
+		zap.Int("rows", count))
+	return nil
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Job timezones must resolve in containers without tzdata
 
+	"github.com/cardonator/copilot-metrics-dashboard/internal/alerting"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/handlers"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/locking"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/services"
 	"github.com/go-co-op/gocron"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the pgx driver of Postgres job locks
 	"go.uber.org/zap"
 )
 
@@ -23,14 +28,22 @@ func main() {
 	}
 	defer logger.Sync()
 
-	logger.Info("Starting GitHub Copilot Metrics Dashboard data ingestion")
-
 	// Load configuration
 	cfg, err := config.Load(logger)
 	if err != nil {
 		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
 
+	// Run a subcommand instead of the service if one was given
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), cfg, logger, os.Args[1], os.Args[2:]); err != nil {
+			logger.Fatal("Command failed", zap.String("command", os.Args[1]), zap.Error(err))
+		}
+		return
+	}
+
+	logger.Info("Starting GitHub Copilot Metrics Dashboard data ingestion")
+
 	// Log if using test data
 	if cfg.UseTestData {
 		logger.Info("Running in test mode with test data")
@@ -47,43 +60,26 @@ func main() {
 	// Set up service clients
 	metricsClient := services.NewCopilotMetricsClient(githubClient, logger)
 	seatsClient := services.NewCopilotSeatsClient(githubClient, logger)
+	teamsClient := services.NewTeamsClient(githubClient, logger)
 
 	// Set up repository based on configuration
-	var repo repositories.Repository
-
-	switch cfg.StorageType {
-	case config.StorageSQLite:
-		if cfg.SQLitePath == "" {
-			logger.Warn("SQLite path is not set.")
-		} else {
-			logger.Info("Using SQLite repository", zap.String("path", cfg.SQLitePath))
-			sqlite, err := repositories.NewSQLiteRepository(cfg.SQLitePath, logger)
-			if err != nil {
-				logger.Error("Failed to create SQLite repository", zap.Error(err))
-			} else {
-				repo = sqlite
-			}
-		}
-	case config.StorageCosmos:
-		if cfg.CosmosDBEndpoint == "" || cfg.CosmosDBKey == "" {
-			logger.Warn("Cosmos DB endpoint or key is not set.")
-		} else {
-			logger.Info("Using Cosmos DB repository", zap.String("endpoint", cfg.CosmosDBEndpoint))
-			cosmos, err := repositories.NewCosmosRepository(cfg.CosmosDBEndpoint, cfg.CosmosDBKey, logger)
-			if err != nil {
-				logger.Error("Failed to create Cosmos DB repository", zap.Error(err))
-			} else {
-				repo = cosmos
-			}
-		}
-	default:
+	repo, err := repositories.NewRepository(cfg, cfg.StorageType, logger)
+	if err != nil {
+		logger.Error("Failed to create repository", zap.String("storageType", string(cfg.StorageType)), zap.Error(err))
+		repo = nil
+	} else if repo == nil {
 		logger.Warn("No storage type specified. Data will be collected but not persisted.")
 	}
 
 	// Initialize repository if it exists
 	if repo != nil {
 		ctx := context.Background()
-		if err := repo.Initialize(ctx); err != nil {
+		if err := repo.Initialize(ctx); errors.Is(err, repositories.ErrPartitionKeyMismatch) {
+			// Saving without the repository would drop every document, and
+			// the containers cannot be repartitioned in place
+			logger.Fatal("Cosmos DB containers are not partitioned by /scope; point COSMOS_CONTAINER_NAMES at containers that are and copy the documents with migrate-data",
+				zap.Error(err))
+		} else if err != nil {
 			logger.Error("Failed to initialize repository, data will not be persisted", zap.Error(err))
 			repo = nil
 		} else {
@@ -93,6 +89,8 @@ func main() {
 
 	if repo == nil {
 		logger.Warn("No valid repository configured. Data will be collected but not persisted.")
+	} else if cfg.ArchiveRawResponses {
+		githubClient.SetResponseArchive(repo)
 	}
 
 	// Set up handlers
@@ -104,6 +102,12 @@ func main() {
 		cfg.UseTestData,
 	)
 
+	metricsHandler.SetSaveRetries(cfg.SaveRetries, cfg.SaveRetryDelay)
+
+	if cfg.EnableAnomalyDetection {
+		metricsHandler.SetAnomalyDetector(newAnomalyDetector(cfg))
+	}
+
 	seatsHandler := handlers.NewSeatsHandler(
 		logger,
 		seatsClient,
@@ -111,50 +115,129 @@ func main() {
 		cfg.UseTestData,
 	)
 
-	// Set up scheduler
+	if cfg.EnableValidation {
+		validator := newValidator(cfg)
+		metricsHandler.SetValidator(validator)
+		seatsHandler.SetValidator(validator)
+	}
+
+	teamsHandler := handlers.NewTeamsHandler(
+		logger,
+		teamsClient,
+		repo,
+		cfg.UseTestData,
+	)
+
+	// Set up alerting when a rules file is configured
+	var alerts *alerting.Engine
+	if cfg.AlertRulesFile != "" {
+		if repo == nil {
+			logger.Warn("Alerting requires a repository, alerting disabled")
+		} else if alerts, err = newAlertEngine(cfg, repo, logger); err != nil {
+			logger.Error("Failed to set up alerting, alerting disabled", zap.Error(err))
+			alerts = nil
+		}
+	}
+
+	// Set up scheduler. Jobs run in singleton mode so that a slow run never
+	// overlaps the next run of the same job.
 	scheduler := gocron.NewScheduler(time.UTC)
+	scheduler.SingletonModeAll()
 
-	// Schedule metrics ingestion using seconds interval
-	_, err = scheduler.Every(cfg.MetricsScheduleSeconds).Seconds().Do(func() {
-		ctx := context.Background()
-		if err := metricsHandler.Run(ctx); err != nil {
+	// Lock scheduled jobs so that only one of several instances runs them
+	var jobLocks *locking.Runner
+	if locker, err := locking.CreateLocker(cfg, locking.NewOwnerID(), logger); err != nil {
+		logger.Fatal("Failed to set up job locking", zap.Error(err))
+	} else if locker != nil {
+		jobLocks = locking.NewRunner(locker, cfg.LockLease, logger)
+	}
+
+	err = scheduleJob(scheduler, jobLocks, jobMetrics, cfg.MetricsSchedule, logger, func(ctx context.Context) {
+		err := metricsHandler.Run(ctx)
+		alerts.AfterRun(ctx, alerting.JobMetrics, err)
+		if err != nil {
 			logger.Error("Metrics ingestion failed", zap.Error(err))
 		}
 	})
 	if err != nil {
-		logger.Fatal("Failed to schedule metrics ingestion", zap.Error(err))
+		logger.Fatal("Failed to schedule metrics ingestion", zap.String("schedule", cfg.MetricsSchedule.Expression), zap.Error(err))
 	}
 
-	logger.Info("Scheduled metrics ingestion", zap.Int("interval_seconds", cfg.MetricsScheduleSeconds))
-
-	// Schedule seats ingestion - every hour
-	_, err = scheduler.Every(1).Hour().Do(func() {
-		ctx := context.Background()
-		if err := seatsHandler.Run(ctx); err != nil {
+	// Seats and team memberships are ingested by the same job
+	err = scheduleJob(scheduler, jobLocks, jobSeats, cfg.SeatsSchedule, logger, func(ctx context.Context) {
+		err := seatsHandler.Run(ctx)
+		alerts.AfterRun(ctx, alerting.JobSeats, err)
+		if err != nil {
 			logger.Error("Seats ingestion failed", zap.Error(err))
 		}
+		err = teamsHandler.Run(ctx)
+		alerts.AfterRun(ctx, alerting.JobTeams, err)
+		if err != nil {
+			logger.Error("Team memberships ingestion failed", zap.Error(err))
+		}
 	})
 	if err != nil {
-		logger.Fatal("Failed to schedule seats ingestion", zap.Error(err))
+		logger.Fatal("Failed to schedule seats ingestion", zap.String("schedule", cfg.SeatsSchedule.Expression), zap.Error(err))
 	}
 
-	// Start the scheduler in a non-blocking manner
-	scheduler.StartAsync()
+	// Schedule the rollups if they have a location
+	if cfg.RollupsLocation != "" {
+		if repo == nil {
+			logger.Warn("Rollups require a repository, rollups disabled")
+		} else if job, err := newRollupsJob(cfg, repo, cfg.RollupsLocation, logger); err != nil {
+			logger.Error("Failed to set up rollups, rollups disabled", zap.Error(err))
+		} else {
+			err = scheduleJob(scheduler, jobLocks, jobRollups, cfg.RollupsSchedule, logger, func(ctx context.Context) {
+				if err := job.Run(ctx); err != nil {
+					logger.Error("Rollups failed", zap.Error(err))
+				}
+			})
+			if err != nil {
+				logger.Fatal("Failed to schedule rollups", zap.String("schedule", cfg.RollupsSchedule.Expression), zap.Error(err))
+			}
+		}
+	}
 
-	// Run once immediately
-	ctx := context.Background()
-	logger.Info("Running initial data collection")
+	// Alert rules are evaluated after every ingestion run, and also on their
+	// own schedule if one is configured
+	if alerts != nil {
+		err = scheduleJob(scheduler, jobLocks, jobAlerts, cfg.AlertsSchedule, logger, func(ctx context.Context) {
+			if err := alerts.Evaluate(ctx); err != nil {
+				logger.Error("Alert evaluation failed", zap.Error(err))
+			}
+		})
+		if err != nil {
+			logger.Fatal("Failed to schedule alert evaluation", zap.String("schedule", cfg.AlertsSchedule.Expression), zap.Error(err))
+		}
+	}
 
-	if err := metricsHandler.Run(ctx); err != nil {
-		logger.Error("Initial metrics ingestion failed", zap.Error(err))
-	} else {
-		logger.Info("Initial metrics ingestion completed successfully")
+	// Schedule the weekly digest if anybody receives it
+	if recipients := digestRecipients(cfg); !recipients.IsEmpty() {
+		if repo == nil {
+			logger.Warn("The weekly digest requires a repository, digest disabled")
+		} else if sender, err := newDigestSender(cfg, repo, recipients, logger); err != nil {
+			logger.Error("Failed to set up the weekly digest, digest disabled", zap.Error(err))
+		} else {
+			err = scheduleJob(scheduler, jobLocks, jobDigest, cfg.DigestSchedule, logger, func(ctx context.Context) {
+				if err := sender.Run(ctx); err != nil {
+					logger.Error("Weekly digest failed", zap.Error(err))
+				}
+			})
+			if err != nil {
+				logger.Fatal("Failed to schedule the weekly digest", zap.String("schedule", cfg.DigestSchedule.Expression), zap.Error(err))
+			}
+		}
 	}
 
-	if err := seatsHandler.Run(ctx); err != nil {
-		logger.Error("Initial seats ingestion failed", zap.Error(err))
-	} else {
-		logger.Info("Initial seats ingestion completed successfully")
+	// Start the scheduler in a non-blocking manner
+	scheduler.StartAsync()
+
+	// Run the ingestion jobs once immediately
+	logger.Info("Running initial data collection")
+	for _, job := range []string{jobMetrics, jobSeats} {
+		if err := runInitially(scheduler, job); err != nil {
+			logger.Info("Skipping initial run of unscheduled job", zap.String("job", job))
+		}
 	}
 
 	// Set up signal handling for graceful shutdown
@@ -165,8 +248,11 @@ func main() {
 	sig := <-sigChan
 	logger.Info("Received signal, shutting down", zap.String("signal", sig.String()))
 
-	// Stop the scheduler
+	// Stop the scheduler and hand the job locks over to other instances
 	scheduler.Stop()
+	if err := jobLocks.Close(context.Background()); err != nil {
+		logger.Warn("Failed to release job locks", zap.Error(err))
+	}
 
 	logger.Info("Shutdown complete")
 }
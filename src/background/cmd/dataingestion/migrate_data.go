@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// migrationStart is the default first day migrated, before Copilot was
+// generally available
+const migrationStart = "2022-01-01"
+
+// migrationKind copies the documents of a kind between repositories
+type migrationKind struct {
+	name string
+	// copy reads the documents of a date window from the source and saves
+	// those selected by include to the target. It returns the IDs of the
+	// source documents and the number of documents saved.
+	copy func(ctx context.Context, source, target repositories.Repository, from, to string, include func(id string) bool) ([]string, int, error)
+}
+
+// migrationKinds lists the document kinds the migration copies
+var migrationKinds = []migrationKind{
+	{"metrics", func(ctx context.Context, source, target repositories.Repository, from, to string, include func(string) bool) ([]string, int, error) {
+		documents, err := source.GetMetrics(ctx, from, to)
+		if err != nil {
+			return nil, 0, err
+		}
+		return copyDocuments(documents, func(m *models.Metrics) (string, string) { return m.ID, m.GetID() }, include,
+			func(pending []models.Metrics) error {
+				_, err := target.SaveMetrics(ctx, pending)
+				return err
+			})
+	}},
+	{"usage", func(ctx context.Context, source, target repositories.Repository, from, to string, include func(string) bool) ([]string, int, error) {
+		documents, err := source.GetUsage(ctx, from, to)
+		if err != nil {
+			return nil, 0, err
+		}
+		return copyDocuments(documents, func(u *models.CopilotUsage) (string, string) { return u.ID, u.GetID() }, include,
+			func(pending []models.CopilotUsage) error {
+				_, err := target.SaveUsage(ctx, pending)
+				return err
+			})
+	}},
+	{"seats", func(ctx context.Context, source, target repositories.Repository, from, to string, include func(string) bool) ([]string, int, error) {
+		documents, err := source.GetSeatsHistory(ctx, from, to)
+		if err != nil {
+			return nil, 0, err
+		}
+		return copyDocuments(documents, func(s *models.CopilotAssignedSeats) (string, string) { return s.ID, s.GetID() }, include,
+			func(pending []models.CopilotAssignedSeats) error {
+				for i := range pending {
+					if err := target.SaveSeats(ctx, &pending[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+	}},
+}
+
+// copyDocuments saves the documents selected by include and returns the IDs
+// of all documents and the number saved
+func copyDocuments[T any](documents []T, id func(document *T) (stored, generated string), include func(id string) bool,
+	save func(documents []T) error) ([]string, int, error) {
+	ids := documentIDs(documents, id)
+	var pending []T
+	for i := range documents {
+		if include(ids[i]) {
+			pending = append(pending, documents[i])
+		}
+	}
+	if len(pending) == 0 {
+		return ids, 0, nil
+	}
+	return ids, len(pending), save(pending)
+}
+
+// migrationCheckpoint records how far a migration got, so that an
+// interrupted migration resumes where it stopped
+type migrationCheckpoint struct {
+	Source    string            `json:"source"`
+	Target    string            `json:"target"`
+	Completed map[string]string `json:"completed"` // Last day copied and verified, by document kind
+}
+
+// loadCheckpoint reads the checkpoint of a migration, or starts a new one
+func loadCheckpoint(path, source, target string) (*migrationCheckpoint, error) {
+	checkpoint := &migrationCheckpoint{Source: source, Target: target, Completed: make(map[string]string)}
+	if path == "" {
+		return checkpoint, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if checkpoint.Source != source || checkpoint.Target != target {
+		return nil, fmt.Errorf("checkpoint %s belongs to the migration from %s to %s; delete it or pass another -checkpoint",
+			path, checkpoint.Source, checkpoint.Target)
+	}
+	if checkpoint.Completed == nil {
+		checkpoint.Completed = make(map[string]string)
+	}
+	return checkpoint, nil
+}
+
+// save writes the checkpoint, replacing the previous one atomically
+func (c *migrationCheckpoint) save(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// migrationEntry summarizes the migration of a document kind
+type migrationEntry struct {
+	Kind            string   `json:"kind"`
+	Windows         int      `json:"windows"`
+	ResumedWindows  int      `json:"resumed_windows"` // Windows completed by an earlier run
+	Source          int      `json:"source"`
+	AlreadyInTarget int      `json:"already_in_target"`
+	Copied          int      `json:"copied"`
+	Missing         []string `json:"missing"` // Source documents not in the target, to copy in a dry run
+	Error           string   `json:"error,omitempty"`
+}
+
+// runMigrateData copies metrics, usage and seats documents between two repositories
+func runMigrateData(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("migrate-data", flag.ContinueOnError)
+	from := flags.String("from", "", "Source repository: sqlite:<path>, cosmos[:<endpoint>] or objectstore:<location>")
+	to := flags.String("to", "", "Target repository: sqlite:<path>, cosmos[:<endpoint>] or objectstore:<location>")
+	fromContainers := flags.String("from-containers", "", "Cosmos DB container names of the source like COSMOS_CONTAINER_NAMES, or default for the default names (default: COSMOS_CONTAINER_NAMES)")
+	toContainers := flags.String("to-containers", "", "Cosmos DB container names of the target like COSMOS_CONTAINER_NAMES, or default for the default names (default: COSMOS_CONTAINER_NAMES)")
+	since := flags.String("since", migrationStart, "First day to migrate (YYYY-MM-DD)")
+	until := flags.String("until", "", "Last day to migrate (YYYY-MM-DD, default: today)")
+	kindsFlag := flags.String("kinds", "metrics,usage,seats", "Comma-separated document kinds to migrate: metrics, usage and seats")
+	windowDays := flags.Int("window-days", 30, "Number of days of documents read and written at once")
+	checkpointPath := flags.String("checkpoint", "migrate-data.checkpoint.json", "Checkpoint file to resume an interrupted migration from, empty to disable")
+	onlyMissing := flags.Bool("only-missing", false, "Only copy documents the target does not have yet instead of overwriting them")
+	dryRun := flags.Bool("dry-run", false, "Count the documents to copy without writing anything")
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	sample := flags.Int("sample", 5, "Number of missing document IDs listed per kind in table and CSV output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("both -from and -to repositories are required")
+	}
+	// Both repositories may be the same Cosmos DB account with other containers
+	sourceName, targetName := repositorySpecName(*from, *fromContainers), repositorySpecName(*to, *toContainers)
+	if sourceName == targetName {
+		return fmt.Errorf("source and target repositories are the same")
+	}
+	if *windowDays < 1 {
+		return fmt.Errorf("window-days must be at least 1")
+	}
+
+	kinds, err := selectMigrationKinds(*kindsFlag)
+	if err != nil {
+		return err
+	}
+
+	sinceDate, untilDate, err := resolveDateRange(*since, *until, 1)
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := loadCheckpoint(*checkpointPath, sourceName, targetName)
+	if err != nil {
+		return err
+	}
+
+	source, err := openRepositorySpec(ctx, cfg, *from, *fromContainers, true, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open source repository: %w", err)
+	}
+	defer source.Close()
+	target, err := openRepositorySpec(ctx, cfg, *to, *toContainers, false, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open target repository: %w", err)
+	}
+	defer target.Close()
+
+	entries := []migrationEntry{}
+	table := reports.NewTable("kind", "windows", "resumed_windows", "source", "already_in_target", "copied", "missing", "missing_ids", "error")
+	failed := 0
+	for _, kind := range kinds {
+		entry := migrateKind(ctx, logger, kind, source, target, sinceDate, untilDate, *windowDays, checkpoint, *checkpointPath, *onlyMissing, *dryRun)
+		if entry.Error != "" || (!*dryRun && len(entry.Missing) > 0) {
+			failed++
+		}
+
+		entries = append(entries, entry)
+		table.AddRow(entry.Kind, strconv.Itoa(entry.Windows), strconv.Itoa(entry.ResumedWindows), strconv.Itoa(entry.Source),
+			strconv.Itoa(entry.AlreadyInTarget), strconv.Itoa(entry.Copied), strconv.Itoa(len(entry.Missing)),
+			strings.Join(entry.Missing[:min(*sample, len(entry.Missing))], " "), entry.Error)
+	}
+
+	if err := reports.Write(os.Stdout, outputFormat, table, entries); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("migration of %d document kinds failed or could not be verified; rerun to resume", failed)
+	}
+	return nil
+}
+
+// migrateKind copies the documents of a kind window by window, verifying
+// after each window that the target has every source document before
+// recording the window in the checkpoint. Windows recorded by an earlier run
+// are verified and only the documents missing from them copied. The kind stops at its first failed window so
+// that the checkpoint never skips past it.
+func migrateKind(ctx context.Context, logger *zap.Logger, kind migrationKind, source, target repositories.Repository,
+	since, until string, windowDays int, checkpoint *migrationCheckpoint, checkpointPath string, onlyMissing, dryRun bool) migrationEntry {
+	entry := migrationEntry{Kind: kind.name, Missing: []string{}}
+	targetIDs := documentKindIDs(kind.name)
+	if targetIDs == nil {
+		entry.Error = "no ID listing for " + kind.name
+		return entry
+	}
+
+	start, _ := time.Parse(dateLayout, since)
+	end, _ := time.Parse(dateLayout, until)
+	for windowStart := start; !windowStart.After(end); windowStart = windowStart.AddDate(0, 0, windowDays) {
+		windowEnd := windowStart.AddDate(0, 0, windowDays-1)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		from, to := windowStart.Format(dateLayout), windowEnd.Format(dateLayout)
+		resumed := checkpoint.Completed[kind.name] >= to
+		entry.Windows++
+		if resumed {
+			entry.ResumedWindows++
+		}
+
+		before, err := targetIDs(ctx, target, from, to)
+		if err != nil {
+			entry.Error = fmt.Sprintf("failed to read target %s to %s: %v", from, to, err)
+			return entry
+		}
+		inTarget := idSet(before)
+
+		ids, copied, err := kind.copy(ctx, source, target, from, to, func(id string) bool {
+			// Windows of an earlier run only get back documents removed since
+			return !dryRun && (!inTarget[id] || (!resumed && !onlyMissing))
+		})
+		entry.Source += len(ids)
+		entry.Copied += copied
+		for _, id := range ids {
+			if inTarget[id] {
+				entry.AlreadyInTarget++
+			}
+		}
+		if err != nil {
+			entry.Error = fmt.Sprintf("failed to copy %s to %s: %v", from, to, err)
+			return entry
+		}
+
+		if copied > 0 {
+			after, err := targetIDs(ctx, target, from, to)
+			if err != nil {
+				entry.Error = fmt.Sprintf("failed to verify %s to %s: %v", from, to, err)
+				return entry
+			}
+			inTarget = idSet(after)
+		}
+		var missing []string
+		for _, id := range ids {
+			if !inTarget[id] {
+				missing = append(missing, id)
+			}
+		}
+		sort.Strings(missing)
+		entry.Missing = append(entry.Missing, missing...)
+
+		if dryRun {
+			continue
+		}
+		if len(missing) > 0 {
+			entry.Error = fmt.Sprintf("%d documents of %s to %s missing from the target after copying", len(missing), from, to)
+			return entry
+		}
+
+		logger.Info("Migrated window",
+			zap.String("kind", kind.name),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("documents", len(ids)),
+			zap.Int("copied", copied),
+			zap.Bool("resumed", resumed))
+		if !resumed {
+			checkpoint.Completed[kind.name] = to
+			if err := checkpoint.save(checkpointPath); err != nil {
+				entry.Error = err.Error()
+				return entry
+			}
+		}
+	}
+	return entry
+}
+
+// documentKindIDs returns the ID listing of a document kind of the consistency check
+func documentKindIDs(name string) func(ctx context.Context, repo repositories.Repository, from, to string) ([]string, error) {
+	for _, kind := range documentKinds {
+		if kind.name == name {
+			return kind.ids
+		}
+	}
+	return nil
+}
+
+// idSet returns a set of IDs
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// selectMigrationKinds returns the migration kinds named in a comma-separated list
+func selectMigrationKinds(list string) ([]migrationKind, error) {
+	var kinds []migrationKind
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, kind := range migrationKinds {
+			if kind.name == name {
+				kinds = append(kinds, kind)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported document kind: %s (expected metrics, usage or seats)", name)
+		}
+	}
+	return kinds, nil
+}
+
+// repositorySpecName names a repository spec and its Cosmos DB container
+// names, to tell apart container sets of one account
+func repositorySpecName(spec, containerNames string) string {
+	if containerNames == "" {
+		return spec
+	}
+	return spec + " containers " + containerNames
+}
+
+// openRepositorySpec creates and initializes the repository of a
+// <type>:<location> spec. The location replaces SQLITE_DB_PATH,
+// AZURE_COSMOSDB_ENDPOINT or OBJECT_STORE_LOCATION, and containerNames, if
+// set, COSMOS_CONTAINER_NAMES; every other setting, such as credentials, comes
+// from the configuration. A source repository may use Cosmos DB containers
+// that are not partitioned by /scope.
+func openRepositorySpec(ctx context.Context, cfg *config.Config, spec, containerNames string, source bool, logger *zap.Logger) (repositories.Repository, error) {
+	storageType, location, _ := strings.Cut(spec, ":")
+	specCfg := *cfg
+	specCfg.StorageType = config.StorageType(storageType)
+
+	if containerNames != "" {
+		if specCfg.StorageType != config.StorageCosmos {
+			return nil, fmt.Errorf("repository %s: container names only apply to cosmos repositories", spec)
+		}
+		if containerNames == "default" {
+			specCfg.CosmosContainerNames = map[string]string{}
+		} else {
+			names, invalid := config.ParseCosmosContainerNames(containerNames)
+			if len(invalid) > 0 {
+				return nil, fmt.Errorf("repository %s: invalid container names %q, expected <default name>=<name>", spec, strings.Join(invalid, ","))
+			}
+			specCfg.CosmosContainerNames = names
+		}
+	}
+
+	switch specCfg.StorageType {
+	case config.StorageSQLite:
+		if location != "" {
+			specCfg.SQLitePath = location
+		}
+		if specCfg.SQLitePath == "" {
+			return nil, fmt.Errorf("repository %s: no SQLite path, use sqlite:<path>", spec)
+		}
+	case config.StorageCosmos:
+		if location != "" {
+			specCfg.CosmosDBEndpoint = location
+		}
+		if specCfg.CosmosDBEndpoint == "" {
+			return nil, fmt.Errorf("repository %s: no Cosmos DB endpoint, use cosmos:<endpoint> or set AZURE_COSMOSDB_ENDPOINT", spec)
+		}
+	case config.StorageObjectStore:
+		if location != "" {
+			specCfg.ObjectStoreLocation = location
+		}
+		if specCfg.ObjectStoreLocation == "" {
+			return nil, fmt.Errorf("repository %s: no object store location, use objectstore:<location>", spec)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported repository %s (expected sqlite, cosmos or objectstore)", spec)
+	}
+
+	repo, err := repositories.NewRepository(&specCfg, specCfg.StorageType, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.Initialize(ctx); err != nil {
+		if source && errors.Is(err, repositories.ErrPartitionKeyMismatch) {
+			// Documents are only read from the source, whatever its partition key
+			logger.Warn("Reading from Cosmos DB containers not partitioned by /scope", zap.Error(err))
+			return repo, nil
+		}
+		repo.Close()
+		return nil, err
+	}
+	return repo, nil
+}
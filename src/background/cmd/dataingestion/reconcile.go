@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"go.uber.org/zap"
+)
+
+// runReconcile compares the stored team metrics with their organization/enterprise totals
+func runReconcile(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First date to include (YYYY-MM-DD, default: 28 days ago)")
+	to := flags.String("to", "", "Last date to include (YYYY-MM-DD, default: today)")
+	surface := flags.String("surface", "", "Only report one surface, e.g. engaged_users or copilot_ide_chat")
+	minCoverage := flags.Float64("min-coverage", cfg.ReconciliationCoverage, "Minimum share of the total the teams should cover, between 0 and 1")
+	flaggedOnly := flags.Bool("flagged", false, "Only report days and surfaces with a flag")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	if *minCoverage < 0 || *minCoverage > 1 {
+		return fmt.Errorf("invalid minimum coverage %v, expected a value between 0 and 1", *minCoverage)
+	}
+
+	if *surface != "" && !containsString(analysis.ReconciliationSurfaces, *surface) {
+		return fmt.Errorf("unsupported surface: %s", *surface)
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 28)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	metrics, err := repo.GetMetrics(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load metrics: %w", err)
+	}
+
+	markers, err := repo.GetMetricsMarkers(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load metrics markers: %w", err)
+	}
+
+	entries := analysis.ReconcileTeamMetrics(metrics, markers, analysis.ReconciliationOptions{MinCoverage: *minCoverage})
+
+	filtered := []analysis.ReconciliationEntry{}
+	table := reports.NewTable("date", "scope", "surface", "total", "teams", "team_sum", "team_max", "max_team", "suppressed_teams", "min_coverage", "max_coverage", "flags")
+	for _, entry := range entries {
+		if *surface != "" && entry.Surface != *surface {
+			continue
+		}
+		if *flaggedOnly && !entry.IsFlagged() {
+			continue
+		}
+
+		filtered = append(filtered, entry)
+		scope := entry.Organization
+		if scope == "" {
+			scope = entry.Enterprise
+		}
+		total := ""
+		if entry.HasTotal {
+			total = strconv.Itoa(entry.Total)
+		}
+		table.AddRow(entry.Date, scope, entry.Surface, total, strconv.Itoa(entry.Teams),
+			strconv.Itoa(entry.TeamSum), strconv.Itoa(entry.TeamMax), entry.MaxTeam, strconv.Itoa(entry.SuppressedTeams),
+			formatCoverage(entry.MinCoverage), formatCoverage(entry.MaxCoverage), strings.Join(entry.Flags, " "))
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, filtered)
+}
+
+// formatCoverage formats an optional coverage ratio as a percentage
+func formatCoverage(coverage *float64) string {
+	if coverage == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*coverage*100, 'f', 1, 64) + "%"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/handlers"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/services"
+	"go.uber.org/zap"
+)
+
+// runReprocess rebuilds stored metrics and usage from archived API responses
+func runReprocess(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First fetch date of the archived responses (YYYY-MM-DD, default: 28 days ago)")
+	to := flags.String("to", "", "Last fetch date of the archived responses (YYYY-MM-DD, default: today)")
+	dryRun := flags.Bool("dry-run", false, "Rebuild the documents without saving them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 28)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	// Reprocessing never calls the GitHub API, the client only provides the transforms
+	githubClient := services.NewGitHubClient(cfg.GithubApiBaseUrl, cfg.GithubToken, cfg.GithubApiVersion, logger)
+	metricsHandler := handlers.NewMetricsHandler(logger, services.NewCopilotMetricsClient(githubClient, logger), repo, cfg.Teams, false)
+	metricsHandler.SetSaveRetries(cfg.SaveRetries, cfg.SaveRetryDelay)
+	if cfg.EnableValidation {
+		metricsHandler.SetValidator(newValidator(cfg))
+	}
+
+	// A partially saved result is reported along with the error
+	result, reprocessErr := metricsHandler.Reprocess(ctx, fromDate, toDate, *dryRun)
+	if result == nil {
+		return reprocessErr
+	}
+
+	table := reports.NewTable("responses", "skipped", "metrics", "quarantined", "usage", "markers", "saved", "failed")
+	table.AddRow(strconv.Itoa(result.Responses), strconv.Itoa(result.Skipped), strconv.Itoa(result.Metrics),
+		strconv.Itoa(result.Quarantined), strconv.Itoa(result.Usage), strconv.Itoa(result.Markers),
+		strconv.FormatBool(result.Saved), strconv.Itoa(result.Failed))
+
+	if err := reports.Write(os.Stdout, outputFormat, table, result); err != nil {
+		return err
+	}
+	return reprocessErr
+}
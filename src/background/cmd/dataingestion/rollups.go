@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/rollups"
+	"go.uber.org/zap"
+)
+
+// newRollupsJob creates the rollups job writing to a location
+func newRollupsJob(cfg *config.Config, repo repositories.Repository, location string, logger *zap.Logger) (*rollups.Job, error) {
+	store, err := objectstore.Open(location, repositories.S3ConfigFrom(cfg), logger)
+	if err != nil {
+		return nil, err
+	}
+	return rollups.NewJob(repo, store, logger), nil
+}
+
+// runRollups writes the weekly and monthly rollups of a date range, e.g. to
+// backfill them
+func runRollups(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("rollups", flag.ContinueOnError)
+	from := flags.String("from", "", fmt.Sprintf("First date to roll up (YYYY-MM-DD, default: %d days ago)", rollups.LookbackDays))
+	to := flags.String("to", "", "Last date to roll up (YYYY-MM-DD, default: today)")
+	granularity := flags.String("granularity", "all", "Periods to roll up: week, month or all")
+	output := flags.String("output", cfg.RollupsLocation, "Directory or s3://bucket/prefix to write to (default: ROLLUPS_LOCATION)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	granularities := rollups.Granularities
+	if !strings.EqualFold(*granularity, "all") {
+		parsed, err := rollups.ParseGranularity(strings.ToLower(*granularity))
+		if err != nil {
+			return err
+		}
+		granularities = []rollups.Granularity{parsed}
+	}
+	if *output == "" {
+		return fmt.Errorf("no rollups location, set ROLLUPS_LOCATION or pass -output")
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, rollups.LookbackDays)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	job, err := newRollupsJob(cfg, repo, *output, logger)
+	if err != nil {
+		return err
+	}
+	_, err = job.Write(ctx, granularities, fromDate, toDate, time.Now().UTC())
+	return err
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"go.uber.org/zap"
+)
+
+// runRuns dispatches the runs subcommands
+func runRuns(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	action := "list"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "list":
+		return runRunsList(ctx, cfg, logger, args)
+	case "latest":
+		return runRunsLatest(ctx, cfg, logger, args)
+	default:
+		return fmt.Errorf("unknown runs action: %s (expected list or latest)", action)
+	}
+}
+
+// runRunsList lists the recorded ingestion runs
+func runRunsList(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("runs list", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First day runs started (YYYY-MM-DD, default: 7 days ago)")
+	to := flags.String("to", "", "Last day runs started (YYYY-MM-DD, default: today)")
+	job := flags.String("job", "", "Only list runs of this job: metrics, seats or teams")
+	status := flags.String("status", "", "Only list runs with this status: succeeded, partial, failed or skipped")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 7)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	runs, err := repo.GetIngestionRuns(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load ingestion runs: %w", err)
+	}
+
+	filtered := []models.IngestionRun{}
+	table := reports.NewTable("started_at", "job", "scope", "status", "duration", "api_calls", "fetched", "saved", "skipped", "failed", "error")
+	for _, run := range runs {
+		if (*job != "" && run.Job != *job) || (*status != "" && string(run.Status) != *status) {
+			continue
+		}
+
+		filtered = append(filtered, run)
+		table.AddRow(run.StartedAt.UTC().Format("2006-01-02T15:04:05Z"), run.Job, runScope(run), string(run.Status),
+			(time.Duration(run.DurationMs) * time.Millisecond).String(), strconv.FormatInt(run.APICalls, 10),
+			strconv.Itoa(run.Fetched), strconv.Itoa(run.Saved), strconv.Itoa(run.Skipped), strconv.Itoa(run.Failed), run.Error)
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, filtered)
+}
+
+// jobFreshness is the latest run and the latest successful run of a job
+type jobFreshness struct {
+	Job                 string               `json:"job"`
+	LatestRun           *models.IngestionRun `json:"latest_run,omitempty"`
+	LatestSuccess       *models.IngestionRun `json:"latest_success,omitempty"`
+	SinceSuccessHours   *float64             `json:"since_success_hours,omitempty"`
+	ConsecutiveFailures int                  `json:"consecutive_failures"`
+}
+
+// runRunsLatest reports, per job, the latest run and how long ago data was
+// last ingested successfully
+func runRunsLatest(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("runs latest", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	days := flags.Int("days", 28, "Number of days of runs to look back")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	if *days < 1 {
+		return fmt.Errorf("days must be at least 1")
+	}
+
+	fromDate, toDate, err := resolveDateRange("", "", *days)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	runs, err := repo.GetIngestionRuns(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load ingestion runs: %w", err)
+	}
+
+	// Runs are ordered by start time, so later runs replace earlier ones
+	byJob := make(map[string]*jobFreshness)
+	for i := range runs {
+		run := &runs[i]
+		freshness, exists := byJob[run.Job]
+		if !exists {
+			freshness = &jobFreshness{Job: run.Job}
+			byJob[run.Job] = freshness
+		}
+
+		freshness.LatestRun = run
+		switch run.Status {
+		case models.RunSucceeded:
+			freshness.LatestSuccess = run
+			freshness.ConsecutiveFailures = 0
+		case models.RunFailed, models.RunPartial:
+			freshness.ConsecutiveFailures++
+		}
+	}
+
+	jobs := make([]jobFreshness, 0, len(byJob))
+	for _, freshness := range byJob {
+		jobs = append(jobs, *freshness)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Job < jobs[j].Job })
+
+	now := time.Now().UTC()
+	table := reports.NewTable("job", "latest_run", "status", "latest_success", "since_success", "consecutive_failures", "error")
+	for i := range jobs {
+		freshness := &jobs[i]
+		latestSuccess, sinceSuccess := "", ""
+		if freshness.LatestSuccess != nil {
+			since := now.Sub(freshness.LatestSuccess.FinishedAt)
+			hours := since.Hours()
+			freshness.SinceSuccessHours = &hours
+			latestSuccess = freshness.LatestSuccess.FinishedAt.UTC().Format("2006-01-02T15:04:05Z")
+			sinceSuccess = since.Round(time.Minute).String()
+		}
+		table.AddRow(freshness.Job, freshness.LatestRun.StartedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			string(freshness.LatestRun.Status), latestSuccess, sinceSuccess,
+			strconv.Itoa(freshness.ConsecutiveFailures), freshness.LatestRun.Error)
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, jobs)
+}
+
+// runScope returns the enterprise or organization a run ingested
+func runScope(run models.IngestionRun) string {
+	if run.Enterprise != "" {
+		return "ent-" + run.Enterprise
+	}
+	return "org-" + run.Organization
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/locking"
+	"github.com/go-co-op/gocron"
+	"go.uber.org/zap"
+)
+
+// Scheduled job names, also used as scheduler tags
+const (
+	jobMetrics = "metrics"
+	jobSeats   = "seats"
+	jobRollups = "rollups"
+	jobAlerts  = "alerts"
+	jobDigest  = "digest"
+)
+
+// initialRuns holds the names of the jobs whose next run is their startup run
+var initialRuns sync.Map
+
+// scheduleJob adds a job to the scheduler. The scheduler runs every job in
+// singleton mode, so a run that is still in progress delays the next one
+// instead of overlapping it, and locks skip runs another instance is doing.
+// Jitter delays the scheduled runs only, not the startup run of runInitially.
+func scheduleJob(scheduler *gocron.Scheduler, locks *locking.Runner, name string, schedule config.JobSchedule, logger *zap.Logger, run func(ctx context.Context)) error {
+	if !schedule.Enabled() {
+		logger.Info("Job not scheduled", zap.String("job", name))
+		return nil
+	}
+
+	_, err := scheduler.Cron(schedule.CronExpression()).Tag(name).Do(func() {
+		if _, initial := initialRuns.LoadAndDelete(name); !initial && schedule.Jitter > 0 {
+			delay := rand.N(schedule.Jitter)
+			logger.Debug("Delaying job", zap.String("job", name), zap.Duration("delay", delay))
+			time.Sleep(delay)
+		}
+		locks.Run(context.Background(), name, run)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Scheduled job",
+		zap.String("job", name),
+		zap.String("schedule", schedule.Expression),
+		zap.String("timezone", schedule.Timezone),
+		zap.Duration("jitter", schedule.Jitter))
+	return nil
+}
+
+// runInitially runs a scheduled job once right away, through the scheduler so
+// that the run does not overlap scheduled ones, and without jitter
+func runInitially(scheduler *gocron.Scheduler, name string) error {
+	initialRuns.Store(name, true)
+	if err := scheduler.RunByTag(name); err != nil {
+		initialRuns.Delete(name)
+		return err
+	}
+	return nil
+}
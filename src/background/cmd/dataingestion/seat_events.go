@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"go.uber.org/zap"
+)
+
+// runSeatEvents lists the seat assignment changes stored for a date range
+func runSeatEvents(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("seat-events", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	from := flags.String("from", "", "First date to include (YYYY-MM-DD, default: 7 days ago)")
+	to := flags.String("to", "", "Last date to include (YYYY-MM-DD, default: today)")
+	eventType := flags.String("type", "", "Only list events of this type, e.g. seat_added or seat_removed")
+	login := flags.String("login", "", "Only list events for this user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	fromDate, toDate, err := resolveDateRange(*from, *to, 7)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	events, err := repo.GetSeatEvents(ctx, fromDate, toDate)
+	if err != nil {
+		return fmt.Errorf("failed to load seat events: %w", err)
+	}
+
+	filtered := []models.SeatEvent{}
+	table := reports.NewTable("date", "observed_at", "type", "login", "organization", "previous_value", "new_value")
+	for _, event := range events {
+		if *eventType != "" && string(event.Type) != *eventType {
+			continue
+		}
+		if *login != "" && event.Login != *login {
+			continue
+		}
+
+		filtered = append(filtered, event)
+		table.AddRow(event.Date, event.ObservedAt.UTC().Format("2006-01-02T15:04:05Z"), string(event.Type),
+			event.Login, event.Organization, event.PreviousValue, event.NewValue)
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, filtered)
+}
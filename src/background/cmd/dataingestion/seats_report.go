@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/reports"
+	"go.uber.org/zap"
+)
+
+// runSeatsReport classifies the seats of the latest stored snapshot and
+// prints a utilization report
+func runSeatsReport(ctx context.Context, cfg *config.Config, logger *zap.Logger, args []string) error {
+	flags := flag.NewFlagSet("seats-report", flag.ContinueOnError)
+	format := flags.String("format", "table", "Output format: table, csv or json")
+	inactiveDays := flags.Int("inactive-days", cfg.SeatInactiveDays, "Days without activity before a seat is considered inactive")
+	listSeats := flags.Bool("seats", false, "List individual seats instead of the per team summary")
+	idleOnly := flags.Bool("idle-only", false, "Only list inactive and never used seats (with -seats)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := reports.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	if *inactiveDays <= 0 {
+		return fmt.Errorf("inactive-days must be positive")
+	}
+
+	repo, err := openRepository(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	snapshot, err := repo.GetLatestSeats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load seats: %w", err)
+	}
+	if snapshot == nil {
+		return fmt.Errorf("no seats snapshot stored, run the seats ingestion first")
+	}
+
+	report := analysis.AnalyzeSeatUtilization(snapshot, analysis.SeatUtilizationOptions{
+		InactiveDays: *inactiveDays,
+		Prices:       cfg.SeatPrices,
+	})
+
+	if *listSeats {
+		seats := report.Seats
+		if *idleOnly {
+			seats = []analysis.SeatUtilization{}
+			for _, seat := range report.Seats {
+				if seat.IsIdle() {
+					seats = append(seats, seat)
+				}
+			}
+		}
+
+		table := reports.NewTable("login", "organization", "team", "plan_type", "status", "last_activity_at", "last_activity_editor", "days_since_activity", "monthly_cost")
+		for _, seat := range seats {
+			lastActivity := ""
+			if seat.LastActivityAt != nil {
+				lastActivity = seat.LastActivityAt.UTC().Format("2006-01-02T15:04:05Z")
+			}
+			daysSince := ""
+			if seat.DaysSinceActivity != nil {
+				daysSince = strconv.Itoa(*seat.DaysSinceActivity)
+			}
+			table.AddRow(seat.Login, seat.Organization, seat.Team, seat.PlanType, string(seat.Status),
+				lastActivity, seat.LastActivityEditor, daysSince, formatAmount(seat.MonthlyCost))
+		}
+		return reports.Write(os.Stdout, outputFormat, table, seats)
+	}
+
+	table := reports.NewTable("organization", "team", "total_seats", "active", "inactive", "never_used", "pending_cancellation", "monthly_cost", "idle_monthly_cost")
+	rows := append(report.Groups, report.Totals)
+	for i, group := range rows {
+		organization, team := group.Organization, group.Team
+		if i == len(rows)-1 {
+			organization, team = "TOTAL", ""
+		}
+		table.AddRow(organization, team,
+			strconv.Itoa(group.TotalSeats),
+			strconv.Itoa(group.Active),
+			strconv.Itoa(group.Inactive),
+			strconv.Itoa(group.NeverUsed),
+			strconv.Itoa(group.PendingCancellation),
+			formatAmount(group.MonthlyCost),
+			formatAmount(group.IdleMonthlyCost))
+	}
+
+	return reports.Write(os.Stdout, outputFormat, table, report)
+}
+
+// formatAmount formats a currency amount with two decimals
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
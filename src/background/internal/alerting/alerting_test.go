@@ -0,0 +1,376 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// sinkRequest is a request received by a webhook sink
+type sinkRequest struct {
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// webhookSink is a local HTTP server recording the notifications posted to it
+type webhookSink struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []sinkRequest
+	failures int // Number of upcoming requests answered with an error
+}
+
+func newWebhookSink(t *testing.T) *webhookSink {
+	sink := &webhookSink{}
+	sink.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		sink.requests = append(sink.requests, sinkRequest{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+		if sink.failures > 0 {
+			sink.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(sink.Close)
+	return sink
+}
+
+// fail answers the next n requests with an error
+func (s *webhookSink) fail(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// received returns the requests received since the last call
+func (s *webhookSink) received() []sinkRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+// notifications decodes the generic webhook notifications received since the last call
+func (s *webhookSink) notifications(t *testing.T) []Notification {
+	t.Helper()
+	var notifications []Notification
+	for _, request := range s.received() {
+		var notification Notification
+		if err := json.Unmarshal(request.Body, &notification); err != nil {
+			t.Fatalf("invalid webhook payload %s: %v", request.Body, err)
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications
+}
+
+// loadTestConfig writes an alerting configuration file and loads it
+func loadTestConfig(t *testing.T, cfg string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "alerting.json")
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// newTestRepository returns an initialized SQLite repository
+func newTestRepository(t *testing.T) *repositories.SQLiteRepository {
+	repo, err := repositories.NewSQLiteRepository(filepath.Join(t.TempDir(), "metrics.db"), repositories.SaveBestEffort, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	if err := repo.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// saveEngagedUsers stores the organization metrics of a day before today
+func saveEngagedUsers(t *testing.T, repo repositories.Repository, daysAgo, engagedUsers int) {
+	t.Helper()
+	metrics := models.Metrics{
+		Date:              time.Now().UTC().AddDate(0, 0, -daysAgo).Format(dateLayout),
+		Organization:      "acme",
+		TotalEngagedUsers: engagedUsers,
+	}
+	if _, err := repo.SaveMetrics(context.Background(), []models.Metrics{metrics}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifierPayloads(t *testing.T) {
+	sink := newWebhookSink(t)
+	firedAt := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+	notification := Notification{
+		Status:       models.AlertFiring,
+		Rule:         "low-engagement",
+		Severity:     SeverityCritical,
+		Subject:      "org-acme",
+		Organization: "acme",
+		Value:        2,
+		Threshold:    5.5,
+		Message:      "2 engaged users",
+		FiredAt:      firedAt,
+	}
+
+	for _, channelType := range []string{ChannelWebhook, ChannelSlack, ChannelTeams} {
+		notifier, err := NewNotifier(ChannelConfig{
+			Name:    channelType,
+			Type:    channelType,
+			URL:     sink.URL + "/" + channelType,
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := notifier.Notify(context.Background(), notification); err != nil {
+			t.Fatalf("%s notifier: %v", channelType, err)
+		}
+	}
+
+	requests := sink.received()
+	if len(requests) != 3 {
+		t.Fatalf("sink received %d requests, want 3", len(requests))
+	}
+	for _, request := range requests {
+		if request.Header.Get("Content-Type") != "application/json" || request.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("%s request headers = %v, want JSON content and the channel headers", request.Path, request.Header)
+		}
+	}
+
+	var generic Notification
+	if err := json.Unmarshal(requests[0].Body, &generic); err != nil {
+		t.Fatal(err)
+	}
+	if generic.Rule != "low-engagement" || generic.Status != models.AlertFiring || generic.Value != 2 || !generic.FiredAt.Equal(firedAt) {
+		t.Errorf("webhook payload = %s", requests[0].Body)
+	}
+
+	var slack struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color  string `json:"color"`
+			Text   string `json:"text"`
+			Fields []struct {
+				Title string `json:"title"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(requests[1].Body, &slack); err != nil {
+		t.Fatal(err)
+	}
+	if slack.Text != "[FIRING] low-engagement: org-acme" || len(slack.Attachments) != 1 ||
+		slack.Attachments[0].Color != "#E01E5A" || slack.Attachments[0].Text != "2 engaged users" {
+		t.Errorf("slack payload = %s", requests[1].Body)
+	} else if fields := slack.Attachments[0].Fields; len(fields) != 4 || fields[2].Title != "Threshold" || fields[2].Value != "5.5" {
+		t.Errorf("slack fields = %+v", fields)
+	}
+
+	var teams struct {
+		Type       string `json:"@type"`
+		ThemeColor string `json:"themeColor"`
+		Title      string `json:"title"`
+		Sections   []struct {
+			Facts []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"facts"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal(requests[2].Body, &teams); err != nil {
+		t.Fatal(err)
+	}
+	if teams.Type != "MessageCard" || teams.ThemeColor != "E01E5A" || teams.Title != "[FIRING] low-engagement: org-acme" ||
+		len(teams.Sections) != 1 || teams.Sections[0].Facts[3].Value != "2024-06-03T08:00:00Z" {
+		t.Errorf("teams payload = %s", requests[2].Body)
+	}
+}
+
+func TestNotifierFailure(t *testing.T) {
+	sink := newWebhookSink(t)
+	sink.fail(1)
+
+	notifier, err := NewNotifier(ChannelConfig{Name: "ops", Type: ChannelWebhook, URL: sink.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), Notification{Rule: "test"}); err == nil {
+		t.Error("Notify succeeded on a 503 response")
+	}
+	if err := notifier.Notify(context.Background(), Notification{Rule: "test"}); err != nil {
+		t.Errorf("Notify after the sink recovered: %v", err)
+	}
+}
+
+func TestEngineFiresOnceAndResolves(t *testing.T) {
+	ctx := context.Background()
+	sink := newWebhookSink(t)
+	repo := newTestRepository(t)
+	cfg := loadTestConfig(t, fmt.Sprintf(`{
+		"channels": [{"name": "ops", "type": "webhook", "url": %q}],
+		"rules": [{"name": "low-engagement", "type": "engaged_users_below", "threshold": 5, "organization": "acme"}]
+	}`, sink.URL))
+	engine, err := NewEngine(cfg, repo, analysis.SeatUtilizationOptions{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saveEngagedUsers(t, repo, 2, 3)
+	engine.AfterRun(ctx, JobMetrics, nil)
+	notifications := sink.notifications(t)
+	if len(notifications) != 1 || notifications[0].Status != models.AlertFiring || notifications[0].Subject != "org-acme" || notifications[0].Value != 3 {
+		t.Fatalf("notifications after the first run = %+v, want one firing alert", notifications)
+	}
+
+	// An alert still firing is not notified again
+	engine.AfterRun(ctx, JobMetrics, nil)
+	if notifications := sink.notifications(t); len(notifications) != 0 {
+		t.Errorf("notifications of an alert still firing = %+v, want none", notifications)
+	}
+
+	// Nor after a restart, as the open alerts are reloaded
+	restarted, err := NewEngine(cfg, repo, analysis.SeatUtilizationOptions{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.AfterRun(ctx, JobMetrics, nil)
+	if notifications := sink.notifications(t); len(notifications) != 0 {
+		t.Errorf("notifications after a restart = %+v, want none", notifications)
+	}
+
+	saveEngagedUsers(t, repo, 1, 8)
+	restarted.AfterRun(ctx, JobMetrics, nil)
+	notifications = sink.notifications(t)
+	if len(notifications) != 1 || notifications[0].Status != models.AlertResolved || notifications[0].ResolvedAt == nil {
+		t.Fatalf("notifications after recovery = %+v, want one resolved alert", notifications)
+	}
+
+	today := time.Now().UTC().Format(dateLayout)
+	alerts, err := repo.GetAlerts(ctx, today, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Status != models.AlertResolved || alerts[0].Notifications != 2 {
+		t.Errorf("stored alerts = %+v, want one resolved alert notified twice", alerts)
+	}
+}
+
+func TestEngineRetriesFailedDelivery(t *testing.T) {
+	ctx := context.Background()
+	sink := newWebhookSink(t)
+	repo := newTestRepository(t)
+	cfg := loadTestConfig(t, fmt.Sprintf(`{
+		"channels": [{"name": "chat", "type": "slack", "url": %q}],
+		"rules": [{"name": "metrics-failing", "type": "ingestion_failed", "job": "metrics", "threshold": 2, "severity": "critical"}]
+	}`, sink.URL))
+	engine, err := NewEngine(cfg, repo, analysis.SeatUtilizationOptions{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runErr := errors.New("GitHub API unavailable")
+	engine.AfterRun(ctx, JobMetrics, runErr)
+	if requests := sink.received(); len(requests) != 0 {
+		t.Fatalf("sink received %d requests below the failure threshold", len(requests))
+	}
+
+	// The first delivery fails, so the next evaluation sends it again
+	sink.fail(1)
+	engine.AfterRun(ctx, JobMetrics, runErr)
+	if requests := sink.received(); len(requests) != 1 {
+		t.Fatalf("sink received %d requests when the alert fired, want 1", len(requests))
+	}
+	engine.AfterRun(ctx, JobMetrics, runErr)
+	if requests := sink.received(); len(requests) != 1 {
+		t.Fatalf("sink received %d requests after a failed delivery, want 1", len(requests))
+	}
+	engine.AfterRun(ctx, JobMetrics, runErr)
+	if requests := sink.received(); len(requests) != 0 {
+		t.Errorf("sink received %d requests once the alert was delivered, want none", len(requests))
+	}
+
+	engine.AfterRun(ctx, JobMetrics, nil)
+	requests := sink.received()
+	if len(requests) != 1 {
+		t.Fatalf("sink received %d requests when the job recovered, want 1", len(requests))
+	}
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(requests[0].Body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Text != "[RESOLVED] metrics-failing: job-metrics" {
+		t.Errorf("resolved slack message = %q", payload.Text)
+	}
+}
+
+func TestSendTest(t *testing.T) {
+	sink := newWebhookSink(t)
+	cfg := loadTestConfig(t, fmt.Sprintf(`{
+		"channels": [
+			{"name": "ops", "type": "webhook", "url": %q},
+			{"name": "chat", "type": "teams", "url": %q}
+		]
+	}`, sink.URL+"/ops", sink.URL+"/chat"))
+	engine, err := NewEngine(cfg, newTestRepository(t), analysis.SeatUtilizationOptions{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.SendTest(context.Background(), "ops", models.AlertResolved); err != nil {
+		t.Fatal(err)
+	}
+	notifications := sink.notifications(t)
+	if len(notifications) != 1 || notifications[0].Status != models.AlertResolved || notifications[0].ResolvedAt == nil {
+		t.Errorf("test notifications = %+v, want one resolved notification", notifications)
+	}
+
+	if err := engine.SendTest(context.Background(), "", models.AlertFiring); err != nil {
+		t.Fatal(err)
+	}
+	if requests := sink.received(); len(requests) != 2 {
+		t.Errorf("sink received %d requests for every channel, want 2", len(requests))
+	}
+
+	if err := engine.SendTest(context.Background(), "missing", models.AlertFiring); err == nil {
+		t.Error("SendTest to an unknown channel succeeded")
+	}
+}
+
+func TestLoadConfigRejectsUnknownChannel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerting.json")
+	cfg := `{"channels": [], "rules": [{"name": "r", "type": "engaged_users_below", "channels": ["ops"]}]}`
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("LoadConfig accepted a rule referencing an unknown channel")
+	}
+}
@@ -0,0 +1,226 @@
+package alerting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Rule types
+const (
+	// RuleAcceptanceRateBelow fires when the code completion acceptance rate
+	// (acceptances / suggestions) of the latest stored day is below the threshold
+	RuleAcceptanceRateBelow = "acceptance_rate_below"
+	// RuleEngagedUsersBelow fires when the engaged users of the latest stored
+	// day are below the threshold
+	RuleEngagedUsersBelow = "engaged_users_below"
+	// RuleIdleSeatsAbove fires when more seats than the threshold are inactive
+	// or never used in the latest seats snapshot
+	RuleIdleSeatsAbove = "idle_seats_above"
+	// RuleIngestionFailed fires when a job failed at least threshold times in a row
+	RuleIngestionFailed = "ingestion_failed"
+	// RuleAnomalyDetected fires when an anomaly of at least the rule severity
+	// was detected on the latest stored day
+	RuleAnomalyDetected = "anomaly_detected"
+)
+
+// Jobs whose runs trigger rule evaluation
+const (
+	JobMetrics = "metrics"
+	JobSeats   = "seats"
+	JobTeams   = "teams"
+)
+
+// Channel types
+const (
+	ChannelWebhook = "webhook"
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+)
+
+// Severities of a rule
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// AllTeams selects every team of a scope in a rule
+const AllTeams = "*"
+
+// Config holds the alerting channels and rules, loaded from a JSON file
+type Config struct {
+	Channels []ChannelConfig `json:"channels"`
+	Rules    []RuleConfig    `json:"rules"`
+}
+
+// ChannelConfig describes where notifications are delivered
+type ChannelConfig struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"` // webhook, slack or teams
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"` // Extra HTTP headers, e.g. for authentication
+}
+
+// RuleConfig describes an alerting rule. The scope fields select the
+// subjects the rule is evaluated for: an empty team selects the
+// organization/enterprise level, "*" every team.
+type RuleConfig struct {
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Description  string     `json:"description,omitempty"`
+	Severity     string     `json:"severity,omitempty"` // warning (default) or critical
+	Threshold    float64    `json:"threshold"`
+	Enterprise   string     `json:"enterprise,omitempty"`
+	Organization string     `json:"organization,omitempty"`
+	Team         string     `json:"team,omitempty"`
+	Job          string     `json:"job,omitempty"`       // Job of ingestion_failed rules: metrics, seats or teams
+	Overrides    []Override `json:"overrides,omitempty"` // Thresholds for specific subjects
+	Channels     []string   `json:"channels,omitempty"`  // Channels to notify; all channels when empty
+	// RepeatAfter re-sends the notification of an alert still firing after
+	// this duration, e.g. "24h"; empty notifies once
+	RepeatAfter string `json:"repeat_after,omitempty"`
+
+	repeatAfter time.Duration
+}
+
+// Override sets the threshold of a rule for an organization and/or team
+type Override struct {
+	Organization string  `json:"organization,omitempty"`
+	Team         string  `json:"team,omitempty"`
+	Threshold    float64 `json:"threshold"`
+}
+
+// LoadConfig reads and validates an alerting configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alerting configuration: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse alerting configuration: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid alerting configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+// validate checks the configuration and applies defaults
+func (c *Config) validate() error {
+	channels := make(map[string]bool, len(c.Channels))
+	for _, channel := range c.Channels {
+		if channel.Name == "" {
+			return fmt.Errorf("channel without a name")
+		}
+		if channels[channel.Name] {
+			return fmt.Errorf("duplicate channel %q", channel.Name)
+		}
+		channels[channel.Name] = true
+
+		switch channel.Type {
+		case ChannelWebhook, ChannelSlack, ChannelTeams:
+		default:
+			return fmt.Errorf("channel %q has unsupported type %q", channel.Name, channel.Type)
+		}
+		if channel.URL == "" {
+			return fmt.Errorf("channel %q has no url", channel.Name)
+		}
+	}
+
+	names := make(map[string]bool, len(c.Rules))
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule without a name")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Type {
+		case RuleAcceptanceRateBelow, RuleEngagedUsersBelow, RuleIdleSeatsAbove, RuleAnomalyDetected:
+		case RuleIngestionFailed:
+			if rule.Job != JobMetrics && rule.Job != JobSeats && rule.Job != JobTeams {
+				return fmt.Errorf("rule %q has unsupported job %q", rule.Name, rule.Job)
+			}
+			if rule.Threshold <= 0 {
+				rule.Threshold = 1
+			}
+		default:
+			return fmt.Errorf("rule %q has unsupported type %q", rule.Name, rule.Type)
+		}
+
+		if rule.Severity == "" {
+			rule.Severity = SeverityWarning
+		}
+		if rule.Severity != SeverityWarning && rule.Severity != SeverityCritical {
+			return fmt.Errorf("rule %q has unsupported severity %q", rule.Name, rule.Severity)
+		}
+
+		for _, channel := range rule.Channels {
+			if !channels[channel] {
+				return fmt.Errorf("rule %q references unknown channel %q", rule.Name, channel)
+			}
+		}
+
+		if rule.RepeatAfter != "" {
+			repeatAfter, err := time.ParseDuration(rule.RepeatAfter)
+			if err != nil || repeatAfter <= 0 {
+				return fmt.Errorf("rule %q has invalid repeat_after %q", rule.Name, rule.RepeatAfter)
+			}
+			rule.repeatAfter = repeatAfter
+		}
+	}
+
+	return nil
+}
+
+// threshold returns the threshold of the rule for a subject, taking the most
+// specific matching override
+func (r *RuleConfig) threshold(organization, team string) float64 {
+	threshold := r.Threshold
+	best := -1
+	for _, override := range r.Overrides {
+		if override.Organization != "" && override.Organization != organization {
+			continue
+		}
+		if override.Team != "" && override.Team != team {
+			continue
+		}
+		specificity := 0
+		if override.Organization != "" {
+			specificity++
+		}
+		if override.Team != "" {
+			specificity += 2
+		}
+		if specificity > best {
+			best = specificity
+			threshold = override.Threshold
+		}
+	}
+	return threshold
+}
+
+// selects reports whether the rule applies to a scope and team
+func (r *RuleConfig) selects(enterprise, organization, team string) bool {
+	if r.Enterprise != "" && r.Enterprise != enterprise {
+		return false
+	}
+	if r.Organization != "" && r.Organization != organization {
+		return false
+	}
+	switch r.Team {
+	case "":
+		return team == ""
+	case AllTeams:
+		return team != ""
+	default:
+		return r.Team == team
+	}
+}
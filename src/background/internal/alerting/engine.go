@@ -0,0 +1,519 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// dateLayout is the layout of the stored document dates
+const dateLayout = "2006-01-02"
+
+// metricsLookbackDays bounds the search for the latest stored metrics day
+const metricsLookbackDays = 7
+
+// alertHistoryDays bounds the search for alerts still firing when the engine starts
+const alertHistoryDays = 365
+
+// observation is the outcome of evaluating a rule for one subject
+type observation struct {
+	subject      string
+	enterprise   string
+	organization string
+	team         string
+	value        float64
+	threshold    float64
+	firing       bool
+	message      string
+}
+
+// Engine evaluates the alerting rules after ingestion runs, keeps one alert
+// per rule and subject while it fires, and notifies the rule's channels when
+// an alert fires, repeats or resolves
+type Engine struct {
+	cfg         *Config
+	repo        repositories.Repository
+	notifiers   map[string]Notifier
+	seatOptions analysis.SeatUtilizationOptions
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	failures map[string]int           // Consecutive failures by job
+	open     map[string]*models.Alert // Firing alerts by rule and subject
+	loaded   bool
+}
+
+// NewEngine creates an alerting engine. Seat options configure the idle
+// seat classification of idle_seats_above rules.
+func NewEngine(cfg *Config, repo repositories.Repository, seatOptions analysis.SeatUtilizationOptions, logger *zap.Logger) (*Engine, error) {
+	engine := &Engine{
+		cfg:         cfg,
+		repo:        repo,
+		notifiers:   make(map[string]Notifier, len(cfg.Channels)),
+		seatOptions: seatOptions,
+		logger:      logger,
+		failures:    make(map[string]int),
+		open:        make(map[string]*models.Alert),
+	}
+
+	for _, channel := range cfg.Channels {
+		notifier, err := NewNotifier(channel)
+		if err != nil {
+			return nil, err
+		}
+		engine.notifiers[channel.Name] = notifier
+	}
+
+	return engine, nil
+}
+
+// AfterRun records the outcome of a job run and evaluates the rules that
+// depend on it. Rules on the job's data are skipped when the run failed, as
+// the stored data did not change. Evaluation errors are logged. A nil engine
+// does nothing, so callers need not check whether alerting is configured.
+func (e *Engine) AfterRun(ctx context.Context, job string, runErr error) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	if runErr != nil {
+		e.failures[job]++
+	} else {
+		e.failures[job] = 0
+	}
+	e.mu.Unlock()
+
+	if err := e.evaluate(ctx, job, runErr == nil); err != nil {
+		e.logger.Error("Failed to evaluate alerting rules", zap.String("job", job), zap.Error(err))
+	}
+}
+
+// Evaluate evaluates the data rules against the stored data, without
+// ingestion_failed rules, e.g. to check a configuration
+func (e *Engine) Evaluate(ctx context.Context) error {
+	var errs []error
+	for _, job := range []string{JobMetrics, JobSeats} {
+		if err := e.evaluate(ctx, job, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// evaluate evaluates the rules depending on a job
+func (e *Engine) evaluate(ctx context.Context, job string, includeData bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.loadOpenAlerts(ctx); err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range e.cfg.Rules {
+		rule := &e.cfg.Rules[i]
+
+		var observations []observation
+		var err error
+		switch {
+		case rule.Type == RuleIngestionFailed && rule.Job == job:
+			observations = e.observeFailures(rule)
+		case !includeData:
+			continue
+		case job == JobMetrics && (rule.Type == RuleAcceptanceRateBelow || rule.Type == RuleEngagedUsersBelow || rule.Type == RuleAnomalyDetected):
+			observations, err = e.observeMetrics(ctx, rule)
+		case job == JobSeats && rule.Type == RuleIdleSeatsAbove:
+			observations, err = e.observeIdleSeats(ctx, rule)
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+			continue
+		}
+
+		for _, obs := range observations {
+			if err := e.apply(ctx, rule, obs); err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadOpenAlerts loads the alerts still firing from the repository once, so
+// that a restart neither repeats nor loses notifications
+func (e *Engine) loadOpenAlerts(ctx context.Context) error {
+	if e.loaded {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	alerts, err := e.repo.GetAlerts(ctx, now.AddDate(0, 0, -alertHistoryDays).Format(dateLayout), now.Format(dateLayout))
+	if err != nil {
+		return fmt.Errorf("failed to load alerts: %w", err)
+	}
+	for i := range alerts {
+		if alerts[i].Status == models.AlertFiring {
+			alert := alerts[i]
+			e.open[alertKey(alert.Rule, alert.Subject)] = &alert
+		}
+	}
+
+	e.loaded = true
+	return nil
+}
+
+// alertKey identifies the open alert of a rule and subject
+func alertKey(rule, subject string) string {
+	return rule + "|" + subject
+}
+
+// subjectName identifies a scope and team in alerts
+func subjectName(enterprise, organization, team string) string {
+	subject := "unscoped"
+	if organization != "" {
+		subject = "org-" + organization
+	} else if enterprise != "" {
+		subject = "ent-" + enterprise
+	}
+	if team != "" {
+		subject += "-team-" + team
+	}
+	return subject
+}
+
+// apply updates the alert of a rule and subject with an observation and
+// sends the resulting notification
+func (e *Engine) apply(ctx context.Context, rule *RuleConfig, obs observation) error {
+	key := alertKey(rule.Name, obs.subject)
+	alert, open := e.open[key]
+	now := time.Now().UTC()
+
+	switch {
+	case obs.firing && !open:
+		alert = &models.Alert{
+			Date:         now.Format(dateLayout),
+			Rule:         rule.Name,
+			Severity:     rule.Severity,
+			Status:       models.AlertFiring,
+			Subject:      obs.subject,
+			Enterprise:   obs.enterprise,
+			Organization: obs.organization,
+			Team:         obs.team,
+			FiredAt:      now,
+		}
+		alert.ID = alert.GetID()
+		e.open[key] = alert
+	case obs.firing:
+		// Still firing: notify again only when a previous delivery failed or
+		// the repeat interval elapsed
+		if alert.LastNotifiedAt != nil && (rule.repeatAfter == 0 || now.Sub(*alert.LastNotifiedAt) < rule.repeatAfter) {
+			alert.Value = obs.value
+			alert.Message = obs.message
+			return e.repo.SaveAlert(ctx, alert)
+		}
+	case open:
+		alert.Status = models.AlertResolved
+		alert.ResolvedAt = &now
+		delete(e.open, key)
+	default:
+		return nil
+	}
+
+	alert.Value = obs.value
+	alert.Threshold = obs.threshold
+	alert.Message = obs.message
+
+	log := e.logger.Warn
+	if alert.Status == models.AlertResolved {
+		log = e.logger.Info
+	}
+	log("Alert "+string(alert.Status),
+		zap.String("rule", alert.Rule),
+		zap.String("subject", alert.Subject),
+		zap.String("severity", alert.Severity),
+		zap.String("message", alert.Message))
+
+	if err := e.notify(ctx, rule, newNotification(alert, rule)); err != nil {
+		e.logger.Error("Failed to deliver alert notification", zap.String("rule", alert.Rule), zap.Error(err))
+	} else {
+		alert.LastNotifiedAt = &now
+		alert.Notifications++
+	}
+
+	return e.repo.SaveAlert(ctx, alert)
+}
+
+// notify delivers a notification to the channels of a rule, or to every
+// channel when the rule names none
+func (e *Engine) notify(ctx context.Context, rule *RuleConfig, notification Notification) error {
+	channels := rule.Channels
+	if len(channels) == 0 {
+		for _, channel := range e.cfg.Channels {
+			channels = append(channels, channel.Name)
+		}
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		if err := e.notifiers[channel].Notify(ctx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendTest delivers a sample notification to a channel, or to every channel
+// when name is empty
+func (e *Engine) SendTest(ctx context.Context, name string, status models.AlertStatus) error {
+	now := time.Now().UTC()
+	notification := Notification{
+		Status:      status,
+		Rule:        "test",
+		Description: "Test notification sent by the alerts test command",
+		Severity:    SeverityWarning,
+		Subject:     "test",
+		Value:       1,
+		Threshold:   0,
+		Message:     "This is a test notification",
+		FiredAt:     now,
+	}
+	if status == models.AlertResolved {
+		notification.ResolvedAt = &now
+	}
+
+	channels := []string{}
+	for _, channel := range e.cfg.Channels {
+		if name == "" || channel.Name == name {
+			channels = append(channels, channel.Name)
+		}
+	}
+	if len(channels) == 0 {
+		return fmt.Errorf("unknown channel: %s", name)
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		if err := e.notifiers[channel].Notify(ctx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// observeFailures observes the consecutive failures of a job
+func (e *Engine) observeFailures(rule *RuleConfig) []observation {
+	failures := float64(e.failures[rule.Job])
+	return []observation{{
+		subject:   "job-" + rule.Job,
+		value:     failures,
+		threshold: rule.Threshold,
+		firing:    failures >= rule.Threshold,
+		message:   fmt.Sprintf("%s ingestion failed %d time(s) in a row", rule.Job, int(failures)),
+	}}
+}
+
+// latestMetrics returns the metrics of the latest stored day of every scope and team
+func (e *Engine) latestMetrics(ctx context.Context) ([]models.Metrics, error) {
+	now := time.Now().UTC()
+	metrics, err := e.repo.GetMetrics(ctx, now.AddDate(0, 0, -metricsLookbackDays).Format(dateLayout), now.Format(dateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics: %w", err)
+	}
+
+	latest := make(map[string]models.Metrics)
+	for _, metric := range metrics {
+		subject := subjectName(metric.Enterprise, metric.Organization, metric.Team)
+		if current, exists := latest[subject]; !exists || metric.Date > current.Date {
+			latest[subject] = metric
+		}
+	}
+
+	result := make([]models.Metrics, 0, len(latest))
+	for _, metric := range latest {
+		result = append(result, metric)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return subjectName(result[i].Enterprise, result[i].Organization, result[i].Team) <
+			subjectName(result[j].Enterprise, result[j].Organization, result[j].Team)
+	})
+	return result, nil
+}
+
+// observeMetrics observes a metrics rule on the latest stored day of every selected subject
+func (e *Engine) observeMetrics(ctx context.Context, rule *RuleConfig) ([]observation, error) {
+	metrics, err := e.latestMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var anomaliesByDay map[string][]models.Anomaly
+	if rule.Type == RuleAnomalyDetected {
+		anomaliesByDay, err = e.recentAnomalies(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var observations []observation
+	for i := range metrics {
+		m := &metrics[i]
+		if !rule.selects(m.Enterprise, m.Organization, m.Team) {
+			continue
+		}
+
+		obs := observation{
+			subject:      subjectName(m.Enterprise, m.Organization, m.Team),
+			enterprise:   m.Enterprise,
+			organization: m.Organization,
+			team:         m.Team,
+			threshold:    rule.threshold(m.Organization, m.Team),
+		}
+
+		switch rule.Type {
+		case RuleAcceptanceRateBelow:
+			suggestions, acceptances := codeCompletionTotals(m)
+			if suggestions == 0 {
+				continue
+			}
+			obs.value = float64(acceptances) / float64(suggestions)
+			obs.firing = obs.value < obs.threshold
+			obs.message = fmt.Sprintf("Acceptance rate on %s is %.1f%% (%d of %d suggestions), threshold %.1f%%",
+				m.Date, obs.value*100, acceptances, suggestions, obs.threshold*100)
+		case RuleEngagedUsersBelow:
+			obs.value = float64(m.TotalEngagedUsers)
+			obs.firing = obs.value < obs.threshold
+			obs.message = fmt.Sprintf("%d engaged users on %s, threshold %s", m.TotalEngagedUsers, m.Date, formatNumber(obs.threshold))
+		case RuleAnomalyDetected:
+			if obs.threshold <= 0 {
+				obs.threshold = 1
+			}
+			var found []string
+			for _, a := range anomaliesByDay[m.Date] {
+				if a.Enterprise == m.Enterprise && a.Organization == m.Organization && a.Team == m.Team &&
+					(rule.Severity != SeverityCritical || a.Severity == SeverityCritical) {
+					metric := a.Metric
+					if a.Editor != "" {
+						metric += " (" + a.Editor + ")"
+					}
+					found = append(found, fmt.Sprintf("%s %s %s", metric, a.Direction, formatNumber(a.Value)))
+				}
+			}
+			obs.value = float64(len(found))
+			obs.firing = obs.value >= obs.threshold
+			obs.message = fmt.Sprintf("%d anomalies on %s", len(found), m.Date)
+			if len(found) > 0 {
+				obs.message += ": " + strings.Join(found, ", ")
+			}
+		}
+
+		observations = append(observations, obs)
+	}
+	return observations, nil
+}
+
+// recentAnomalies returns the anomalies of the metrics lookback window by date
+func (e *Engine) recentAnomalies(ctx context.Context) (map[string][]models.Anomaly, error) {
+	now := time.Now().UTC()
+	anomalies, err := e.repo.GetAnomalies(ctx, now.AddDate(0, 0, -metricsLookbackDays).Format(dateLayout), now.Format(dateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load anomalies: %w", err)
+	}
+
+	byDay := make(map[string][]models.Anomaly)
+	for _, a := range anomalies {
+		byDay[a.Date] = append(byDay[a.Date], a)
+	}
+	return byDay, nil
+}
+
+// codeCompletionTotals sums the code suggestions and acceptances of metrics
+func codeCompletionTotals(m *models.Metrics) (suggestions, acceptances int) {
+	if m.CopilotIdeCodeCompletions == nil {
+		return 0, 0
+	}
+	for _, editor := range m.CopilotIdeCodeCompletions.Editors {
+		for _, model := range editor.Models {
+			for _, language := range model.Languages {
+				suggestions += language.TotalCodeSuggestions
+				acceptances += language.TotalCodeAcceptances
+			}
+		}
+	}
+	return suggestions, acceptances
+}
+
+// observeIdleSeats observes the idle seats of the latest seats snapshot, per
+// organization or per assigning team
+func (e *Engine) observeIdleSeats(ctx context.Context, rule *RuleConfig) ([]observation, error) {
+	snapshot, err := e.repo.GetLatestSeats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load seats: %w", err)
+	}
+	if snapshot == nil {
+		return nil, nil
+	}
+
+	opts := e.seatOptions
+	opts.Now = time.Now().UTC()
+	report := analysis.AnalyzeSeatUtilization(snapshot, opts)
+
+	// Team groups are keyed by assigning team; organization groups sum them
+	groups := make(map[string]*analysis.SeatUtilizationGroup)
+	var keys []string
+	for _, group := range report.Groups {
+		team := group.Team
+		if team == analysis.DirectAssignment {
+			team = ""
+		}
+		if rule.Team == "" {
+			team = ""
+		}
+
+		key := group.Organization + "/" + team
+		total, exists := groups[key]
+		if !exists {
+			total = &analysis.SeatUtilizationGroup{Organization: group.Organization, Team: team}
+			groups[key] = total
+			keys = append(keys, key)
+		}
+		total.TotalSeats += group.TotalSeats
+		total.Inactive += group.Inactive
+		total.NeverUsed += group.NeverUsed
+	}
+	sort.Strings(keys)
+
+	var observations []observation
+	for _, key := range keys {
+		group := groups[key]
+		if !rule.selects(snapshot.Enterprise, group.Organization, group.Team) {
+			continue
+		}
+
+		idle := group.Inactive + group.NeverUsed
+		threshold := rule.threshold(group.Organization, group.Team)
+		observations = append(observations, observation{
+			subject:      subjectName(snapshot.Enterprise, group.Organization, group.Team),
+			enterprise:   snapshot.Enterprise,
+			organization: group.Organization,
+			team:         group.Team,
+			value:        float64(idle),
+			threshold:    threshold,
+			firing:       float64(idle) > threshold,
+			message: fmt.Sprintf("%d of %d seats idle on %s (%d inactive for %d+ days, %d never used), threshold %s",
+				idle, group.TotalSeats, snapshot.Date, group.Inactive, opts.InactiveDays, group.NeverUsed, formatNumber(threshold)),
+		})
+	}
+	return observations, nil
+}
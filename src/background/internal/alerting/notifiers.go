@@ -0,0 +1,206 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// Notification is the message sent when an alert fires or resolves
+type Notification struct {
+	Status       models.AlertStatus `json:"status"`
+	Rule         string             `json:"rule"`
+	Description  string             `json:"description,omitempty"`
+	Severity     string             `json:"severity"`
+	Subject      string             `json:"subject"`
+	Enterprise   string             `json:"enterprise,omitempty"`
+	Organization string             `json:"organization,omitempty"`
+	Team         string             `json:"team,omitempty"`
+	Value        float64            `json:"value"`
+	Threshold    float64            `json:"threshold"`
+	Message      string             `json:"message"`
+	FiredAt      time.Time          `json:"fired_at"`
+	ResolvedAt   *time.Time         `json:"resolved_at,omitempty"`
+}
+
+// newNotification builds the notification of an alert
+func newNotification(alert *models.Alert, rule *RuleConfig) Notification {
+	return Notification{
+		Status:       alert.Status,
+		Rule:         alert.Rule,
+		Description:  rule.Description,
+		Severity:     alert.Severity,
+		Subject:      alert.Subject,
+		Enterprise:   alert.Enterprise,
+		Organization: alert.Organization,
+		Team:         alert.Team,
+		Value:        alert.Value,
+		Threshold:    alert.Threshold,
+		Message:      alert.Message,
+		FiredAt:      alert.FiredAt,
+		ResolvedAt:   alert.ResolvedAt,
+	}
+}
+
+// title returns a one-line summary of the notification
+func (n *Notification) title() string {
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(string(n.Status)), n.Rule, n.Subject)
+}
+
+// Notifier delivers notifications to a channel
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// httpNotifier posts a channel specific JSON payload to a URL
+type httpNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	payload func(notification Notification) interface{}
+}
+
+// NewNotifier creates the notifier of a channel
+func NewNotifier(channel ChannelConfig) (Notifier, error) {
+	notifier := &httpNotifier{
+		url:     channel.URL,
+		headers: channel.Headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+
+	switch channel.Type {
+	case ChannelWebhook:
+		notifier.payload = func(notification Notification) interface{} { return notification }
+	case ChannelSlack:
+		notifier.payload = slackPayload
+	case ChannelTeams:
+		notifier.payload = teamsPayload
+	default:
+		return nil, fmt.Errorf("unsupported channel type: %s", channel.Type)
+	}
+	return notifier, nil
+}
+
+// Notify posts the notification, failing on any non-2xx response
+func (n *httpNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(n.payload(notification))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range n.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// statusColor returns the color of a notification for chat payloads
+func statusColor(notification Notification) string {
+	switch {
+	case notification.Status == models.AlertResolved:
+		return "2EB67D"
+	case notification.Severity == SeverityCritical:
+		return "E01E5A"
+	default:
+		return "ECB22E"
+	}
+}
+
+// facts returns the labelled details of a notification for chat payloads
+func facts(notification Notification) [][2]string {
+	result := [][2]string{
+		{"Severity", notification.Severity},
+		{"Value", formatNumber(notification.Value)},
+		{"Threshold", formatNumber(notification.Threshold)},
+		{"Fired at", notification.FiredAt.UTC().Format(time.RFC3339)},
+	}
+	if notification.ResolvedAt != nil {
+		result = append(result, [2]string{"Resolved at", notification.ResolvedAt.UTC().Format(time.RFC3339)})
+	}
+	return result
+}
+
+// formatNumber formats a value without trailing zeros
+func formatNumber(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.4f", value), "0"), ".")
+}
+
+// slackPayload builds a Slack-compatible incoming webhook message
+func slackPayload(notification Notification) interface{} {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	type attachment struct {
+		Color    string  `json:"color"`
+		Title    string  `json:"title"`
+		Text     string  `json:"text"`
+		Fields   []field `json:"fields"`
+		Fallback string  `json:"fallback"`
+	}
+
+	fields := []field{}
+	for _, fact := range facts(notification) {
+		fields = append(fields, field{Title: fact[0], Value: fact[1], Short: true})
+	}
+
+	return map[string]interface{}{
+		"text": notification.title(),
+		"attachments": []attachment{{
+			Color:    "#" + statusColor(notification),
+			Title:    notification.title(),
+			Text:     notification.Message,
+			Fields:   fields,
+			Fallback: notification.title() + ": " + notification.Message,
+		}},
+	}
+}
+
+// teamsPayload builds a Microsoft Teams-compatible MessageCard
+func teamsPayload(notification Notification) interface{} {
+	type fact struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	cardFacts := []fact{}
+	for _, f := range facts(notification) {
+		cardFacts = append(cardFacts, fact{Name: f[0], Value: f[1]})
+	}
+
+	return map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    notification.title(),
+		"themeColor": statusColor(notification),
+		"title":      notification.title(),
+		"text":       notification.Message,
+		"sections": []map[string]interface{}{{
+			"facts": cardFacts,
+		}},
+	}
+}
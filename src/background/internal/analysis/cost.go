@@ -0,0 +1,333 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// ProrationRule defines how monthly seat prices are attributed to days
+type ProrationRule string
+
+const (
+	// ProrationDaily charges each day a seat is held at the monthly price
+	// divided by the number of days in the month
+	ProrationDaily ProrationRule = "daily"
+	// ProrationFullMonth charges the full monthly price for every seat held
+	// at any point during the month, on the first day it is observed
+	ProrationFullMonth ProrationRule = "full_month"
+)
+
+// ParseProrationRule converts a proration rule name to a ProrationRule
+func ParseProrationRule(name string) (ProrationRule, error) {
+	switch ProrationRule(name) {
+	case ProrationDaily, ProrationFullMonth:
+		return ProrationRule(name), nil
+	default:
+		return "", fmt.Errorf("unsupported proration rule: %s", name)
+	}
+}
+
+// CostLevel defines the aggregation level of a cost entry
+type CostLevel string
+
+const (
+	CostLevelTotal        CostLevel = "total"
+	CostLevelOrganization CostLevel = "organization"
+	CostLevelTeam         CostLevel = "team"
+)
+
+// CostOptions controls how seat spend is computed
+type CostOptions struct {
+	Prices    map[string]float64 // Monthly price per seat keyed by plan type
+	Proration ProrationRule
+}
+
+// CostEntry is the seat spend of a group for a day or month
+type CostEntry struct {
+	Period             string    `json:"period"`
+	Level              CostLevel `json:"level"`
+	Organization       string    `json:"organization,omitempty"`
+	Team               string    `json:"team,omitempty"`
+	Seats              int       `json:"seats"`
+	Spend              float64   `json:"spend"`
+	EngagedUsers       float64   `json:"engaged_users"`
+	CostPerEngagedUser *float64  `json:"cost_per_engaged_user,omitempty"`
+}
+
+// CostReport holds daily and monthly seat spend
+type CostReport struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Proration ProrationRule `json:"proration"`
+	Daily     []CostEntry   `json:"daily"`
+	Monthly   []CostEntry   `json:"monthly"`
+}
+
+// costKey identifies a cost entry within a period
+type costKey struct {
+	period       string
+	level        CostLevel
+	organization string
+	team         string
+}
+
+// costAccumulator collects seat spend and engaged users per group and period
+type costAccumulator struct {
+	entries map[costKey]*CostEntry
+	// engagedDays tracks the number of days with engaged user data per
+	// monthly entry, to average engaged users over the month
+	engagedDays map[costKey]int
+}
+
+func newCostAccumulator() *costAccumulator {
+	return &costAccumulator{
+		entries:     make(map[costKey]*CostEntry),
+		engagedDays: make(map[costKey]int),
+	}
+}
+
+// entry returns the entry for a key, creating it if needed
+func (a *costAccumulator) entry(key costKey) *CostEntry {
+	entry, exists := a.entries[key]
+	if !exists {
+		entry = &CostEntry{
+			Period:       key.period,
+			Level:        key.level,
+			Organization: key.organization,
+			Team:         key.team,
+		}
+		a.entries[key] = entry
+	}
+	return entry
+}
+
+// result returns the entries sorted by period, level and group
+func (a *costAccumulator) result() []CostEntry {
+	result := make([]CostEntry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		if entry.EngagedUsers > 0 {
+			costPerUser := entry.Spend / entry.EngagedUsers
+			entry.CostPerEngagedUser = &costPerUser
+		}
+		result = append(result, *entry)
+	}
+
+	levelOrder := map[CostLevel]int{CostLevelTotal: 0, CostLevelOrganization: 1, CostLevelTeam: 2}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Period != result[j].Period {
+			return result[i].Period < result[j].Period
+		}
+		if result[i].Level != result[j].Level {
+			return levelOrder[result[i].Level] < levelOrder[result[j].Level]
+		}
+		if result[i].Organization != result[j].Organization {
+			return result[i].Organization < result[j].Organization
+		}
+		return result[i].Team < result[j].Team
+	})
+
+	return result
+}
+
+// groupKeys returns the total, organization and team keys a seat contributes to
+func groupKeys(period, organization, team string) []costKey {
+	return []costKey{
+		{period: period, level: CostLevelTotal},
+		{period: period, level: CostLevelOrganization, organization: organization},
+		{period: period, level: CostLevelTeam, organization: organization, team: team},
+	}
+}
+
+// ComputeSeatCosts computes daily and monthly seat spend between two dates
+// from the stored seats history. Days without a snapshot reuse the previous
+// snapshot, up to the last stored snapshot; the history may include the
+// snapshot in effect before from, which covers the first days. Engaged users
+// are taken from the metrics of the same day: organization/enterprise level
+// metrics for the total and organization entries, and team metrics for team
+// entries. Monthly engaged users are the average over the days with metrics.
+func ComputeSeatCosts(history []models.CopilotAssignedSeats, metrics []models.Metrics, from, to string, opts CostOptions) (*CostReport, error) {
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date: %w", err)
+	}
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date: %w", err)
+	}
+
+	proration := opts.Proration
+	if proration == "" {
+		proration = ProrationDaily
+	}
+
+	// Combine the snapshots of each day, as several scopes may be stored
+	snapshotsByDate := make(map[string][]*models.CopilotAssignedSeats)
+	lastSnapshotDate := ""
+	for i := range history {
+		snapshot := &history[i]
+		snapshotsByDate[snapshot.Date] = append(snapshotsByDate[snapshot.Date], snapshot)
+		if snapshot.Date > lastSnapshotDate {
+			lastSnapshotDate = snapshot.Date
+		}
+	}
+
+	engagedByDate := engagedUsersByDate(metrics)
+
+	daily := newCostAccumulator()
+	monthly := newCostAccumulator()
+	// chargedInMonth tracks seats already charged under full month proration
+	chargedInMonth := make(map[string]map[seatKey]bool)
+
+	// Start from the latest snapshot before the range, if any
+	var current []*models.CopilotAssignedSeats
+	previousDate := ""
+	for date, snapshots := range snapshotsByDate {
+		if date < from && date > previousDate {
+			current, previousDate = snapshots, date
+		}
+	}
+
+	for day := fromDate; !day.After(toDate); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if date > lastSnapshotDate {
+			break
+		}
+		if snapshots, exists := snapshotsByDate[date]; exists {
+			current = snapshots
+		}
+		if current == nil {
+			continue
+		}
+
+		month := day.Format("2006-01")
+		daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if chargedInMonth[month] == nil {
+			chargedInMonth[month] = make(map[seatKey]bool)
+		}
+
+		// Groups seen on this day, and the seats they held for the monthly maximum
+		dailyKeys := make(map[costKey]bool)
+		seatsHeld := make(map[costKey]int)
+
+		for _, snapshot := range current {
+			for i := range snapshot.Seats {
+				seat := &snapshot.Seats[i]
+				organization := SeatOrganization(seat, snapshot)
+				team := SeatTeam(seat)
+				price := SeatMonthlyPrice(opts.Prices, seat.PlanType)
+
+				var spend float64
+				switch proration {
+				case ProrationFullMonth:
+					key := seatKey{organization, seat.Assignee.Login}
+					if !chargedInMonth[month][key] {
+						chargedInMonth[month][key] = true
+						spend = price
+					}
+				default:
+					spend = price / float64(daysInMonth)
+				}
+
+				for _, key := range groupKeys(date, organization, team) {
+					dailyKeys[key] = true
+					entry := daily.entry(key)
+					entry.Seats++
+					entry.Spend += spend
+				}
+
+				for _, key := range groupKeys(month, organization, team) {
+					monthly.entry(key).Spend += spend
+					seatsHeld[key]++
+				}
+			}
+		}
+
+		for key, seats := range seatsHeld {
+			if entry := monthly.entry(key); seats > entry.Seats {
+				entry.Seats = seats
+			}
+		}
+
+		// Join engaged users for the day
+		for key := range dailyKeys {
+			engaged, found := engagedByDate[date].lookup(key)
+			if !found {
+				continue
+			}
+			daily.entry(key).EngagedUsers = float64(engaged)
+
+			monthlyKey := key
+			monthlyKey.period = month
+			monthly.entry(monthlyKey).EngagedUsers += float64(engaged)
+			monthly.engagedDays[monthlyKey]++
+		}
+	}
+
+	for key, days := range monthly.engagedDays {
+		monthly.entries[key].EngagedUsers /= float64(days)
+	}
+
+	return &CostReport{
+		From:      from,
+		To:        to,
+		Proration: proration,
+		Daily:     daily.result(),
+		Monthly:   monthly.result(),
+	}, nil
+}
+
+// engagedUsers holds the engaged users of a day per scope and team
+type engagedUsers struct {
+	total          int
+	hasTotal       bool
+	byOrganization map[string]int
+	byTeam         map[string]int
+}
+
+// lookup returns the engaged users matching a cost entry key
+func (e *engagedUsers) lookup(key costKey) (int, bool) {
+	if e == nil {
+		return 0, false
+	}
+
+	switch key.level {
+	case CostLevelTotal:
+		return e.total, e.hasTotal
+	case CostLevelOrganization:
+		engaged, found := e.byOrganization[key.organization]
+		return engaged, found
+	default:
+		engaged, found := e.byTeam[key.team]
+		return engaged, found
+	}
+}
+
+// engagedUsersByDate indexes the engaged users of the metrics by date
+func engagedUsersByDate(metrics []models.Metrics) map[string]*engagedUsers {
+	result := make(map[string]*engagedUsers)
+	for _, metric := range metrics {
+		day, exists := result[metric.Date]
+		if !exists {
+			day = &engagedUsers{
+				byOrganization: make(map[string]int),
+				byTeam:         make(map[string]int),
+			}
+			result[metric.Date] = day
+		}
+
+		if metric.Team != "" {
+			day.byTeam[metric.Team] += metric.TotalEngagedUsers
+			continue
+		}
+
+		day.total += metric.TotalEngagedUsers
+		day.hasTotal = true
+		if metric.Organization != "" {
+			day.byOrganization[metric.Organization] += metric.TotalEngagedUsers
+		}
+	}
+	return result
+}
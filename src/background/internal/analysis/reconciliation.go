@@ -0,0 +1,233 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// Reconciliation flags raised for a day and surface
+const (
+	// FlagTeamExceedsTotal is raised when a single team reports more users
+	// than its organization/enterprise
+	FlagTeamExceedsTotal = "team_exceeds_total"
+	// FlagLowCoverage is raised when the teams together, even without any
+	// overlap, account for less than the minimum share of the total
+	FlagLowCoverage = "low_coverage"
+	// FlagMissingTotal is raised when teams report activity for a day without
+	// organization/enterprise metrics
+	FlagMissingTotal = "missing_total"
+)
+
+// Surfaces compared by the reconciliation, in report order
+var ReconciliationSurfaces = []string{
+	"active_users",
+	"engaged_users",
+	"copilot_ide_code_completions",
+	"copilot_ide_chat",
+	"copilot_dotcom_chat",
+	"copilot_dotcom_pull_requests",
+}
+
+// ReconciliationOptions controls how team metrics are reconciled
+type ReconciliationOptions struct {
+	// MinCoverage is the minimum share of the total the teams are expected
+	// to account for, between 0 and 1
+	MinCoverage float64
+}
+
+// ReconciliationEntry compares the users of a surface on a day for an
+// organization/enterprise with those reported by its teams. Teams overlap, so
+// the users covered by the teams lie between TeamMax and TeamSum.
+type ReconciliationEntry struct {
+	Date         string `json:"date"`
+	Enterprise   string `json:"enterprise,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	Surface      string `json:"surface"`
+	Total        int    `json:"total"`
+	HasTotal     bool   `json:"has_total"`
+	Teams        int    `json:"teams"`
+	TeamSum      int    `json:"team_sum"`
+	TeamMax      int    `json:"team_max"`
+	MaxTeam      string `json:"max_team,omitempty"`
+	// SuppressedTeams counts the teams without metrics for the day, because
+	// of the privacy threshold or because they were not found
+	SuppressedTeams int      `json:"suppressed_teams"`
+	MinCoverage     *float64 `json:"min_coverage,omitempty"` // TeamMax relative to the total
+	MaxCoverage     *float64 `json:"max_coverage,omitempty"` // TeamSum relative to the total, capped at 1
+	Flags           []string `json:"flags"`
+}
+
+// IsFlagged reports whether the entry raised any flag
+func (e *ReconciliationEntry) IsFlagged() bool {
+	return len(e.Flags) > 0
+}
+
+// reconciliationKey identifies the day and scope of a reconciliation entry
+type reconciliationKey struct {
+	date         string
+	enterprise   string
+	organization string
+}
+
+// surfaceUsers returns the users of every reconciled surface of metrics. A
+// surface missing from the metrics counts as zero users.
+func surfaceUsers(m *models.Metrics) map[string]int {
+	users := map[string]int{
+		"active_users":                 m.TotalActiveUsers,
+		"engaged_users":                m.TotalEngagedUsers,
+		"copilot_ide_code_completions": 0,
+		"copilot_ide_chat":             0,
+		"copilot_dotcom_chat":          0,
+		"copilot_dotcom_pull_requests": 0,
+	}
+	if m.CopilotIdeCodeCompletions != nil {
+		users["copilot_ide_code_completions"] = m.CopilotIdeCodeCompletions.TotalEngagedUsers
+	}
+	if m.IdeChat != nil {
+		users["copilot_ide_chat"] = m.IdeChat.TotalEngagedUsers
+	}
+	if m.DotComChat != nil {
+		users["copilot_dotcom_chat"] = m.DotComChat.TotalEngagedUsers
+	}
+	if m.DotComPullRequests != nil {
+		users["copilot_dotcom_pull_requests"] = m.DotComPullRequests.TotalEngagedUsers
+	}
+	return users
+}
+
+// ReconcileTeamMetrics compares, for each day, scope and surface, the
+// organization/enterprise totals with the sum and the maximum of the teams.
+// Markers of team days without metrics are counted as suppressed teams. Days
+// without team metrics or markers are not reported.
+func ReconcileTeamMetrics(metrics []models.Metrics, markers []models.MetricsMarker, opts ReconciliationOptions) []ReconciliationEntry {
+	entries := make(map[reconciliationKey]map[string]*ReconciliationEntry)
+	hasTeams := make(map[reconciliationKey]bool)
+
+	entry := func(key reconciliationKey, surface string) *ReconciliationEntry {
+		surfaces, exists := entries[key]
+		if !exists {
+			surfaces = make(map[string]*ReconciliationEntry)
+			entries[key] = surfaces
+		}
+		e, exists := surfaces[surface]
+		if !exists {
+			e = &ReconciliationEntry{
+				Date:         key.date,
+				Enterprise:   key.enterprise,
+				Organization: key.organization,
+				Surface:      surface,
+				Flags:        []string{},
+			}
+			surfaces[surface] = e
+		}
+		return e
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+		key := reconciliationKey{date: m.Date, enterprise: m.Enterprise, organization: m.Organization}
+		if m.Team != "" {
+			hasTeams[key] = true
+		}
+
+		for surface, users := range surfaceUsers(m) {
+			e := entry(key, surface)
+			if m.Team == "" {
+				e.Total = users
+				e.HasTotal = true
+				continue
+			}
+
+			e.Teams++
+			e.TeamSum += users
+			if users > e.TeamMax {
+				e.TeamMax = users
+				e.MaxTeam = m.Team
+			}
+		}
+	}
+
+	reported := make(map[string]bool, len(metrics))
+	for i := range metrics {
+		reported[metrics[i].GetID()] = true
+	}
+	for i := range markers {
+		if reported[markers[i].GetID()] {
+			continue
+		}
+		key := reconciliationKey{date: markers[i].Date, enterprise: markers[i].Enterprise, organization: markers[i].Organization}
+		hasTeams[key] = true
+		for _, surface := range ReconciliationSurfaces {
+			entry(key, surface).SuppressedTeams++
+		}
+	}
+
+	var result []ReconciliationEntry
+	for key, surfaces := range entries {
+		if !hasTeams[key] {
+			continue
+		}
+		for _, surface := range ReconciliationSurfaces {
+			e, exists := surfaces[surface]
+			if !exists {
+				continue
+			}
+			evaluateReconciliation(e, opts)
+			result = append(result, *e)
+		}
+	}
+
+	surfaceOrder := make(map[string]int, len(ReconciliationSurfaces))
+	for i, surface := range ReconciliationSurfaces {
+		surfaceOrder[surface] = i
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Enterprise != b.Enterprise {
+			return a.Enterprise < b.Enterprise
+		}
+		if a.Organization != b.Organization {
+			return a.Organization < b.Organization
+		}
+		return surfaceOrder[a.Surface] < surfaceOrder[b.Surface]
+	})
+
+	return result
+}
+
+// evaluateReconciliation computes the coverage of an entry and raises its flags
+func evaluateReconciliation(e *ReconciliationEntry, opts ReconciliationOptions) {
+	if !e.HasTotal {
+		if e.TeamSum > 0 {
+			e.Flags = append(e.Flags, FlagMissingTotal)
+		}
+		return
+	}
+
+	if e.TeamMax > e.Total {
+		e.Flags = append(e.Flags, FlagTeamExceedsTotal)
+	}
+
+	if e.Total == 0 {
+		return
+	}
+
+	minCoverage := float64(e.TeamMax) / float64(e.Total)
+	maxCoverage := float64(e.TeamSum) / float64(e.Total)
+	if minCoverage > 1 {
+		minCoverage = 1
+	}
+	if maxCoverage > 1 {
+		maxCoverage = 1
+	}
+	e.MinCoverage = &minCoverage
+	e.MaxCoverage = &maxCoverage
+
+	if maxCoverage < opts.MinCoverage {
+		e.Flags = append(e.Flags, FlagLowCoverage)
+	}
+}
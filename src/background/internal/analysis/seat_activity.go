@@ -0,0 +1,181 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// ExtractSeatActivity returns the seat activity observed in the current
+// snapshot that differs from the previous snapshot. Without a previous
+// snapshot the last activity of every seat is returned.
+func ExtractSeatActivity(previous, current *models.CopilotAssignedSeats) []models.SeatActivity {
+	if current == nil {
+		return nil
+	}
+
+	var previousSeats map[seatKey]*models.Seat
+	if previous != nil {
+		previousSeats = indexSeats(previous)
+	}
+
+	var activity []models.SeatActivity
+	for i := range current.Seats {
+		seat := &current.Seats[i]
+		if seat.LastActivityAt == nil || seat.LastActivityAt.IsZero() {
+			continue
+		}
+
+		organization := SeatOrganization(seat, current)
+		if previousSeat, exists := previousSeats[seatKey{organization, seat.Assignee.Login}]; exists &&
+			previousSeat.LastActivityAt != nil &&
+			previousSeat.LastActivityAt.Equal(*seat.LastActivityAt) &&
+			previousSeat.LastActivityEditor == seat.LastActivityEditor {
+			continue
+		}
+
+		entry := models.SeatActivity{
+			Date:               seat.LastActivityAt.UTC().Format("2006-01-02"),
+			Login:              seat.Assignee.Login,
+			Enterprise:         current.Enterprise,
+			Organization:       organization,
+			Team:               SeatTeam(seat),
+			LastActivityAt:     seat.LastActivityAt.UTC(),
+			LastActivityEditor: seat.LastActivityEditor,
+			ObservedAt:         current.LastUpdate,
+		}
+		entry.ID = entry.GetID()
+		activity = append(activity, entry)
+	}
+
+	return activity
+}
+
+// Granularity defines the period length used to count active users
+type Granularity string
+
+const (
+	GranularityDay  Granularity = "day"
+	GranularityWeek Granularity = "week"
+)
+
+// ParseGranularity converts a granularity name to a Granularity
+func ParseGranularity(name string) (Granularity, error) {
+	switch Granularity(name) {
+	case GranularityDay, GranularityWeek:
+		return Granularity(name), nil
+	default:
+		return "", fmt.Errorf("unsupported granularity: %s", name)
+	}
+}
+
+// Period returns the label of the period containing the given time: the
+// date for daily periods and the ISO week (e.g. 2024-W25) for weekly periods
+func (g Granularity) Period(t time.Time) string {
+	if g == GranularityWeek {
+		year, week := t.UTC().ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// TeamActiveUsers is the number of distinct active users of a team in a period
+type TeamActiveUsers struct {
+	Period      string `json:"period"`
+	Team        string `json:"team"`
+	ActiveUsers int    `json:"active_users"`
+}
+
+// UserActivitySummary summarizes the activity of a single user
+type UserActivitySummary struct {
+	Login          string    `json:"login"`
+	Organization   string    `json:"organization"`
+	Team           string    `json:"team"`
+	Teams          []string  `json:"teams"`
+	ActivePeriods  int       `json:"active_periods"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	Editors        []string  `json:"editors"`
+}
+
+// AllTeams is the team name used for counts across all teams
+const AllTeams = "(all)"
+
+// CountActiveUsers counts distinct active users per period, both overall and
+// per team, and summarizes the activity of each user. With a team resolver,
+// activity is attributed to every team the user belongs to; otherwise, or
+// for users in no team, to the assigning team of the seat.
+func CountActiveUsers(activity []models.SeatActivity, granularity Granularity, resolver *TeamResolver) ([]TeamActiveUsers, []UserActivitySummary) {
+	type periodTeam struct{ period, team string }
+	activeUsers := make(map[periodTeam]map[string]bool)
+	markActive := func(period, team, login string) {
+		key := periodTeam{period, team}
+		if activeUsers[key] == nil {
+			activeUsers[key] = make(map[string]bool)
+		}
+		activeUsers[key][login] = true
+	}
+
+	users := make(map[seatKey]*UserActivitySummary)
+	userPeriods := make(map[seatKey]map[string]bool)
+	userEditors := make(map[seatKey]map[string]bool)
+
+	for _, entry := range activity {
+		period := granularity.Period(entry.LastActivityAt)
+
+		teams := activityTeams(entry, resolver)
+		markActive(period, AllTeams, entry.Login)
+		for _, team := range teams {
+			markActive(period, team, entry.Login)
+		}
+
+		key := seatKey{entry.Organization, entry.Login}
+		user, exists := users[key]
+		if !exists {
+			user = &UserActivitySummary{Login: entry.Login, Organization: entry.Organization, Team: entry.Team}
+			users[key] = user
+			userPeriods[key] = make(map[string]bool)
+			userEditors[key] = make(map[string]bool)
+		}
+		userPeriods[key][period] = true
+		if entry.LastActivityEditor != "" {
+			userEditors[key][entry.LastActivityEditor] = true
+		}
+		if entry.LastActivityAt.After(user.LastActivityAt) {
+			user.LastActivityAt = entry.LastActivityAt
+			user.Team = entry.Team
+			user.Teams = teams
+		}
+	}
+
+	teamCounts := make([]TeamActiveUsers, 0, len(activeUsers))
+	for key, logins := range activeUsers {
+		teamCounts = append(teamCounts, TeamActiveUsers{Period: key.period, Team: key.team, ActiveUsers: len(logins)})
+	}
+	sort.Slice(teamCounts, func(i, j int) bool {
+		if teamCounts[i].Period != teamCounts[j].Period {
+			return teamCounts[i].Period < teamCounts[j].Period
+		}
+		return teamCounts[i].Team < teamCounts[j].Team
+	})
+
+	summaries := make([]UserActivitySummary, 0, len(users))
+	for key, user := range users {
+		user.ActivePeriods = len(userPeriods[key])
+		user.Editors = make([]string, 0, len(userEditors[key]))
+		for editor := range userEditors[key] {
+			user.Editors = append(user.Editors, editor)
+		}
+		sort.Strings(user.Editors)
+		summaries = append(summaries, *user)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Login != summaries[j].Login {
+			return summaries[i].Login < summaries[j].Login
+		}
+		return summaries[i].Organization < summaries[j].Organization
+	})
+
+	return teamCounts, summaries
+}
@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// seatKey identifies a seat across snapshots
+type seatKey struct {
+	organization string
+	login        string
+}
+
+// indexSeats maps the seats of a snapshot by organization and assignee
+func indexSeats(snapshot *models.CopilotAssignedSeats) map[seatKey]*models.Seat {
+	index := make(map[seatKey]*models.Seat, len(snapshot.Seats))
+	for i := range snapshot.Seats {
+		seat := &snapshot.Seats[i]
+		index[seatKey{SeatOrganization(seat, snapshot), seat.Assignee.Login}] = seat
+	}
+	return index
+}
+
+// DiffSeats compares two consecutive seats snapshots and returns the seat
+// assignment changes between them. A nil previous snapshot yields no events,
+// as there is nothing to compare against.
+func DiffSeats(previous, current *models.CopilotAssignedSeats) []models.SeatEvent {
+	if previous == nil || current == nil {
+		return nil
+	}
+
+	previousSeats := indexSeats(previous)
+	currentSeats := indexSeats(current)
+
+	var events []models.SeatEvent
+	newEvent := func(key seatKey, eventType models.SeatEventType, previousValue, newValue string) {
+		event := models.SeatEvent{
+			Date:               current.Date,
+			Type:               eventType,
+			Login:              key.login,
+			Enterprise:         current.Enterprise,
+			Organization:       key.organization,
+			PreviousValue:      previousValue,
+			NewValue:           newValue,
+			PreviousSnapshotID: previous.ID,
+			SnapshotID:         current.ID,
+			ObservedAt:         current.LastUpdate,
+		}
+		event.ID = event.GetID()
+		events = append(events, event)
+	}
+
+	for key, seat := range currentSeats {
+		previousSeat, existed := previousSeats[key]
+		if !existed {
+			newEvent(key, models.SeatEventAdded, "", seat.PlanType)
+			continue
+		}
+
+		if previousSeat.PlanType != seat.PlanType {
+			newEvent(key, models.SeatEventPlanChanged, previousSeat.PlanType, seat.PlanType)
+		}
+
+		if previousTeam, team := SeatTeam(previousSeat), SeatTeam(seat); previousTeam != team {
+			newEvent(key, models.SeatEventAssigningTeamChanged, previousTeam, team)
+		}
+
+		if previousSeat.PendingCancellationDate == "" && seat.PendingCancellationDate != "" {
+			newEvent(key, models.SeatEventPendingCancellationSet, "", seat.PendingCancellationDate)
+		}
+	}
+
+	for key, seat := range previousSeats {
+		if _, exists := currentSeats[key]; !exists {
+			newEvent(key, models.SeatEventRemoved, seat.PlanType, "")
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Organization != events[j].Organization {
+			return events[i].Organization < events[j].Organization
+		}
+		if events[i].Login != events[j].Login {
+			return events[i].Login < events[j].Login
+		}
+		return events[i].Type < events[j].Type
+	})
+
+	return events
+}
@@ -0,0 +1,205 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// SeatStatus classifies how a seat is being used
+type SeatStatus string
+
+const (
+	SeatStatusActive              SeatStatus = "active"
+	SeatStatusInactive            SeatStatus = "inactive"
+	SeatStatusNeverUsed           SeatStatus = "never_used"
+	SeatStatusPendingCancellation SeatStatus = "pending_cancellation"
+)
+
+// DirectAssignment is the team name used for seats assigned directly to a user
+const DirectAssignment = "(direct)"
+
+// SeatUtilizationOptions controls how seats are classified and priced
+type SeatUtilizationOptions struct {
+	InactiveDays int                // Days without activity before a seat is inactive
+	Prices       map[string]float64 // Monthly price per seat keyed by plan type
+	Now          time.Time          // Reference time for activity calculations
+}
+
+// SeatUtilization is the classification of a single seat
+type SeatUtilization struct {
+	Login                   string     `json:"login"`
+	Organization            string     `json:"organization"`
+	Team                    string     `json:"team"`
+	PlanType                string     `json:"plan_type"`
+	Status                  SeatStatus `json:"status"`
+	LastActivityAt          *time.Time `json:"last_activity_at,omitempty"`
+	LastActivityEditor      string     `json:"last_activity_editor,omitempty"`
+	DaysSinceActivity       *int       `json:"days_since_activity,omitempty"`
+	PendingCancellationDate string     `json:"pending_cancellation_date,omitempty"`
+	MonthlyCost             float64    `json:"monthly_cost"`
+}
+
+// IsIdle reports whether the seat is paid for but not being used
+func (s *SeatUtilization) IsIdle() bool {
+	return s.Status == SeatStatusInactive || s.Status == SeatStatusNeverUsed
+}
+
+// SeatUtilizationGroup summarizes seats for an organization and assigning team
+type SeatUtilizationGroup struct {
+	Organization        string  `json:"organization"`
+	Team                string  `json:"team"`
+	TotalSeats          int     `json:"total_seats"`
+	Active              int     `json:"active"`
+	Inactive            int     `json:"inactive"`
+	NeverUsed           int     `json:"never_used"`
+	PendingCancellation int     `json:"pending_cancellation"`
+	MonthlyCost         float64 `json:"monthly_cost"`
+	IdleMonthlyCost     float64 `json:"idle_monthly_cost"`
+}
+
+// add accounts a seat in the group totals
+func (g *SeatUtilizationGroup) add(seat *SeatUtilization) {
+	g.TotalSeats++
+	g.MonthlyCost += seat.MonthlyCost
+
+	switch seat.Status {
+	case SeatStatusActive:
+		g.Active++
+	case SeatStatusInactive:
+		g.Inactive++
+	case SeatStatusNeverUsed:
+		g.NeverUsed++
+	case SeatStatusPendingCancellation:
+		g.PendingCancellation++
+	}
+
+	if seat.IsIdle() {
+		g.IdleMonthlyCost += seat.MonthlyCost
+	}
+}
+
+// SeatUtilizationReport is the result of a seat utilization analysis
+type SeatUtilizationReport struct {
+	Date         string                 `json:"date"`
+	GeneratedAt  time.Time              `json:"generated_at"`
+	InactiveDays int                    `json:"inactive_days"`
+	Totals       SeatUtilizationGroup   `json:"totals"`
+	Groups       []SeatUtilizationGroup `json:"groups"`
+	Seats        []SeatUtilization      `json:"seats"`
+}
+
+// SeatMonthlyPrice returns the monthly price of a seat for the given plan type
+func SeatMonthlyPrice(prices map[string]float64, planType string) float64 {
+	return prices[strings.ToLower(planType)]
+}
+
+// ClassifySeat determines the status of a seat at the given reference time
+func ClassifySeat(seat *models.Seat, inactiveDays int, now time.Time) SeatStatus {
+	if seat.PendingCancellationDate != "" {
+		return SeatStatusPendingCancellation
+	}
+
+	if seat.LastActivityAt == nil || seat.LastActivityAt.IsZero() {
+		return SeatStatusNeverUsed
+	}
+
+	if now.Sub(*seat.LastActivityAt) <= time.Duration(inactiveDays)*24*time.Hour {
+		return SeatStatusActive
+	}
+
+	return SeatStatusInactive
+}
+
+// SeatOrganization returns the organization a seat belongs to, falling back to
+// the organization or enterprise of the snapshot
+func SeatOrganization(seat *models.Seat, snapshot *models.CopilotAssignedSeats) string {
+	if seat.Organization != nil && seat.Organization.Login != "" {
+		return seat.Organization.Login
+	}
+	if snapshot.Organization != "" {
+		return snapshot.Organization
+	}
+	return snapshot.Enterprise
+}
+
+// SeatTeam returns the slug of the team that assigned the seat
+func SeatTeam(seat *models.Seat) string {
+	if seat.AssigningTeam == nil {
+		return DirectAssignment
+	}
+	if seat.AssigningTeam.Slug != "" {
+		return seat.AssigningTeam.Slug
+	}
+	return seat.AssigningTeam.Name
+}
+
+// AnalyzeSeatUtilization classifies every seat of a snapshot and summarizes
+// the results per organization and assigning team
+func AnalyzeSeatUtilization(snapshot *models.CopilotAssignedSeats, opts SeatUtilizationOptions) *SeatUtilizationReport {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+
+	report := &SeatUtilizationReport{
+		Date:         snapshot.Date,
+		GeneratedAt:  now,
+		InactiveDays: opts.InactiveDays,
+		Groups:       []SeatUtilizationGroup{},
+		Seats:        make([]SeatUtilization, 0, len(snapshot.Seats)),
+	}
+
+	groups := make(map[[2]string]*SeatUtilizationGroup)
+
+	for i := range snapshot.Seats {
+		seat := &snapshot.Seats[i]
+
+		utilization := SeatUtilization{
+			Login:                   seat.Assignee.Login,
+			Organization:            SeatOrganization(seat, snapshot),
+			Team:                    SeatTeam(seat),
+			PlanType:                seat.PlanType,
+			Status:                  ClassifySeat(seat, opts.InactiveDays, now),
+			LastActivityAt:          seat.LastActivityAt,
+			LastActivityEditor:      seat.LastActivityEditor,
+			PendingCancellationDate: seat.PendingCancellationDate,
+			MonthlyCost:             SeatMonthlyPrice(opts.Prices, seat.PlanType),
+		}
+
+		if seat.LastActivityAt != nil && !seat.LastActivityAt.IsZero() {
+			days := int(now.Sub(*seat.LastActivityAt).Hours() / 24)
+			utilization.DaysSinceActivity = &days
+		}
+
+		key := [2]string{utilization.Organization, utilization.Team}
+		group, exists := groups[key]
+		if !exists {
+			group = &SeatUtilizationGroup{Organization: utilization.Organization, Team: utilization.Team}
+			groups[key] = group
+		}
+
+		group.add(&utilization)
+		report.Totals.add(&utilization)
+		report.Seats = append(report.Seats, utilization)
+	}
+
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Organization != report.Groups[j].Organization {
+			return report.Groups[i].Organization < report.Groups[j].Organization
+		}
+		return report.Groups[i].Team < report.Groups[j].Team
+	})
+
+	sort.Slice(report.Seats, func(i, j int) bool {
+		return report.Seats[i].Login < report.Seats[j].Login
+	})
+
+	return report
+}
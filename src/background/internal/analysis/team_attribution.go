@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// TeamResolver attributes users to the teams they belong to, based on team
+// membership snapshots
+type TeamResolver struct {
+	dates []string
+	// teamsByDate maps each snapshot date to the teams of every member login
+	teamsByDate map[string]map[string][]string
+}
+
+// NewTeamResolver indexes team membership snapshots by date. Snapshots of
+// several organizations on the same date are combined.
+func NewTeamResolver(snapshots []models.TeamMemberships) *TeamResolver {
+	resolver := &TeamResolver{teamsByDate: make(map[string]map[string][]string)}
+
+	for _, snapshot := range snapshots {
+		teamsByLogin, exists := resolver.teamsByDate[snapshot.Date]
+		if !exists {
+			teamsByLogin = make(map[string][]string)
+			resolver.teamsByDate[snapshot.Date] = teamsByLogin
+			resolver.dates = append(resolver.dates, snapshot.Date)
+		}
+
+		for _, team := range snapshot.Teams {
+			for _, login := range team.Members {
+				teamsByLogin[login] = append(teamsByLogin[login], team.Slug)
+			}
+		}
+	}
+
+	sort.Strings(resolver.dates)
+	return resolver
+}
+
+// TeamsOf returns the teams a user belonged to on the given date, using the
+// latest snapshot on or before that date, or the earliest snapshot when the
+// date precedes all snapshots. It returns nil if the user is in no team.
+func (r *TeamResolver) TeamsOf(login, date string) []string {
+	if r == nil || len(r.dates) == 0 {
+		return nil
+	}
+
+	// Find the first snapshot after the date and step back to the one before
+	index := sort.SearchStrings(r.dates, date)
+	if index == len(r.dates) || r.dates[index] != date {
+		index--
+	}
+	if index < 0 {
+		index = 0
+	}
+
+	return r.teamsByDate[r.dates[index]][login]
+}
+
+// activityTeams returns the teams an activity is attributed to: every team
+// the user belongs to, or the assigning team of the seat when the user is in
+// no known team
+func activityTeams(entry models.SeatActivity, resolver *TeamResolver) []string {
+	if teams := resolver.TeamsOf(entry.Login, entry.Date); len(teams) > 0 {
+		return teams
+	}
+	return []string{entry.Team}
+}
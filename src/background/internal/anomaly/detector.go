@@ -0,0 +1,291 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+)
+
+// Directions of an anomaly
+const (
+	DirectionDrop  = "drop"
+	DirectionSpike = "spike"
+)
+
+// Severities of an anomaly
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Metrics of the daily series checked for anomalies
+const (
+	MetricActiveUsers         = "active_users"
+	MetricEngagedUsers        = "engaged_users"
+	MetricCompletionsEngaged  = "code_completions_engaged_users"
+	MetricChatEngaged         = "ide_chat_engaged_users"
+	MetricDotComChatEngaged   = "dotcom_chat_engaged_users"
+	MetricPullRequestsEngaged = "pull_requests_engaged_users"
+)
+
+// dateLayout is the layout of the metrics dates
+const dateLayout = "2006-01-02"
+
+// madScale turns a median absolute deviation into a standard deviation
+// estimate for normally distributed values
+const madScale = 1.4826
+
+// Options configures the anomaly detector
+type Options struct {
+	BaselineWeeks int     // Number of previous same weekdays the baseline is built from
+	MinSamples    int     // Minimum number of baseline days needed to evaluate a day
+	WarningScore  float64 // Absolute score from which a deviation is a warning
+	CriticalScore float64 // Absolute score from which a deviation is critical
+	MinChange     float64 // Minimum absolute change from the baseline, to ignore noise in small series
+}
+
+// DefaultOptions returns the default detector options
+func DefaultOptions() Options {
+	return Options{
+		BaselineWeeks: 4,
+		MinSamples:    3,
+		WarningScore:  3,
+		CriticalScore: 5,
+		MinChange:     3,
+	}
+}
+
+// Detector finds anomalies in the daily series of stored metrics. Each day is
+// compared with the same weekday of the previous weeks, so that the weekly
+// cycle of working days and weekends is not reported as anomalies.
+type Detector struct {
+	opts Options
+}
+
+// NewDetector creates an anomaly detector
+func NewDetector(opts Options) *Detector {
+	return &Detector{opts: opts}
+}
+
+// HistoryDays returns the number of days of history needed before the first
+// evaluated day
+func (d *Detector) HistoryDays() int {
+	return d.opts.BaselineWeeks * 7
+}
+
+// seriesKey identifies a daily series
+type seriesKey struct {
+	enterprise   string
+	organization string
+	team         string
+	editor       string
+	metric       string
+}
+
+// Detect evaluates the days between from and to (YYYY-MM-DD, inclusive) of
+// every series found in the metrics. The metrics should include the
+// HistoryDays before from. Days without stored metrics, such as days
+// suppressed by the privacy threshold, are left out of the series rather
+// than counted as zero.
+func (d *Detector) Detect(metrics []models.Metrics, from, to string, now time.Time) ([]models.Anomaly, error) {
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q: %w", from, err)
+	}
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q: %w", to, err)
+	}
+
+	series := buildSeries(metrics)
+
+	var anomalies []models.Anomaly
+	for key, values := range series {
+		for date, value := range values {
+			day, err := time.Parse(dateLayout, date)
+			if err != nil || day.Before(fromDate) || day.After(toDate) {
+				continue
+			}
+
+			var baseline []float64
+			for week := 1; week <= d.opts.BaselineWeeks; week++ {
+				if previous, exists := values[day.AddDate(0, 0, -7*week).Format(dateLayout)]; exists {
+					baseline = append(baseline, previous)
+				}
+			}
+			if len(baseline) < d.opts.MinSamples || len(baseline) == 0 {
+				continue
+			}
+
+			if anomaly, found := d.evaluate(value, baseline); found {
+				anomaly.Date = date
+				anomaly.Enterprise = key.enterprise
+				anomaly.Organization = key.organization
+				anomaly.Team = key.team
+				anomaly.Editor = key.editor
+				anomaly.Metric = key.metric
+				anomaly.DetectedAt = now
+				anomaly.ID = anomaly.GetID()
+				anomalies = append(anomalies, anomaly)
+			}
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Date != anomalies[j].Date {
+			return anomalies[i].Date < anomalies[j].Date
+		}
+		return anomalies[i].ID < anomalies[j].ID
+	})
+
+	return anomalies, nil
+}
+
+// evaluate scores a value against its baseline with the median and the
+// median absolute deviation, which are not thrown off by earlier anomalies
+func (d *Detector) evaluate(value float64, baseline []float64) (models.Anomaly, bool) {
+	median := medianOf(baseline)
+
+	deviations := make([]float64, len(baseline))
+	for i, sample := range baseline {
+		deviations[i] = math.Abs(sample - median)
+	}
+
+	// A perfectly flat baseline has no deviation; fall back to a share of the
+	// median so that any change is not scored as infinite
+	scale := medianOf(deviations) * madScale
+	scale = math.Max(scale, math.Max(median*0.05, 1))
+
+	change := value - median
+	if math.Abs(change) < d.opts.MinChange {
+		return models.Anomaly{}, false
+	}
+
+	score := change / scale
+	severity := ""
+	switch {
+	case math.Abs(score) >= d.opts.CriticalScore:
+		severity = SeverityCritical
+	case math.Abs(score) >= d.opts.WarningScore:
+		severity = SeverityWarning
+	default:
+		return models.Anomaly{}, false
+	}
+
+	direction := DirectionSpike
+	if change < 0 {
+		direction = DirectionDrop
+	}
+
+	return models.Anomaly{
+		Value:     value,
+		Baseline:  median,
+		Score:     math.Round(score*100) / 100,
+		Direction: direction,
+		Severity:  severity,
+		Samples:   len(baseline),
+	}, true
+}
+
+// medianOf returns the median of values
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// buildSeries indexes the daily values of every series by date. Totals are
+// tracked per scope and team, the IDE surfaces also per editor. A surface or
+// editor missing from the metrics of a day after it first appeared counts as
+// zero, as that is how a broken rollout shows up.
+func buildSeries(metrics []models.Metrics) map[seriesKey]map[string]float64 {
+	series := make(map[seriesKey]map[string]float64)
+	days := make(map[seriesKey][]string) // Days with metrics per scope and team
+	add := func(m *models.Metrics, editor, metric string, value int) {
+		key := seriesKey{
+			enterprise:   m.Enterprise,
+			organization: m.Organization,
+			team:         m.Team,
+			editor:       editor,
+			metric:       metric,
+		}
+		values, exists := series[key]
+		if !exists {
+			values = make(map[string]float64)
+			series[key] = values
+		}
+		values[m.Date] = float64(value)
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+		scope := seriesKey{enterprise: m.Enterprise, organization: m.Organization, team: m.Team}
+		days[scope] = append(days[scope], m.Date)
+
+		add(m, "", MetricActiveUsers, m.TotalActiveUsers)
+		add(m, "", MetricEngagedUsers, m.TotalEngagedUsers)
+
+		if m.CopilotIdeCodeCompletions != nil {
+			add(m, "", MetricCompletionsEngaged, m.CopilotIdeCodeCompletions.TotalEngagedUsers)
+			for _, editor := range m.CopilotIdeCodeCompletions.Editors {
+				add(m, editor.Name, MetricCompletionsEngaged, editor.TotalEngagedUsers)
+			}
+		}
+		if m.IdeChat != nil {
+			add(m, "", MetricChatEngaged, m.IdeChat.TotalEngagedUsers)
+			for _, editor := range m.IdeChat.Editors {
+				add(m, editor.Name, MetricChatEngaged, editor.TotalEngagedUsers)
+			}
+		}
+		if m.DotComChat != nil {
+			add(m, "", MetricDotComChatEngaged, m.DotComChat.TotalEngagedUsers)
+		}
+		if m.DotComPullRequests != nil {
+			add(m, "", MetricPullRequestsEngaged, m.DotComPullRequests.TotalEngagedUsers)
+		}
+	}
+
+	for key, values := range series {
+		first := ""
+		for date := range values {
+			if first == "" || date < first {
+				first = date
+			}
+		}
+		scope := seriesKey{enterprise: key.enterprise, organization: key.organization, team: key.team}
+		for _, date := range days[scope] {
+			if _, exists := values[date]; !exists && date > first {
+				values[date] = 0
+			}
+		}
+	}
+
+	return series
+}
+
+// DetectStored loads the metrics stored for the days between from and to
+// (YYYY-MM-DD, inclusive) and the history before them, and detects anomalies
+// in those days
+func (d *Detector) DetectStored(ctx context.Context, repo repositories.Repository, from, to string, now time.Time) ([]models.Anomaly, error) {
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q: %w", from, err)
+	}
+
+	historyFrom := fromDate.AddDate(0, 0, -d.HistoryDays()).Format(dateLayout)
+	metrics, err := repo.GetMetrics(ctx, historyFrom, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics: %w", err)
+	}
+
+	return d.Detect(metrics, from, to, now)
+}
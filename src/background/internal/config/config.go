@@ -1,10 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -14,8 +17,27 @@ import (
 type StorageType string
 
 const (
-	StorageCosmos StorageType = "cosmos"
-	StorageSQLite StorageType = "sqlite"
+	StorageCosmos      StorageType = "cosmos"
+	StorageSQLite      StorageType = "sqlite"
+	StorageObjectStore StorageType = "objectstore"
+	// StorageFanout writes to each backend of STORAGE_BACKENDS
+	StorageFanout StorageType = "fanout"
+)
+
+// StorageBackend is a backend of fan-out storage
+type StorageBackend struct {
+	Type     StorageType
+	Required bool // A failed save to a required backend fails the save; others are logged
+}
+
+// LockType defines how instances coordinate scheduled jobs
+type LockType string
+
+const (
+	LockNone     LockType = "none"
+	LockSQLite   LockType = "sqlite"
+	LockPostgres LockType = "postgres"
+	LockCosmos   LockType = "cosmos"
 )
 
 // Config holds the application configuration
@@ -28,11 +50,88 @@ type Config struct {
 	GithubOrganization     string
 	CosmosDBEndpoint       string
 	CosmosDBKey            string
+	CosmosAuth             string            // Cosmos DB authentication method: auto, key, default, managed-identity, workload-identity or service-principal
+	AzureClientID          string            // Client ID of a user-assigned managed identity
+	CosmosDatabase         string            // Cosmos DB database name
+	CosmosContainerNames   map[string]string // Cosmos DB container names keyed by default name
+	CosmosBulkConcurrency  int               // Cosmos DB transactional batches written at once
 	Teams                  []string
 	UseTestData            bool
 	StorageType            StorageType
+	StorageBackends        []StorageBackend // Backends of fan-out storage, the first one serving reads
 	SQLitePath             string
-	MetricsScheduleSeconds int // Interval in seconds for metrics collection
+	MetricsScheduleSeconds int                // Interval in seconds for metrics collection
+	SeatPrices             map[string]float64 // Monthly price per seat keyed by plan type
+	SeatInactiveDays       int                // Days without activity before a seat is considered inactive
+	SeatProration          string             // How seat prices are prorated: "daily" or "full_month"
+	ObjectStoreEndpoint    string             // S3-compatible endpoint, e.g. minio:9000 (default: AWS S3)
+	ObjectStoreRegion      string
+	ObjectStoreAccessKey   string
+	ObjectStoreSecretKey   string
+	ObjectStoreUseSSL      bool
+	ObjectStoreLocation    string              // s3://bucket/prefix or local directory of the object store repository
+	ObjectStoreGzip        bool                // Gzip documents written by the object store repository
+	ArchiveRawResponses    bool                // Archive every raw GitHub API response in the repository
+	EnableValidation       bool                // Run data-quality rules over fetched metrics and seats
+	ValidationDisabled     []string            // Data-quality rules that are not run
+	ValidationSeverities   map[string]string   // Severity overrides by data-quality rule
+	ValidationQuarantine   string              // Minimum severity that quarantines a document, empty to disable
+	ReconciliationCoverage float64             // Minimum share of the total activity the configured teams should cover
+	EnableAnomalyDetection bool                // Detect anomalies in the stored metrics after each ingestion
+	AnomalyBaselineWeeks   int                 // Previous same weekdays an anomaly baseline is built from
+	AnomalyWarningScore    float64             // Deviation score from which an anomaly is a warning
+	AnomalyCriticalScore   float64             // Deviation score from which an anomaly is critical
+	AnomalyMinChange       float64             // Minimum absolute change from the baseline to report an anomaly
+	AlertRulesFile         string              // JSON file with the alerting channels and rules, empty to disable alerting
+	MetricsSchedule        JobSchedule         // When metrics are ingested
+	SeatsSchedule          JobSchedule         // When seats and team memberships are ingested
+	AlertsSchedule         JobSchedule         // When the alert data rules are evaluated, besides after every ingestion
+	DigestSchedule         JobSchedule         // When the weekly digest is sent
+	RollupsSchedule        JobSchedule         // When the weekly and monthly rollups are written
+	RollupsLocation        string              // s3://bucket/prefix or local directory the rollups are written to, empty to disable them
+	DigestRecipients       []string            // Receive the digest of every scope and team
+	DigestTeamRecipients   map[string][]string // Receive the digest of a team, by team slug
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string
+	SMTPPassword           string
+	SMTPFrom               string
+	SMTPSecurity           string // starttls, tls or none
+	LockType               LockType
+	LockLease              time.Duration // How long a job lock is held without being renewed
+	LockSQLitePath         string
+	LockPostgresDriver     string // database/sql driver name of the Postgres locker
+	LockPostgresURL        string
+	SaveMode               string        // How batch saves handle failed documents: best-effort or all-or-nothing
+	SaveRetries            int           // Times the documents of a failed save are retried
+	SaveRetryDelay         time.Duration // Delay before the first retry, doubled for each further retry
+}
+
+// JobSchedule configures when a scheduled job runs
+type JobSchedule struct {
+	Expression string        // Cron expression or descriptor such as @hourly or "@every 1h", empty to disable the job
+	Timezone   string        // IANA timezone the cron expression is evaluated in
+	Jitter     time.Duration // Upper bound of a random delay before each run
+}
+
+// Enabled reports whether the job is scheduled
+func (s JobSchedule) Enabled() bool {
+	return s.Expression != ""
+}
+
+// CronExpression returns the expression with its timezone, in the format
+// understood by the scheduler
+func (s JobSchedule) CronExpression() string {
+	if s.Timezone == "" || strings.HasPrefix(s.Expression, "CRON_TZ=") || strings.HasPrefix(s.Expression, "TZ=") {
+		return s.Expression
+	}
+	return fmt.Sprintf("CRON_TZ=%s %s", s.Timezone, s.Expression)
+}
+
+// DefaultSeatPrices holds the list prices per seat per month for each Copilot plan type
+var DefaultSeatPrices = map[string]float64{
+	"business":   19,
+	"enterprise": 39,
 }
 
 // Load loads the configuration from environment variables
@@ -49,6 +148,7 @@ func Load(logger *zap.Logger) (*Config, error) {
 		GithubOrganization: os.Getenv("GITHUB_ORGANIZATION"),
 		CosmosDBEndpoint:   os.Getenv("AZURE_COSMOSDB_ENDPOINT"),
 		CosmosDBKey:        os.Getenv("AZURE_COSMOSDB_KEY"),
+		AzureClientID:      os.Getenv("AZURE_CLIENT_ID"),
 	}
 
 	// Set default values if not provided
@@ -78,6 +178,49 @@ func Load(logger *zap.Logger) (*Config, error) {
 	switch strings.ToLower(storageType) {
 	case "sqlite":
 		config.StorageType = StorageSQLite
+	case "fanout":
+		config.StorageType = StorageFanout
+	case "objectstore":
+		config.StorageType = StorageObjectStore
+	default:
+		config.StorageType = StorageCosmos
+	}
+
+	// Parse the fan-out backends, e.g. "cosmos:required,sqlite:best-effort"
+	if config.StorageType == StorageFanout {
+		for _, entry := range strings.Split(os.Getenv("STORAGE_BACKENDS"), ",") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			name, policy, _ := strings.Cut(strings.ToLower(strings.TrimSpace(entry)), ":")
+			backend := StorageBackend{Type: StorageType(name), Required: true}
+			switch policy {
+			case "", "required":
+			case "best-effort":
+				backend.Required = false
+			default:
+				logger.Warn("Invalid STORAGE_BACKENDS failure policy, ignoring entry", zap.String("entry", entry))
+				continue
+			}
+			switch {
+			case backend.Type != StorageCosmos && backend.Type != StorageSQLite && backend.Type != StorageObjectStore:
+				logger.Warn("Invalid STORAGE_BACKENDS storage type, ignoring entry", zap.String("entry", entry))
+			case config.UsesStorage(backend.Type):
+				// Backends of a type share their settings, so a second one
+				// would write to the same storage as the first
+				return nil, fmt.Errorf("duplicate STORAGE_BACKENDS storage type %q, each type can be listed once", backend.Type)
+			default:
+				config.StorageBackends = append(config.StorageBackends, backend)
+			}
+		}
+		if len(config.StorageBackends) == 0 {
+			logger.Warn("STORAGE_BACKENDS not set")
+		} else if !slices.ContainsFunc(config.StorageBackends, func(backend StorageBackend) bool { return backend.Required }) {
+			return nil, fmt.Errorf("STORAGE_BACKENDS has no required backend to serve reads")
+		}
+	}
+
+	if config.UsesStorage(StorageSQLite) {
 		config.SQLitePath = os.Getenv("SQLITE_DB_PATH")
 		if config.SQLitePath == "" {
 			homeDir, err := os.UserHomeDir()
@@ -94,8 +237,6 @@ func Load(logger *zap.Logger) (*Config, error) {
 				logger.Warn("Failed to determine home directory for default SQLite path", zap.Error(err))
 			}
 		}
-	default:
-		config.StorageType = StorageCosmos
 	}
 
 	// Get metrics schedule interval in seconds (default: 3600 seconds = 1 hour)
@@ -116,6 +257,176 @@ func Load(logger *zap.Logger) (*Config, error) {
 		}
 	}
 
+	// Parse seat prices, e.g. "business=19,enterprise=39"
+	config.SeatPrices = make(map[string]float64, len(DefaultSeatPrices))
+	for plan, price := range DefaultSeatPrices {
+		config.SeatPrices[plan] = price
+	}
+	if pricesStr := os.Getenv("COPILOT_SEAT_PRICES"); pricesStr != "" {
+		for _, entry := range strings.Split(pricesStr, ",") {
+			plan, priceStr, found := strings.Cut(entry, "=")
+			plan = strings.ToLower(strings.TrimSpace(plan))
+			price, err := strconv.ParseFloat(strings.TrimSpace(priceStr), 64)
+			if !found || plan == "" || err != nil || price < 0 {
+				logger.Warn("Invalid COPILOT_SEAT_PRICES entry, ignoring", zap.String("entry", entry))
+				continue
+			}
+			config.SeatPrices[plan] = price
+		}
+	}
+
+	// Get the seat inactivity threshold in days (default: 30 days)
+	config.SeatInactiveDays = 30
+	if inactiveStr := os.Getenv("SEAT_INACTIVE_DAYS"); inactiveStr != "" {
+		days, err := strconv.Atoi(inactiveStr)
+		if err != nil || days <= 0 {
+			logger.Warn("Invalid SEAT_INACTIVE_DAYS, using default",
+				zap.String("value", inactiveStr),
+				zap.Int("default_days", 30))
+		} else {
+			config.SeatInactiveDays = days
+		}
+	}
+
+	// Configure seat cost proration (default: daily)
+	config.SeatProration = "daily"
+	if prorationStr := strings.ToLower(os.Getenv("COPILOT_SEAT_PRORATION")); prorationStr != "" {
+		if prorationStr == "daily" || prorationStr == "full_month" {
+			config.SeatProration = prorationStr
+		} else {
+			logger.Warn("Invalid COPILOT_SEAT_PRORATION, using default",
+				zap.String("value", prorationStr),
+				zap.String("default", "daily"))
+		}
+	}
+
+	// Configure the S3-compatible object store used by exports
+	config.ObjectStoreEndpoint = os.Getenv("OBJECT_STORE_ENDPOINT")
+	config.ObjectStoreRegion = os.Getenv("OBJECT_STORE_REGION")
+	config.ObjectStoreAccessKey = os.Getenv("OBJECT_STORE_ACCESS_KEY")
+	config.ObjectStoreSecretKey = os.Getenv("OBJECT_STORE_SECRET_KEY")
+	config.ObjectStoreUseSSL = strings.ToLower(os.Getenv("OBJECT_STORE_USE_SSL")) != "false"
+	config.ObjectStoreLocation = os.Getenv("OBJECT_STORE_LOCATION")
+	config.ObjectStoreGzip = strings.ToLower(os.Getenv("OBJECT_STORE_GZIP")) == "true"
+
+	// Archive raw API responses unless disabled
+	config.ArchiveRawResponses = strings.ToLower(os.Getenv("ARCHIVE_RAW_RESPONSES")) != "false"
+
+	// Configure data-quality validation
+	config.EnableValidation = strings.ToLower(os.Getenv("ENABLE_VALIDATION")) != "false"
+	if disabledStr := os.Getenv("VALIDATION_DISABLED_RULES"); disabledStr != "" {
+		for _, rule := range strings.Split(disabledStr, ",") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				config.ValidationDisabled = append(config.ValidationDisabled, rule)
+			}
+		}
+	}
+	config.ValidationSeverities = make(map[string]string)
+	if severitiesStr := os.Getenv("VALIDATION_SEVERITIES"); severitiesStr != "" {
+		for _, entry := range strings.Split(severitiesStr, ",") {
+			rule, severity, found := strings.Cut(entry, "=")
+			rule = strings.TrimSpace(rule)
+			severity = strings.ToLower(strings.TrimSpace(severity))
+			if !found || rule == "" || !isSeverity(severity) {
+				logger.Warn("Invalid VALIDATION_SEVERITIES entry, ignoring", zap.String("entry", entry))
+				continue
+			}
+			config.ValidationSeverities[rule] = severity
+		}
+	}
+	if quarantineStr := strings.ToLower(os.Getenv("VALIDATION_QUARANTINE")); quarantineStr != "" && quarantineStr != "off" {
+		if isSeverity(quarantineStr) {
+			config.ValidationQuarantine = quarantineStr
+		} else {
+			logger.Warn("Invalid VALIDATION_QUARANTINE, quarantine disabled", zap.String("value", quarantineStr))
+		}
+	}
+
+	// Configure the minimum team coverage of the reconciliation (default: 20%)
+	config.ReconciliationCoverage = 0.2
+	if coverageStr := os.Getenv("RECONCILIATION_MIN_COVERAGE"); coverageStr != "" {
+		coverage, err := strconv.ParseFloat(coverageStr, 64)
+		if err != nil || coverage < 0 || coverage > 1 {
+			logger.Warn("Invalid RECONCILIATION_MIN_COVERAGE, using default",
+				zap.String("value", coverageStr),
+				zap.Float64("default", 0.2))
+		} else {
+			config.ReconciliationCoverage = coverage
+		}
+	}
+
+	// Configure anomaly detection
+	config.EnableAnomalyDetection = strings.ToLower(os.Getenv("ENABLE_ANOMALY_DETECTION")) != "false"
+	config.AnomalyBaselineWeeks = 4
+	if weeksStr := os.Getenv("ANOMALY_BASELINE_WEEKS"); weeksStr != "" {
+		weeks, err := strconv.Atoi(weeksStr)
+		if err != nil || weeks < 3 {
+			logger.Warn("Invalid ANOMALY_BASELINE_WEEKS, using default",
+				zap.String("value", weeksStr),
+				zap.Int("default_weeks", 4))
+		} else {
+			config.AnomalyBaselineWeeks = weeks
+		}
+	}
+	config.AnomalyWarningScore = parsePositiveFloat(logger, "ANOMALY_WARNING_SCORE", 3)
+	config.AnomalyCriticalScore = parsePositiveFloat(logger, "ANOMALY_CRITICAL_SCORE", 5)
+	if config.AnomalyCriticalScore < config.AnomalyWarningScore {
+		logger.Warn("ANOMALY_CRITICAL_SCORE is below ANOMALY_WARNING_SCORE, using the warning score",
+			zap.Float64("warning_score", config.AnomalyWarningScore))
+		config.AnomalyCriticalScore = config.AnomalyWarningScore
+	}
+	config.AnomalyMinChange = parsePositiveFloat(logger, "ANOMALY_MIN_CHANGE", 3)
+
+	// Alerting is enabled by a rules file
+	config.AlertRulesFile = os.Getenv("ALERT_RULES_FILE")
+
+	// Rollups are enabled by their location
+	config.RollupsLocation = os.Getenv("ROLLUPS_LOCATION")
+
+	// Configure the weekly digest recipients
+	config.DigestRecipients = splitList(os.Getenv("DIGEST_RECIPIENTS"), ",")
+	config.DigestTeamRecipients = make(map[string][]string)
+	if teamsStr := os.Getenv("DIGEST_TEAM_RECIPIENTS"); teamsStr != "" {
+		// e.g. "backend=lead@example.com|pm@example.com,frontend=fe-lead@example.com"
+		for _, entry := range strings.Split(teamsStr, ",") {
+			team, addresses, found := strings.Cut(entry, "=")
+			team = strings.TrimSpace(team)
+			recipients := splitList(addresses, "|")
+			if !found || team == "" || len(recipients) == 0 {
+				logger.Warn("Invalid DIGEST_TEAM_RECIPIENTS entry, ignoring", zap.String("entry", entry))
+				continue
+			}
+			config.DigestTeamRecipients[team] = append(config.DigestTeamRecipients[team], recipients...)
+		}
+	}
+
+	// Configure the SMTP server digests are sent through
+	config.SMTPHost = os.Getenv("SMTP_HOST")
+	config.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	config.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	config.SMTPFrom = os.Getenv("SMTP_FROM")
+	config.SMTPPort = 587
+	if portStr := os.Getenv("SMTP_PORT"); portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port <= 0 || port > 65535 {
+			logger.Warn("Invalid SMTP_PORT, using default",
+				zap.String("value", portStr),
+				zap.Int("default_port", 587))
+		} else {
+			config.SMTPPort = port
+		}
+	}
+	config.SMTPSecurity = "starttls"
+	if securityStr := strings.ToLower(os.Getenv("SMTP_SECURITY")); securityStr != "" {
+		if securityStr == "starttls" || securityStr == "tls" || securityStr == "none" {
+			config.SMTPSecurity = securityStr
+		} else {
+			logger.Warn("Invalid SMTP_SECURITY, using default",
+				zap.String("value", securityStr),
+				zap.String("default", "starttls"))
+		}
+	}
+
 	// Validate required configuration
 	if config.GithubToken == "" {
 		logger.Warn("GITHUB_TOKEN not set")
@@ -129,17 +440,239 @@ func Load(logger *zap.Logger) (*Config, error) {
 		logger.Warn("GITHUB_ORGANIZATION not set and GITHUB_API_SCOPE is not 'enterprise'")
 	}
 
-	if config.StorageType == StorageCosmos {
+	// Get the Cosmos DB authentication method (default: auto, the account key
+	// when one is set, Azure AD otherwise)
+	config.CosmosAuth = "auto"
+	switch auth := strings.ToLower(os.Getenv("COSMOS_AUTH")); auth {
+	case "", "auto":
+	case "key", "default", "managed-identity", "workload-identity", "service-principal":
+		config.CosmosAuth = auth
+	default:
+		logger.Warn("Invalid COSMOS_AUTH, using auto", zap.String("value", auth))
+	}
+
+	if config.UsesStorage(StorageCosmos) {
 		if config.CosmosDBEndpoint == "" {
 			logger.Warn("AZURE_COSMOSDB_ENDPOINT not set")
 		}
 
-		if config.CosmosDBKey == "" {
+		if config.CosmosDBKey == "" && config.CosmosAuth == "key" {
 			logger.Warn("AZURE_COSMOSDB_KEY not set")
 		}
 	}
 
+	if config.UsesStorage(StorageObjectStore) && config.ObjectStoreLocation == "" {
+		logger.Warn("OBJECT_STORE_LOCATION not set")
+	}
+
 	config.MetricsScheduleSeconds = metricsScheduleSeconds
 
+	// Configure the job schedules. Metrics default to the interval of
+	// METRICS_SCHEDULE_SECONDS; alert rules are only evaluated after
+	// ingestion runs unless ALERTS_SCHEDULE is set.
+	defaultTimezone := loadTimezone(logger, "SCHEDULE_TIMEZONE", "UTC")
+	config.MetricsSchedule = loadJobSchedule(logger, "METRICS", fmt.Sprintf("@every %ds", metricsScheduleSeconds), defaultTimezone)
+	config.SeatsSchedule = loadJobSchedule(logger, "SEATS", "@every 1h", defaultTimezone)
+	config.AlertsSchedule = loadJobSchedule(logger, "ALERTS", "", defaultTimezone)
+	config.DigestSchedule = loadJobSchedule(logger, "DIGEST", "0 8 * * 1", defaultTimezone)
+	config.RollupsSchedule = loadJobSchedule(logger, "ROLLUPS", "0 7 * * *", defaultTimezone)
+
+	// Configure the locking of scheduled jobs across instances (default: none)
+	config.LockType = LockNone
+	switch lockType := strings.ToLower(os.Getenv("LOCK_TYPE")); lockType {
+	case "", "none":
+	case "sqlite", "postgres", "cosmos":
+		config.LockType = LockType(lockType)
+	default:
+		logger.Warn("Invalid LOCK_TYPE, jobs are not locked", zap.String("value", lockType))
+	}
+	config.LockLease = 2 * time.Minute
+	if leaseStr := os.Getenv("LOCK_LEASE"); leaseStr != "" {
+		lease, err := time.ParseDuration(leaseStr)
+		if err != nil || lease < 3*time.Second {
+			logger.Warn("Invalid LOCK_LEASE, using default",
+				zap.String("value", leaseStr),
+				zap.Duration("default", config.LockLease))
+		} else {
+			config.LockLease = lease
+		}
+	}
+	config.LockSQLitePath = os.Getenv("LOCK_SQLITE_PATH")
+	if config.LockSQLitePath == "" {
+		config.LockSQLitePath = config.SQLitePath
+	}
+	config.LockPostgresDriver = os.Getenv("LOCK_POSTGRES_DRIVER")
+	if config.LockPostgresDriver == "" {
+		config.LockPostgresDriver = "pgx"
+	}
+	config.LockPostgresURL = os.Getenv("LOCK_POSTGRES_URL")
+
+	// Configure how batch saves handle documents that fail (default:
+	// best-effort, retried twice)
+	config.SaveMode = "best-effort"
+	switch saveMode := strings.ToLower(os.Getenv("SAVE_MODE")); saveMode {
+	case "", "best-effort":
+	case "all-or-nothing":
+		config.SaveMode = saveMode
+	default:
+		logger.Warn("Invalid SAVE_MODE, using best-effort", zap.String("value", saveMode))
+	}
+	config.SaveRetries = 2
+	if retriesStr := os.Getenv("SAVE_RETRIES"); retriesStr != "" {
+		retries, err := strconv.Atoi(retriesStr)
+		if err != nil || retries < 0 {
+			logger.Warn("Invalid SAVE_RETRIES, using default",
+				zap.String("value", retriesStr),
+				zap.Int("default", config.SaveRetries))
+		} else {
+			config.SaveRetries = retries
+		}
+	}
+	config.SaveRetryDelay = 2 * time.Second
+	if delayStr := os.Getenv("SAVE_RETRY_DELAY"); delayStr != "" {
+		delay, err := time.ParseDuration(delayStr)
+		if err != nil || delay < 0 {
+			logger.Warn("Invalid SAVE_RETRY_DELAY, using default",
+				zap.String("value", delayStr),
+				zap.Duration("default", config.SaveRetryDelay))
+		} else {
+			config.SaveRetryDelay = delay
+		}
+	}
+
+	// Get the Cosmos DB database (default: platform-engineering) and container
+	// names, e.g. "metrics_history=copilot_metrics,seats_history=copilot_seats"
+	config.CosmosDatabase = os.Getenv("COSMOS_DATABASE")
+	if config.CosmosDatabase == "" {
+		config.CosmosDatabase = "platform-engineering"
+	}
+	var invalidNames []string
+	config.CosmosContainerNames, invalidNames = ParseCosmosContainerNames(os.Getenv("COSMOS_CONTAINER_NAMES"))
+	for _, entry := range invalidNames {
+		logger.Warn("Invalid COSMOS_CONTAINER_NAMES entry, ignoring", zap.String("entry", entry))
+	}
+
+	// Get the number of Cosmos DB transactional batches written at once (default: 4)
+	config.CosmosBulkConcurrency = 4
+	if concurrencyStr := os.Getenv("COSMOS_BULK_CONCURRENCY"); concurrencyStr != "" {
+		concurrency, err := strconv.Atoi(concurrencyStr)
+		if err != nil || concurrency <= 0 {
+			logger.Warn("Invalid COSMOS_BULK_CONCURRENCY, using default",
+				zap.String("value", concurrencyStr),
+				zap.Int("default", config.CosmosBulkConcurrency))
+		} else {
+			config.CosmosBulkConcurrency = concurrency
+		}
+	}
+
 	return config, nil
 }
+
+// UsesStorage reports whether a storage type is used, on its own or as a
+// fan-out backend
+func (c *Config) UsesStorage(storageType StorageType) bool {
+	if c.StorageType == storageType {
+		return true
+	}
+	for _, backend := range c.StorageBackends {
+		if backend.Type == storageType {
+			return true
+		}
+	}
+	return false
+}
+
+// loadJobSchedule reads the schedule of a job from <PREFIX>_SCHEDULE,
+// <PREFIX>_TIMEZONE and <PREFIX>_JITTER
+func loadJobSchedule(logger *zap.Logger, prefix, defaultExpression, defaultTimezone string) JobSchedule {
+	schedule := JobSchedule{
+		Expression: strings.TrimSpace(os.Getenv(prefix + "_SCHEDULE")),
+		Timezone:   loadTimezone(logger, prefix+"_TIMEZONE", defaultTimezone),
+	}
+	if schedule.Expression == "" {
+		schedule.Expression = defaultExpression
+	} else if strings.ToLower(schedule.Expression) == "off" {
+		schedule.Expression = ""
+	}
+
+	if jitterStr := os.Getenv(prefix + "_JITTER"); jitterStr != "" {
+		jitter, err := time.ParseDuration(jitterStr)
+		if err != nil || jitter < 0 {
+			logger.Warn("Invalid "+prefix+"_JITTER, running without jitter", zap.String("value", jitterStr))
+		} else {
+			schedule.Jitter = jitter
+		}
+	}
+	return schedule
+}
+
+// loadTimezone reads an IANA timezone name from an environment variable,
+// warning and using the default when it is unknown
+func loadTimezone(logger *zap.Logger, name, defaultValue string) string {
+	timezone := os.Getenv(name)
+	if timezone == "" {
+		return defaultValue
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		logger.Warn("Invalid "+name+", using default",
+			zap.String("value", timezone),
+			zap.String("default", defaultValue),
+			zap.Error(err))
+		return defaultValue
+	}
+	return timezone
+}
+
+// splitList splits a separated list, dropping blank entries
+func splitList(value, separator string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, separator) {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// isSeverity reports whether a value is a data-quality severity
+func isSeverity(value string) bool {
+	return value == "info" || value == "warning" || value == "error"
+}
+
+// parsePositiveFloat reads a positive number from an environment variable,
+// warning and using the default when it is invalid
+func parsePositiveFloat(logger *zap.Logger, name string, defaultValue float64) float64 {
+	valueStr := os.Getenv(name)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil || value <= 0 {
+		logger.Warn("Invalid "+name+", using default",
+			zap.String("value", valueStr),
+			zap.Float64("default", defaultValue))
+		return defaultValue
+	}
+	return value
+}
+
+// ParseCosmosContainerNames parses Cosmos DB container names keyed by default
+// name, e.g. "metrics_history=metrics_history_v2,seats_history=seats_history_v2".
+// It also returns the entries that are not <default name>=<name>.
+func ParseCosmosContainerNames(value string) (names map[string]string, invalid []string) {
+	names = make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return names, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		container, name, found := strings.Cut(entry, "=")
+		container, name = strings.TrimSpace(container), strings.TrimSpace(name)
+		if !found || container == "" || name == "" {
+			invalid = append(invalid, entry)
+			continue
+		}
+		names[container] = name
+	}
+	return names, invalid
+}
@@ -0,0 +1,170 @@
+// Package cosmosauth creates Cosmos DB clients authenticated with an account
+// key or an Azure AD token credential
+package cosmosauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"go.uber.org/zap"
+)
+
+// Method selects how Cosmos DB clients authenticate
+type Method string
+
+const (
+	// Auto uses the account key when one is configured and the Azure AD
+	// credential chain otherwise
+	Auto Method = "auto"
+	// Key uses the account key
+	Key Method = "key"
+	// Default uses the Azure AD credential chain: environment, workload
+	// identity, managed identity, Azure CLI and Azure Developer CLI
+	Default Method = "default"
+	// ManagedIdentity uses the managed identity of the Azure host
+	ManagedIdentity Method = "managed-identity"
+	// WorkloadIdentity uses a Kubernetes workload identity federated token
+	WorkloadIdentity Method = "workload-identity"
+	// ServicePrincipal uses the service principal of the AZURE_TENANT_ID,
+	// AZURE_CLIENT_ID and AZURE_CLIENT_SECRET or AZURE_CLIENT_CERTIFICATE_PATH
+	// environment variables
+	ServicePrincipal Method = "service-principal"
+)
+
+// validationTimeout bounds the token request that validates an Azure AD credential at startup
+const validationTimeout = 30 * time.Second
+
+// Config holds the Cosmos DB connection settings
+type Config struct {
+	Endpoint string
+	Key      string // Account key, used by Key, and by Auto when set
+	Method   Method
+	ClientID string // Client ID of a user-assigned managed identity, from AZURE_CLIENT_ID (optional)
+}
+
+// ConfigFrom returns the Cosmos DB connection settings of the application configuration
+func ConfigFrom(cfg *config.Config) Config {
+	return Config{
+		Endpoint: cfg.CosmosDBEndpoint,
+		Key:      cfg.CosmosDBKey,
+		Method:   Method(cfg.CosmosAuth),
+		ClientID: cfg.AzureClientID,
+	}
+}
+
+// NewClient creates a Cosmos DB client with the configured authentication
+// method. Azure AD credentials are validated by requesting a token, so a
+// misconfigured identity fails at startup rather than on the first request.
+func NewClient(ctx context.Context, cfg Config, logger *zap.Logger) (*azcosmos.Client, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("Cosmos DB endpoint is not specified")
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = Auto
+	}
+
+	switch method {
+	case Key:
+		return newKeyClient(cfg, logger)
+	case Auto:
+		// A configured key is an explicit choice. Azure AD is not tried first:
+		// a token does not prove the identity has a data-plane role on the
+		// account, and hosts without an identity endpoint would wait for the
+		// token request to time out.
+		if cfg.Key != "" {
+			return newKeyClient(cfg, logger)
+		}
+		return newTokenClient(ctx, cfg, Default, logger)
+	case Default, ManagedIdentity, WorkloadIdentity, ServicePrincipal:
+		return newTokenClient(ctx, cfg, method, logger)
+	default:
+		return nil, fmt.Errorf("unsupported Cosmos DB authentication method: %s", method)
+	}
+}
+
+// newKeyClient creates a client authenticated with the account key
+func newKeyClient(cfg Config, logger *zap.Logger) (*azcosmos.Client, error) {
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("Cosmos DB key is not specified")
+	}
+
+	cred, err := azcosmos.NewKeyCredential(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cosmos DB credentials: %w", err)
+	}
+	client, err := azcosmos.NewClientWithKey(cfg.Endpoint, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cosmos DB client: %w", err)
+	}
+
+	logger.Info("Authenticating to Cosmos DB", zap.String("method", string(Key)))
+	return client, nil
+}
+
+// newTokenClient creates a client authenticated with an Azure AD credential
+// and checks that the credential can get a token for the account
+func newTokenClient(ctx context.Context, cfg Config, method Method, logger *zap.Logger) (*azcosmos.Client, error) {
+	cred, err := credential(cfg, method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s credential: %w", method, err)
+	}
+
+	scope, err := tokenScope(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, validationTimeout)
+	defer cancel()
+	if _, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		return nil, fmt.Errorf("failed to get a Cosmos DB token with the %s credential: %w", method, err)
+	}
+
+	client, err := azcosmos.NewClient(cfg.Endpoint, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cosmos DB client: %w", err)
+	}
+
+	fields := []zap.Field{zap.String("method", string(method))}
+	if cfg.ClientID != "" && method == ManagedIdentity {
+		fields = append(fields, zap.String("clientId", cfg.ClientID))
+	}
+	logger.Info("Authenticating to Cosmos DB", fields...)
+	return client, nil
+}
+
+// credential creates the Azure AD credential of a method
+func credential(cfg Config, method Method) (azcore.TokenCredential, error) {
+	switch method {
+	case ManagedIdentity:
+		options := &azidentity.ManagedIdentityCredentialOptions{}
+		if cfg.ClientID != "" {
+			options.ID = azidentity.ClientID(cfg.ClientID)
+		}
+		return azidentity.NewManagedIdentityCredential(options)
+	case WorkloadIdentity:
+		return azidentity.NewWorkloadIdentityCredential(nil)
+	case ServicePrincipal:
+		return azidentity.NewEnvironmentCredential(nil)
+	default:
+		// The chain reads AZURE_CLIENT_ID itself for user-assigned managed identities
+		return azidentity.NewDefaultAzureCredential(nil)
+	}
+}
+
+// tokenScope returns the Azure AD scope of a Cosmos DB account endpoint
+func tokenScope(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid Cosmos DB endpoint: %s", endpoint)
+	}
+	return fmt.Sprintf("%s://%s/.default", u.Scheme, u.Hostname()), nil
+}
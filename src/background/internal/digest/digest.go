@@ -0,0 +1,441 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+)
+
+// dateLayout is the layout of the stored document dates
+const dateLayout = "2006-01-02"
+
+// DefaultTopN is the number of languages and editors listed per section
+const DefaultTopN = 5
+
+// Options configures the digest
+type Options struct {
+	TopN        int                             // Languages and editors listed per section (default: DefaultTopN)
+	SeatOptions analysis.SeatUtilizationOptions // Classification of idle seats
+}
+
+// Digest is the weekly adoption summary of every scope and team
+type Digest struct {
+	From         string    `json:"from"` // First day of the week (YYYY-MM-DD)
+	To           string    `json:"to"`   // Last day of the week (YYYY-MM-DD)
+	PreviousFrom string    `json:"previous_from"`
+	PreviousTo   string    `json:"previous_to"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	Sections     []Section `json:"sections"`
+}
+
+// Section summarizes the week of an organization/enterprise or a team
+type Section struct {
+	Enterprise             string       `json:"enterprise,omitempty"`
+	Organization           string       `json:"organization,omitempty"`
+	Team                   string       `json:"team,omitempty"`
+	Days                   int          `json:"days"`          // Days with stored metrics
+	PreviousDays           int          `json:"previous_days"` // Days with stored metrics in the previous week
+	SuppressedDays         int          `json:"suppressed_days"`
+	EngagedUsers           float64      `json:"engaged_users"` // Average daily engaged users
+	PreviousEngagedUsers   float64      `json:"previous_engaged_users"`
+	PeakEngagedUsers       int          `json:"peak_engaged_users"`
+	ActiveUsers            float64      `json:"active_users"` // Average daily active users
+	PreviousActiveUsers    float64      `json:"previous_active_users"`
+	Suggestions            int          `json:"suggestions"`
+	Acceptances            int          `json:"acceptances"`
+	AcceptanceRate         *float64     `json:"acceptance_rate,omitempty"`
+	PreviousAcceptanceRate *float64     `json:"previous_acceptance_rate,omitempty"`
+	Languages              []Breakdown  `json:"languages"`
+	Editors                []Breakdown  `json:"editors"`
+	Seats                  *SeatSummary `json:"seats,omitempty"`
+}
+
+// Breakdown summarizes the code completions of a language or editor
+type Breakdown struct {
+	Name           string   `json:"name"`
+	EngagedUsers   float64  `json:"engaged_users"` // Average daily engaged users
+	Suggestions    int      `json:"suggestions"`
+	Acceptances    int      `json:"acceptances"`
+	AcceptanceRate *float64 `json:"acceptance_rate,omitempty"`
+}
+
+// SeatSummary counts the idle seats of the latest seats snapshot
+type SeatSummary struct {
+	Date            string  `json:"date"`
+	TotalSeats      int     `json:"total_seats"`
+	Inactive        int     `json:"inactive"`
+	NeverUsed       int     `json:"never_used"`
+	IdleMonthlyCost float64 `json:"idle_monthly_cost"`
+}
+
+// Idle returns the number of idle seats
+func (s *SeatSummary) Idle() int {
+	return s.Inactive + s.NeverUsed
+}
+
+// Scope returns the organization, or the enterprise, of the section
+func (s *Section) Scope() string {
+	if s.Organization != "" {
+		return s.Organization
+	}
+	return s.Enterprise
+}
+
+// Title names the section
+func (s *Section) Title() string {
+	if s.Team != "" {
+		return fmt.Sprintf("%s / %s", s.Scope(), s.Team)
+	}
+	return s.Scope()
+}
+
+// EngagedUsersChange returns the relative week-over-week change of the
+// average engaged users, or nil if either week has no metrics
+func (s *Section) EngagedUsersChange() *float64 {
+	if s.Days == 0 || s.PreviousDays == 0 || s.PreviousEngagedUsers == 0 {
+		return nil
+	}
+	change := (s.EngagedUsers - s.PreviousEngagedUsers) / s.PreviousEngagedUsers
+	return &change
+}
+
+// AcceptanceRateChange returns the week-over-week change of the acceptance
+// rate in percentage points, or nil if either rate is unknown
+func (s *Section) AcceptanceRateChange() *float64 {
+	if s.AcceptanceRate == nil || s.PreviousAcceptanceRate == nil {
+		return nil
+	}
+	change := (*s.AcceptanceRate - *s.PreviousAcceptanceRate) * 100
+	return &change
+}
+
+// ForTeam returns the digest restricted to the sections of a team
+func (d *Digest) ForTeam(team string) *Digest {
+	filtered := *d
+	filtered.Sections = nil
+	for _, section := range d.Sections {
+		if section.Team == team {
+			filtered.Sections = append(filtered.Sections, section)
+		}
+	}
+	return &filtered
+}
+
+// LastCompleteWeek returns the Monday to Sunday week before the week of now
+func LastCompleteWeek(now time.Time) (from, to string) {
+	now = now.UTC()
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	monday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -daysSinceMonday-7)
+	return monday.Format(dateLayout), monday.AddDate(0, 0, 6).Format(dateLayout)
+}
+
+// Build builds the digest of the seven days ending at to (YYYY-MM-DD) from
+// the stored metrics, metrics markers and latest seats snapshot
+func Build(ctx context.Context, repo repositories.Repository, to string, opts Options, now time.Time) (*Digest, error) {
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", to)
+	}
+	if opts.TopN <= 0 {
+		opts.TopN = DefaultTopN
+	}
+
+	digest := &Digest{
+		From:         end.AddDate(0, 0, -6).Format(dateLayout),
+		To:           to,
+		PreviousFrom: end.AddDate(0, 0, -13).Format(dateLayout),
+		PreviousTo:   end.AddDate(0, 0, -7).Format(dateLayout),
+		GeneratedAt:  now.UTC(),
+		Sections:     []Section{},
+	}
+
+	metrics, err := repo.GetMetrics(ctx, digest.PreviousFrom, digest.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics: %w", err)
+	}
+	markers, err := repo.GetMetricsMarkers(ctx, digest.From, digest.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics markers: %w", err)
+	}
+	seats, err := repo.GetLatestSeats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load seats: %w", err)
+	}
+
+	sections := make(map[sectionKey]*sectionAccumulator)
+	var keys []sectionKey
+	section := func(key sectionKey) *sectionAccumulator {
+		acc, exists := sections[key]
+		if !exists {
+			acc = newSectionAccumulator(key)
+			sections[key] = acc
+			keys = append(keys, key)
+		}
+		return acc
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+		key := sectionKey{enterprise: m.Enterprise, organization: m.Organization, team: m.Team}
+		if m.Date >= digest.From {
+			section(key).addCurrent(m)
+		} else {
+			section(key).addPrevious(m)
+		}
+	}
+
+	// A marker only counts for a day without stored metrics
+	for _, marker := range markers {
+		key := sectionKey{enterprise: marker.Enterprise, organization: marker.Organization, team: marker.Team}
+		if acc, exists := sections[key]; !exists || !acc.currentDates[marker.Date] {
+			if marker.Status == models.MetricsSuppressed {
+				section(key).suppressed[marker.Date] = true
+			}
+		}
+	}
+
+	var seatReport *analysis.SeatUtilizationReport
+	if seats != nil {
+		seatOptions := opts.SeatOptions
+		seatOptions.Now = now.UTC()
+		seatReport = analysis.AnalyzeSeatUtilization(seats, seatOptions)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].scope() != keys[j].scope() {
+			return keys[i].scope() < keys[j].scope()
+		}
+		return keys[i].team < keys[j].team
+	})
+
+	for _, key := range keys {
+		result := sections[key].result(opts.TopN)
+		if seatReport != nil {
+			result.Seats = summarizeSeats(seatReport, key)
+		}
+		digest.Sections = append(digest.Sections, result)
+	}
+
+	return digest, nil
+}
+
+// sectionKey identifies the scope and team of a section
+type sectionKey struct {
+	enterprise   string
+	organization string
+	team         string
+}
+
+// scope returns the organization, or the enterprise, of the key
+func (k sectionKey) scope() string {
+	if k.organization != "" {
+		return k.organization
+	}
+	return k.enterprise
+}
+
+// breakdownTotals accumulates the code completions of a language or editor
+type breakdownTotals struct {
+	engagedUsers int
+	suggestions  int
+	acceptances  int
+}
+
+// sectionAccumulator sums the metrics of a section over both weeks
+type sectionAccumulator struct {
+	key                  sectionKey
+	currentDates         map[string]bool
+	previousDates        map[string]bool
+	suppressed           map[string]bool
+	engagedUsers         int
+	previousEngagedUsers int
+	peakEngagedUsers     int
+	activeUsers          int
+	previousActiveUsers  int
+	suggestions          int
+	acceptances          int
+	previousSuggestions  int
+	previousAcceptances  int
+	languages            map[string]*breakdownTotals
+	editors              map[string]*breakdownTotals
+}
+
+// newSectionAccumulator starts the totals of a section
+func newSectionAccumulator(key sectionKey) *sectionAccumulator {
+	return &sectionAccumulator{
+		key:           key,
+		currentDates:  make(map[string]bool),
+		previousDates: make(map[string]bool),
+		suppressed:    make(map[string]bool),
+		languages:     make(map[string]*breakdownTotals),
+		editors:       make(map[string]*breakdownTotals),
+	}
+}
+
+// addPrevious accounts a day of the previous week
+func (a *sectionAccumulator) addPrevious(m *models.Metrics) {
+	a.previousDates[m.Date] = true
+	a.previousEngagedUsers += m.TotalEngagedUsers
+	a.previousActiveUsers += m.TotalActiveUsers
+	suggestions, acceptances := codeCompletionTotals(m)
+	a.previousSuggestions += suggestions
+	a.previousAcceptances += acceptances
+}
+
+// addCurrent accounts a day of the digest week
+func (a *sectionAccumulator) addCurrent(m *models.Metrics) {
+	a.currentDates[m.Date] = true
+	a.engagedUsers += m.TotalEngagedUsers
+	a.activeUsers += m.TotalActiveUsers
+	if m.TotalEngagedUsers > a.peakEngagedUsers {
+		a.peakEngagedUsers = m.TotalEngagedUsers
+	}
+
+	if m.CopilotIdeCodeCompletions == nil {
+		return
+	}
+	for _, language := range m.CopilotIdeCodeCompletions.Languages {
+		totals(a.languages, language.Name).engagedUsers += language.TotalEngagedUsers
+	}
+	for _, editor := range m.CopilotIdeCodeCompletions.Editors {
+		editorTotals := totals(a.editors, editor.Name)
+		editorTotals.engagedUsers += editor.TotalEngagedUsers
+		for _, model := range editor.Models {
+			for _, language := range model.Languages {
+				editorTotals.suggestions += language.TotalCodeSuggestions
+				editorTotals.acceptances += language.TotalCodeAcceptances
+
+				languageTotals := totals(a.languages, language.Name)
+				languageTotals.suggestions += language.TotalCodeSuggestions
+				languageTotals.acceptances += language.TotalCodeAcceptances
+
+				a.suggestions += language.TotalCodeSuggestions
+				a.acceptances += language.TotalCodeAcceptances
+			}
+		}
+	}
+}
+
+// totals returns the totals of a name, creating them on first use
+func totals(byName map[string]*breakdownTotals, name string) *breakdownTotals {
+	t, exists := byName[name]
+	if !exists {
+		t = &breakdownTotals{}
+		byName[name] = t
+	}
+	return t
+}
+
+// result computes the section from the accumulated totals
+func (a *sectionAccumulator) result(topN int) Section {
+	section := Section{
+		Enterprise:             a.key.enterprise,
+		Organization:           a.key.organization,
+		Team:                   a.key.team,
+		Days:                   len(a.currentDates),
+		PreviousDays:           len(a.previousDates),
+		SuppressedDays:         len(a.suppressed),
+		EngagedUsers:           average(a.engagedUsers, len(a.currentDates)),
+		PreviousEngagedUsers:   average(a.previousEngagedUsers, len(a.previousDates)),
+		PeakEngagedUsers:       a.peakEngagedUsers,
+		ActiveUsers:            average(a.activeUsers, len(a.currentDates)),
+		PreviousActiveUsers:    average(a.previousActiveUsers, len(a.previousDates)),
+		Suggestions:            a.suggestions,
+		Acceptances:            a.acceptances,
+		AcceptanceRate:         rate(a.acceptances, a.suggestions),
+		PreviousAcceptanceRate: rate(a.previousAcceptances, a.previousSuggestions),
+	}
+	section.Languages = topBreakdowns(a.languages, len(a.currentDates), topN)
+	section.Editors = topBreakdowns(a.editors, len(a.currentDates), topN)
+	return section
+}
+
+// topBreakdowns returns the breakdowns with the most accepted suggestions,
+// then the most engaged users
+func topBreakdowns(byName map[string]*breakdownTotals, days, topN int) []Breakdown {
+	breakdowns := []Breakdown{}
+	for name, t := range byName {
+		breakdowns = append(breakdowns, Breakdown{
+			Name:           name,
+			EngagedUsers:   average(t.engagedUsers, days),
+			Suggestions:    t.suggestions,
+			Acceptances:    t.acceptances,
+			AcceptanceRate: rate(t.acceptances, t.suggestions),
+		})
+	}
+
+	sort.Slice(breakdowns, func(i, j int) bool {
+		if breakdowns[i].Acceptances != breakdowns[j].Acceptances {
+			return breakdowns[i].Acceptances > breakdowns[j].Acceptances
+		}
+		if breakdowns[i].EngagedUsers != breakdowns[j].EngagedUsers {
+			return breakdowns[i].EngagedUsers > breakdowns[j].EngagedUsers
+		}
+		return breakdowns[i].Name < breakdowns[j].Name
+	})
+
+	if len(breakdowns) > topN {
+		breakdowns = breakdowns[:topN]
+	}
+	return breakdowns
+}
+
+// summarizeSeats counts the seats of a section: all seats of the scope, or
+// the seats assigned through the team
+func summarizeSeats(report *analysis.SeatUtilizationReport, key sectionKey) *SeatSummary {
+	summary := &SeatSummary{Date: report.Date}
+	for _, group := range report.Groups {
+		if key.organization != "" && group.Organization != key.organization {
+			continue
+		}
+		if key.team != "" && group.Team != key.team {
+			continue
+		}
+		summary.TotalSeats += group.TotalSeats
+		summary.Inactive += group.Inactive
+		summary.NeverUsed += group.NeverUsed
+		summary.IdleMonthlyCost += group.IdleMonthlyCost
+	}
+	if summary.TotalSeats == 0 {
+		return nil
+	}
+	return summary
+}
+
+// codeCompletionTotals sums the code suggestions and acceptances of metrics
+func codeCompletionTotals(m *models.Metrics) (suggestions, acceptances int) {
+	if m.CopilotIdeCodeCompletions == nil {
+		return 0, 0
+	}
+	for _, editor := range m.CopilotIdeCodeCompletions.Editors {
+		for _, model := range editor.Models {
+			for _, language := range model.Languages {
+				suggestions += language.TotalCodeSuggestions
+				acceptances += language.TotalCodeAcceptances
+			}
+		}
+	}
+	return suggestions, acceptances
+}
+
+// average divides a total by a number of days, or returns 0 without days
+func average(total, days int) float64 {
+	if days == 0 {
+		return 0
+	}
+	return float64(total) / float64(days)
+}
+
+// rate returns the share of accepted suggestions, or nil without suggestions
+func rate(acceptances, suggestions int) *float64 {
+	if suggestions == 0 {
+		return nil
+	}
+	r := float64(acceptances) / float64(suggestions)
+	return &r
+}
@@ -0,0 +1,340 @@
+package digest
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// sinkMessage is a message received by an SMTP sink
+type sinkMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpSink is a local SMTP server recording the messages sent to it. It
+// offers AUTH PLAIN but not STARTTLS.
+type smtpSink struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []sinkMessage
+	auth     []string // Decoded AUTH PLAIN credentials
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &smtpSink{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+// config returns the mailer settings of the sink
+func (s *smtpSink) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return SMTPConfig{Host: host, Port: portNumber, From: "Copilot Metrics <digest@example.com>", Security: SecurityNone}
+}
+
+// received returns the messages received so far
+func (s *smtpSink) received() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sinkMessage(nil), s.messages...)
+}
+
+// serve handles an SMTP session
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		io.WriteString(conn, line+"\r\n")
+	}
+
+	var message sinkMessage
+	reply("220 localhost test sink")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, argument, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			_, initial, _ := strings.Cut(argument, " ")
+			credentials, _ := base64.StdEncoding.DecodeString(initial)
+			s.mu.Lock()
+			s.auth = append(s.auth, string(credentials))
+			s.mu.Unlock()
+			reply("235 Authenticated")
+		case "MAIL":
+			message = sinkMessage{From: strings.TrimPrefix(argument, "FROM:")}
+			reply("250 OK")
+		case "RCPT":
+			message.To = append(message.To, strings.TrimPrefix(argument, "TO:"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			message.Data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// parsedMessage is the decoded content of a received message
+type parsedMessage struct {
+	Header mail.Header
+	Text   string
+	HTML   string
+}
+
+// parseMessage decodes a multipart/alternative message
+func parseMessage(t *testing.T, data string) parsedMessage {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("message content type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	parsed := parsedMessage{Header: msg.Header}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid message part: %v", err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch part.Header.Get("Content-Type") {
+		case "text/plain; charset=utf-8":
+			parsed.Text = string(body)
+		case "text/html; charset=utf-8":
+			parsed.HTML = string(body)
+		}
+	}
+	return parsed
+}
+
+func TestMailerSend(t *testing.T) {
+	sink := newSMTPSink(t)
+	cfg := sink.config()
+	cfg.Username, cfg.Password = "digest", "secret"
+	mailer, err := NewMailer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mailer.Send(context.Background(), Message{
+		To:      []string{"lead@example.com", "cto@example.com"},
+		Subject: "Adoption digest – week 23",
+		Text:    "Engaged users: 12 " + strings.Repeat("long line ", 20),
+		HTML:    "<p>Engaged users: <b>12</b></p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := sink.received()
+	if len(messages) != 1 {
+		t.Fatalf("sink received %d messages, want 1", len(messages))
+	}
+	message := messages[0]
+	if message.From != "<digest@example.com>" {
+		t.Errorf("MAIL FROM = %q, want the bare sender address", message.From)
+	}
+	if len(message.To) != 2 || message.To[0] != "<lead@example.com>" || message.To[1] != "<cto@example.com>" {
+		t.Errorf("RCPT TO = %q", message.To)
+	}
+	if sink.auth[0] != "\x00digest\x00secret" {
+		t.Errorf("AUTH PLAIN credentials = %q", sink.auth[0])
+	}
+
+	parsed := parseMessage(t, message.Data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "Adoption digest – week 23" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if from := parsed.Header.Get("From"); from != "Copilot Metrics <digest@example.com>" {
+		t.Errorf("From header = %q", from)
+	}
+	if to := parsed.Header.Get("To"); to != "lead@example.com, cto@example.com" {
+		t.Errorf("To header = %q", to)
+	}
+	if !strings.HasSuffix(parsed.Header.Get("Message-Id"), "@example.com>") {
+		t.Errorf("Message-ID = %q, want one in the sender's domain", parsed.Header.Get("Message-Id"))
+	}
+	if parsed.Text != "Engaged users: 12 "+strings.Repeat("long line ", 20) {
+		t.Errorf("text part = %q", parsed.Text)
+	}
+	if parsed.HTML != "<p>Engaged users: <b>12</b></p>" {
+		t.Errorf("HTML part = %q", parsed.HTML)
+	}
+}
+
+func TestNewMailerRejectsInvalidSender(t *testing.T) {
+	if _, err := NewMailer(SMTPConfig{Host: "localhost", From: "digest at example.com"}); err == nil {
+		t.Error("NewMailer accepted an invalid sender address")
+	}
+}
+
+func TestMailerRequiresStartTLS(t *testing.T) {
+	sink := newSMTPSink(t)
+	cfg := sink.config()
+	cfg.Security = ""
+	mailer, err := NewMailer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mailer.Send(context.Background(), Message{To: []string{"lead@example.com"}, Subject: "digest"})
+	if err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+		t.Errorf("Send without STARTTLS support: got error %v", err)
+	}
+	if messages := sink.received(); len(messages) != 0 {
+		t.Errorf("sink received %d messages in plain text", len(messages))
+	}
+}
+
+// saveEngagedUsers stores metrics of a day for acme or one of its teams
+func saveEngagedUsers(t *testing.T, repo repositories.Repository, date, team string, engagedUsers int) {
+	t.Helper()
+	metrics := models.Metrics{Date: date, Organization: "acme", Team: team, TotalEngagedUsers: engagedUsers, TotalActiveUsers: engagedUsers}
+	if _, err := repo.SaveMetrics(context.Background(), []models.Metrics{metrics}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSenderSend(t *testing.T) {
+	repo, err := repositories.NewSQLiteRepository(filepath.Join(t.TempDir(), "metrics.db"), repositories.SaveBestEffort, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	if err := repo.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	saveEngagedUsers(t, repo, "2024-05-29", "", 4)
+	saveEngagedUsers(t, repo, "2024-06-04", "", 5)
+	saveEngagedUsers(t, repo, "2024-06-05", "", 7)
+	saveEngagedUsers(t, repo, "2024-06-05", "platform", 3)
+
+	sink := newSMTPSink(t)
+	mailer, err := NewMailer(sink.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipients := Recipients{
+		All: []string{"lead@example.com"},
+		Teams: map[string][]string{
+			"platform": {"platform@example.com"},
+			"mobile":   {"mobile@example.com"}, // No metrics, skipped
+		},
+	}
+	sender := NewSender(repo, mailer, recipients, Options{}, zap.NewNop())
+
+	now := time.Date(2024, 6, 10, 7, 0, 0, 0, time.UTC)
+	if from, to := LastCompleteWeek(now); from != "2024-06-03" || to != "2024-06-09" {
+		t.Fatalf("LastCompleteWeek(%s) = %s to %s, want 2024-06-03 to 2024-06-09", now, from, to)
+	}
+	if err := sender.Send(context.Background(), "2024-06-09", now); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := sink.received()
+	if len(messages) != 2 {
+		t.Fatalf("sink received %d messages, want 2", len(messages))
+	}
+
+	all := parseMessage(t, messages[0].Data)
+	if subject := all.Header.Get("Subject"); subject != "Copilot adoption digest 2024-06-03 to 2024-06-09: acme" {
+		t.Errorf("digest subject = %q", subject)
+	}
+	for _, want := range []string{
+		"== acme ==",
+		"Engaged users (daily average): 6 (+50.0% week over week, peak 7)",
+		"== acme / platform ==",
+	} {
+		if !strings.Contains(all.Text, want) {
+			t.Errorf("digest text does not contain %q:\n%s", want, all.Text)
+		}
+	}
+	if !strings.Contains(all.HTML, "acme / platform") {
+		t.Errorf("digest HTML does not contain the team section:\n%s", all.HTML)
+	}
+
+	team := parseMessage(t, messages[1].Data)
+	if len(messages[1].To) != 1 || messages[1].To[0] != "<platform@example.com>" {
+		t.Errorf("team digest recipients = %q", messages[1].To)
+	}
+	if subject := team.Header.Get("Subject"); subject != "Copilot adoption digest 2024-06-03 to 2024-06-09: platform" {
+		t.Errorf("team digest subject = %q", subject)
+	}
+	if strings.Contains(team.Text, "== acme ==") {
+		t.Errorf("team digest contains the organization section:\n%s", team.Text)
+	}
+}
@@ -0,0 +1,162 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"math"
+	"strings"
+	texttemplate "text/template"
+)
+
+// templateFuncs formats the digest figures in both templates
+var templateFuncs = map[string]interface{}{
+	"users":   formatUsers,
+	"percent": formatPercent,
+	"change":  formatChange,
+	"points":  formatPoints,
+	"money":   func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+}
+
+// formatUsers formats an average number of users
+func formatUsers(users float64) string {
+	if users == math.Trunc(users) {
+		return fmt.Sprintf("%.0f", users)
+	}
+	return fmt.Sprintf("%.1f", users)
+}
+
+// formatPercent formats a rate, or n/a if it is unknown
+func formatPercent(rate *float64) string {
+	if rate == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", *rate*100)
+}
+
+// formatChange formats a relative change, or n/a if it is unknown
+func formatChange(change *float64) string {
+	if change == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", *change*100)
+}
+
+// formatPoints formats a change in percentage points, or n/a if it is unknown
+func formatPoints(change *float64) string {
+	if change == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f pts", *change)
+}
+
+const textTemplate = `Copilot adoption digest for {{.From}} to {{.To}}
+Compared with {{.PreviousFrom}} to {{.PreviousTo}}
+{{range .Sections}}
+== {{.Title}} ==
+{{if eq .Days 0}}No metrics stored for this week.{{if gt .SuppressedDays 0}} {{.SuppressedDays}} days withheld by the GitHub privacy threshold.{{end}}
+{{else}}Engaged users (daily average): {{users .EngagedUsers}} ({{change .EngagedUsersChange}} week over week, peak {{.PeakEngagedUsers}})
+Active users (daily average): {{users .ActiveUsers}}
+Acceptance rate: {{percent .AcceptanceRate}} ({{points .AcceptanceRateChange}}), {{.Acceptances}} of {{.Suggestions}} suggestions
+Days with metrics: {{.Days}}{{if gt .SuppressedDays 0}}, {{.SuppressedDays}} withheld by the GitHub privacy threshold{{end}}
+{{if .Languages}}Top languages:
+{{range .Languages}}  - {{.Name}}: {{.Acceptances}} acceptances, {{percent .AcceptanceRate}} acceptance rate, {{users .EngagedUsers}} engaged users
+{{end}}{{end}}{{if .Editors}}Top editors:
+{{range .Editors}}  - {{.Name}}: {{.Acceptances}} acceptances, {{percent .AcceptanceRate}} acceptance rate, {{users .EngagedUsers}} engaged users
+{{end}}{{end}}{{end}}{{with .Seats}}Idle seats on {{.Date}}: {{.Idle}} of {{.TotalSeats}} ({{.Inactive}} inactive, {{.NeverUsed}} never used, {{money .IdleMonthlyCost}} per month)
+{{end}}{{else}}
+No metrics stored for this week.
+{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, Segoe UI, Helvetica, Arial, sans-serif; color: #1f2328;">
+<h2>Copilot adoption digest</h2>
+<p>{{.From}} to {{.To}}, compared with {{.PreviousFrom}} to {{.PreviousTo}}</p>
+{{range .Sections}}
+<h3>{{.Title}}</h3>
+{{if eq .Days 0}}<p>No metrics stored for this week.{{if gt .SuppressedDays 0}} {{.SuppressedDays}} days withheld by the GitHub privacy threshold.{{end}}</p>
+{{else}}<table cellpadding="4" style="border-collapse: collapse;">
+<tr><td>Engaged users (daily average)</td><td><b>{{users .EngagedUsers}}</b></td><td>{{change .EngagedUsersChange}} week over week, peak {{.PeakEngagedUsers}}</td></tr>
+<tr><td>Active users (daily average)</td><td><b>{{users .ActiveUsers}}</b></td><td></td></tr>
+<tr><td>Acceptance rate</td><td><b>{{percent .AcceptanceRate}}</b></td><td>{{points .AcceptanceRateChange}}, {{.Acceptances}} of {{.Suggestions}} suggestions</td></tr>
+<tr><td>Days with metrics</td><td><b>{{.Days}}</b></td><td>{{if gt .SuppressedDays 0}}{{.SuppressedDays}} withheld by the GitHub privacy threshold{{end}}</td></tr>
+</table>
+{{if .Languages}}<h4>Top languages</h4>
+<table cellpadding="4" style="border-collapse: collapse;">
+<tr><th align="left">Language</th><th align="right">Acceptances</th><th align="right">Acceptance rate</th><th align="right">Engaged users</th></tr>
+{{range .Languages}}<tr><td>{{.Name}}</td><td align="right">{{.Acceptances}}</td><td align="right">{{percent .AcceptanceRate}}</td><td align="right">{{users .EngagedUsers}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Editors}}<h4>Top editors</h4>
+<table cellpadding="4" style="border-collapse: collapse;">
+<tr><th align="left">Editor</th><th align="right">Acceptances</th><th align="right">Acceptance rate</th><th align="right">Engaged users</th></tr>
+{{range .Editors}}<tr><td>{{.Name}}</td><td align="right">{{.Acceptances}}</td><td align="right">{{percent .AcceptanceRate}}</td><td align="right">{{users .EngagedUsers}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}{{with .Seats}}<p>Idle seats on {{.Date}}: <b>{{.Idle}}</b> of {{.TotalSeats}} ({{.Inactive}} inactive, {{.NeverUsed}} never used, {{money .IdleMonthlyCost}} per month)</p>
+{{end}}{{else}}
+<p>No metrics stored for this week.</p>
+{{end}}
+</body>
+</html>
+`
+
+var (
+	parsedText = texttemplate.Must(texttemplate.New("digest").Funcs(templateFuncs).Parse(textTemplate))
+	parsedHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(templateFuncs).Parse(htmlTemplate))
+)
+
+// Subject returns the email subject of the digest
+func (d *Digest) Subject() string {
+	// A team's digest is named after the team, others after their scopes
+	teamDigest := allSameTeam(d.Sections)
+
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, section := range d.Sections {
+		title := section.Scope()
+		if teamDigest {
+			title = section.Team
+		}
+		if !seen[title] {
+			seen[title] = true
+			scopes = append(scopes, title)
+		}
+	}
+
+	subject := fmt.Sprintf("Copilot adoption digest %s to %s", d.From, d.To)
+	if len(scopes) > 0 {
+		subject += ": " + strings.Join(scopes, ", ")
+	}
+	return subject
+}
+
+// allSameTeam reports whether every section belongs to the same team
+func allSameTeam(sections []Section) bool {
+	if len(sections) == 0 {
+		return false
+	}
+	for _, section := range sections {
+		if section.Team == "" || section.Team != sections[0].Team {
+			return false
+		}
+	}
+	return true
+}
+
+// Text renders the plain-text digest
+func (d *Digest) Text() (string, error) {
+	var buf bytes.Buffer
+	if err := parsedText.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("failed to render text digest: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// HTML renders the HTML digest
+func (d *Digest) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := parsedHTML.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("failed to render HTML digest: %w", err)
+	}
+	return buf.String(), nil
+}
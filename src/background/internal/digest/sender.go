@@ -0,0 +1,104 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// Recipients lists who receives which digest
+type Recipients struct {
+	All   []string            // Receive every section
+	Teams map[string][]string // Receive the sections of a team, by team slug
+}
+
+// IsEmpty reports whether nobody receives a digest
+func (r Recipients) IsEmpty() bool {
+	return len(r.All) == 0 && len(r.Teams) == 0
+}
+
+// Sender builds the weekly digest and emails it to the recipients
+type Sender struct {
+	repo       repositories.Repository
+	mailer     *Mailer
+	recipients Recipients
+	opts       Options
+	logger     *zap.Logger
+}
+
+// NewSender creates a digest sender
+func NewSender(repo repositories.Repository, mailer *Mailer, recipients Recipients, opts Options, logger *zap.Logger) *Sender {
+	return &Sender{
+		repo:       repo,
+		mailer:     mailer,
+		recipients: recipients,
+		opts:       opts,
+		logger:     logger,
+	}
+}
+
+// Run sends the digest of the last complete week
+func (s *Sender) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	_, to := LastCompleteWeek(now)
+	return s.Send(ctx, to, now)
+}
+
+// Send builds the digest of the week ending at to (YYYY-MM-DD) and sends it
+// to every recipient, continuing past failed deliveries
+func (s *Sender) Send(ctx context.Context, to string, now time.Time) error {
+	s.logger.Info("Sending weekly adoption digest", zap.String("to", to))
+
+	digest, err := Build(ctx, s.repo, to, s.opts, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if len(s.recipients.All) > 0 {
+		errs = append(errs, s.send(ctx, digest, s.recipients.All))
+	}
+
+	teams := make([]string, 0, len(s.recipients.Teams))
+	for team := range s.recipients.Teams {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+
+	for _, team := range teams {
+		teamDigest := digest.ForTeam(team)
+		if len(teamDigest.Sections) == 0 {
+			s.logger.Warn("No metrics stored for digest team, skipping", zap.String("team", team))
+			continue
+		}
+		errs = append(errs, s.send(ctx, teamDigest, s.recipients.Teams[team]))
+	}
+
+	return errors.Join(errs...)
+}
+
+// send renders a digest and emails it
+func (s *Sender) send(ctx context.Context, digest *Digest, to []string) error {
+	text, err := digest.Text()
+	if err != nil {
+		return err
+	}
+	html, err := digest.HTML()
+	if err != nil {
+		return err
+	}
+
+	msg := Message{To: to, Subject: digest.Subject(), Text: text, HTML: html}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.logger.Error("Failed to send digest", zap.Strings("to", to), zap.Error(err))
+		return fmt.Errorf("failed to send digest to %v: %w", to, err)
+	}
+
+	s.logger.Info("Sent digest", zap.Strings("to", to), zap.String("subject", msg.Subject))
+	return nil
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+)
+
+// Filter restricts the rows of an export
+type Filter struct {
+	From         string // First date to include (YYYY-MM-DD)
+	To           string // Last date to include (YYYY-MM-DD)
+	Enterprise   string // Only include this enterprise, if set
+	Organization string // Only include this organization, if set
+	Team         string // Only include this team, if set
+	ExcludeTeams bool   // Only include organization/enterprise level data
+}
+
+// matches reports whether a document scope passes the filter
+func (f *Filter) matches(enterprise, organization, team string) bool {
+	if f.Enterprise != "" && enterprise != f.Enterprise {
+		return false
+	}
+	if f.Organization != "" && organization != f.Organization {
+		return false
+	}
+	if f.Team != "" && team != f.Team {
+		return false
+	}
+	if f.ExcludeTeams && team != "" {
+		return false
+	}
+	return true
+}
+
+// Export streams the rows of a dataset from a repository to a row writer,
+// reading one day at a time to bound memory use. It returns the number of
+// rows written.
+func Export(ctx context.Context, repo repositories.Repository, dataset Dataset, filter Filter, writer RowWriter) (int, error) {
+	fromDate, err := time.Parse("2006-01-02", filter.From)
+	if err != nil {
+		return 0, fmt.Errorf("invalid from date: %w", err)
+	}
+	toDate, err := time.Parse("2006-01-02", filter.To)
+	if err != nil {
+		return 0, fmt.Errorf("invalid to date: %w", err)
+	}
+
+	count := 0
+	write := func(rows []Row) error {
+		for _, row := range rows {
+			if err := writer.WriteRow(row); err != nil {
+				return fmt.Errorf("failed to write row: %w", err)
+			}
+			count++
+		}
+		return nil
+	}
+
+	for day := fromDate; !day.After(toDate); day = day.AddDate(0, 0, 1) {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		date := day.Format("2006-01-02")
+		rows, err := DayRows(ctx, repo, dataset, date, filter)
+		if err != nil {
+			return count, err
+		}
+		if err := write(rows); err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// DayRows loads the documents of a dataset for a single day and returns
+// their rows, filtered by scope
+func DayRows(ctx context.Context, repo repositories.Repository, dataset Dataset, date string, filter Filter) ([]Row, error) {
+	var rows []Row
+
+	switch dataset {
+	case DatasetMetrics:
+		metrics, err := repo.GetMetrics(ctx, date, date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load metrics for %s: %w", date, err)
+		}
+		reported := make(map[string]bool, len(metrics))
+		for i := range metrics {
+			reported[metrics[i].GetID()] = true
+			if filter.matches(metrics[i].Enterprise, metrics[i].Organization, metrics[i].Team) {
+				rows = append(rows, MetricsRows(&metrics[i])...)
+			}
+		}
+
+		// Team days without metrics are exported with the reason they are missing
+		markers, err := repo.GetMetricsMarkers(ctx, date, date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load metrics markers for %s: %w", date, err)
+		}
+		for i := range markers {
+			if reported[markers[i].GetID()] {
+				continue
+			}
+			if filter.matches(markers[i].Enterprise, markers[i].Organization, markers[i].Team) {
+				rows = append(rows, MetricsMarkerRow(&markers[i]))
+			}
+		}
+	case DatasetUsage:
+		usage, err := repo.GetUsage(ctx, date, date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load usage for %s: %w", date, err)
+		}
+		for i := range usage {
+			if filter.matches(usage[i].Enterprise, usage[i].Organization, usage[i].Team) {
+				rows = append(rows, UsageRows(&usage[i])...)
+			}
+		}
+	case DatasetSeats:
+		snapshots, err := repo.GetSeatsHistory(ctx, date, date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load seats for %s: %w", date, err)
+		}
+		for i := range snapshots {
+			rows = append(rows, SeatsRows(filterSeats(&snapshots[i], filter))...)
+		}
+	case DatasetSeatEvents:
+		events, err := repo.GetSeatEvents(ctx, date, date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load seat events for %s: %w", date, err)
+		}
+		// Seat events carry no team, so only the scope filters apply
+		scope := Filter{Enterprise: filter.Enterprise, Organization: filter.Organization}
+		for i := range events {
+			if scope.matches(events[i].Enterprise, events[i].Organization, "") {
+				rows = append(rows, SeatEventRow(&events[i]))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported dataset: %s", dataset)
+	}
+
+	return rows, nil
+}
+
+// filterSeats returns the seats of a snapshot matching the filter. Seats are
+// filtered on their own organization and assigning team, as an enterprise
+// snapshot spans organizations; ExcludeTeams does not apply to seats.
+func filterSeats(snapshot *models.CopilotAssignedSeats, filter Filter) *models.CopilotAssignedSeats {
+	filtered := *snapshot
+	filtered.Seats = make([]models.Seat, 0, len(snapshot.Seats))
+	for i := range snapshot.Seats {
+		seat := &snapshot.Seats[i]
+		if filter.Enterprise != "" && snapshot.Enterprise != filter.Enterprise {
+			continue
+		}
+		if filter.Organization != "" && analysis.SeatOrganization(seat, snapshot) != filter.Organization {
+			continue
+		}
+		if filter.Team != "" && analysis.SeatTeam(seat) != filter.Team {
+			continue
+		}
+		filtered.Seats = append(filtered.Seats, *seat)
+	}
+	return &filtered
+}
@@ -0,0 +1,212 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore/s3test"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"github.com/parquet-go/parquet-go"
+	"go.uber.org/zap"
+)
+
+// newTestRepository returns a SQLite repository holding usage and metrics
+// for two organizations on 2024-06-01 and 2024-06-03
+func newTestRepository(t *testing.T) repositories.Repository {
+	ctx := context.Background()
+	repo, err := repositories.NewSQLiteRepository(filepath.Join(t.TempDir(), "metrics.db"), repositories.SaveBestEffort, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var usage []models.CopilotUsage
+	var metrics []models.Metrics
+	for _, day := range []string{"2024-06-01", "2024-06-03"} {
+		for _, organization := range []string{"acme", "globex"} {
+			usage = append(usage, models.CopilotUsage{
+				Day:          day,
+				Organization: organization,
+				Breakdown: []models.UsageBreakdown{
+					{Language: "go", Editor: "vscode", SuggestionsCount: 10, AcceptancesCount: 4, ActiveUsers: 2},
+					{Language: "python", Editor: "jetbrains", SuggestionsCount: 6, AcceptancesCount: 1, ActiveUsers: 1},
+				},
+			})
+			metrics = append(metrics, models.Metrics{Date: day, Organization: organization, TotalActiveUsers: 3, TotalEngagedUsers: 2})
+		}
+	}
+	if _, err := repo.SaveUsage(ctx, usage); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SaveMetrics(ctx, metrics); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// usageRecord is a row of an exported usage Parquet file
+type usageRecord struct {
+	Organization     *string `parquet:"organization,optional"`
+	Language         *string `parquet:"language,optional"`
+	Editor           *string `parquet:"editor,optional"`
+	SuggestionsCount *int64  `parquet:"suggestions_count,optional"`
+	ActiveUsers      *int64  `parquet:"active_users,optional"`
+}
+
+// readUsage reads the rows of an exported usage Parquet file
+func readUsage(t *testing.T, data []byte) []usageRecord {
+	t.Helper()
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid parquet file: %v", err)
+	}
+	if _, exists := file.Schema().Lookup("day"); exists {
+		t.Error("the partition column day is stored in the file")
+	}
+
+	reader := parquet.NewGenericReader[usageRecord](file)
+	defer reader.Close()
+	records := make([]usageRecord, file.NumRows())
+	if n, err := reader.Read(records); n != len(records) {
+		t.Fatalf("read %d of %d rows: %v", n, len(records), err)
+	}
+	return records
+}
+
+func TestExportParquet(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	local, err := objectstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := s3test.NewServer("exports")
+	defer server.Close()
+	s3, err := objectstore.NewS3Store(server.Config("exports", "copilot"), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range map[string]objectstore.Store{"local": local, "s3": s3} {
+		t.Run(name, func(t *testing.T) {
+			filter := Filter{From: "2024-06-01", To: "2024-06-03", Organization: "acme"}
+			rows, files, err := ExportParquet(ctx, repo, DatasetUsage, filter, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != 4 || files != 2 {
+				t.Errorf("ExportParquet wrote %d rows in %d files, want 4 rows in 2 files", rows, files)
+			}
+
+			// Days without data produce no partition
+			partitions, err := store.List(ctx, "usage/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"usage/date=2024-06-01/", "usage/date=2024-06-03/"}; !slices.Equal(partitions, want) {
+				t.Errorf("usage partitions = %q, want %q", partitions, want)
+			}
+
+			data, err := store.Get(ctx, ParquetKey(DatasetUsage, "2024-06-01"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			records := readUsage(t, data)
+			if len(records) != 2 {
+				t.Fatalf("2024-06-01 partition holds %d rows, want 2", len(records))
+			}
+			record := records[0]
+			if *record.Organization != "acme" || *record.Language != "go" || *record.Editor != "vscode" ||
+				*record.SuggestionsCount != 10 || *record.ActiveUsers != 2 {
+				t.Errorf("first row = {%s %s %s %d %d}, want {acme go vscode 10 2}", *record.Organization,
+					*record.Language, *record.Editor, *record.SuggestionsCount, *record.ActiveUsers)
+			}
+
+			// Re-exporting a day replaces its file
+			filter = Filter{From: "2024-06-01", To: "2024-06-01"}
+			if _, _, err := ExportParquet(ctx, repo, DatasetUsage, filter, store); err != nil {
+				t.Fatal(err)
+			}
+			data, err = store.Get(ctx, ParquetKey(DatasetUsage, "2024-06-01"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if records := readUsage(t, data); len(records) != 4 {
+				t.Errorf("re-exported 2024-06-01 partition holds %d rows, want 4", len(records))
+			}
+		})
+	}
+
+	if _, exists := server.Object("exports", "copilot/usage/date=2024-06-03/usage.parquet"); !exists {
+		t.Errorf("parquet file not stored below the prefix, bucket keys: %q", server.Keys("exports"))
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	repo := newTestRepository(t)
+
+	var output bytes.Buffer
+	writer := NewNDJSONWriter(&output, Columns(DatasetMetrics))
+	filter := Filter{From: "2024-06-01", To: "2024-06-02", Organization: "globex"}
+	count, err := Export(context.Background(), repo, DatasetMetrics, filter, writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Errorf("Export wrote %d rows, want 1", count)
+	}
+	want := `{"date":"2024-06-01","enterprise":"","organization":"globex","team":"","surface":"total","editor":null,` +
+		`"model":null,"is_custom_model":null,"language":null,"repository":null,"active_users":3,"engaged_users":2,` +
+		`"code_suggestions":null,"code_acceptances":null,"code_lines_suggested":null,"code_lines_accepted":null,` +
+		`"chats":null,"chat_insertion_events":null,"chat_copy_events":null,"pr_summaries_created":null,"status":"reported"}`
+	if got := strings.TrimSpace(output.String()); got != want {
+		t.Errorf("NDJSON export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	repo := newTestRepository(t)
+
+	var output bytes.Buffer
+	writer, err := NewCSVWriter(&output, Columns(DatasetUsage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := Filter{From: "2024-06-03", To: "2024-06-03", Organization: "acme"}
+	if _, err := Export(context.Background(), repo, DatasetUsage, filter, writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"day,enterprise,organization,team,language,editor,suggestions_count,acceptances_count,lines_suggested,lines_accepted,active_users",
+		"2024-06-03,,acme,,go,vscode,10,4,0,0,2",
+		"2024-06-03,,acme,,python,jetbrains,6,1,0,0,1",
+	}, "\n") + "\n"
+	if got := output.String(); got != want {
+		t.Errorf("CSV export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportInvalidDates(t *testing.T) {
+	filter := Filter{From: "2024-06-01", To: "03/06/2024"}
+	if _, _, err := ExportParquet(context.Background(), nil, DatasetUsage, filter, nil); err == nil {
+		t.Error("ExportParquet accepted an invalid to date")
+	}
+}
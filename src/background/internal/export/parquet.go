@@ -0,0 +1,170 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetTable holds the Parquet schema of a dataset. The first column of
+// every dataset is its date, which becomes the date=YYYY-MM-DD partition
+// directory and is therefore not stored in the files.
+type parquetTable struct {
+	schema *parquet.Schema
+	// columnIndex maps each leaf column of the schema, in schema order, to
+	// its position in the exported rows
+	columnIndex []int
+	columnTypes []ColumnType
+}
+
+// parquetNode returns the Parquet node for a column type
+func parquetNode(columnType ColumnType) parquet.Node {
+	switch columnType {
+	case ColumnInteger:
+		return parquet.Optional(parquet.Int(64))
+	case ColumnBoolean:
+		return parquet.Optional(parquet.Leaf(parquet.BooleanType))
+	case ColumnDate:
+		return parquet.Optional(parquet.Date())
+	case ColumnTimestamp:
+		return parquet.Optional(parquet.Timestamp(parquet.Microsecond))
+	default:
+		return parquet.Optional(parquet.String())
+	}
+}
+
+// newParquetTable builds the Parquet schema of a dataset
+func newParquetTable(dataset Dataset) *parquetTable {
+	columns := Columns(dataset)
+	positions := columnIndex(columns)
+
+	group := parquet.Group{}
+	for _, column := range columns[1:] {
+		group[column.Name] = parquetNode(column.Type)
+	}
+
+	schema := parquet.NewSchema(string(dataset), group)
+	table := &parquetTable{schema: schema}
+	for _, field := range schema.Fields() {
+		position := positions[field.Name()]
+		table.columnIndex = append(table.columnIndex, position)
+		table.columnTypes = append(table.columnTypes, columns[position].Type)
+	}
+
+	return table
+}
+
+// parquetValue converts a row value to a Parquet value
+func parquetValue(value interface{}, columnType ColumnType) (parquet.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return parquet.NullValue(), nil
+	case int:
+		return parquet.Int64Value(int64(v)), nil
+	case bool:
+		return parquet.BooleanValue(v), nil
+	case time.Time:
+		return parquet.Int64Value(v.UTC().UnixMicro()), nil
+	case string:
+		if columnType == ColumnDate {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return parquet.Value{}, fmt.Errorf("invalid date %q: %w", v, err)
+			}
+			return parquet.Int32Value(int32(date.Unix() / 86400)), nil
+		}
+		return parquet.ByteArrayValue([]byte(v)), nil
+	default:
+		return parquet.Value{}, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// encode writes rows to an in-memory Parquet file
+func (t *parquetTable) encode(rows []Row) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := parquet.NewWriter(&buffer, t.schema, parquet.Compression(&parquet.Snappy))
+
+	parquetRows := make([]parquet.Row, 0, len(rows))
+	for _, row := range rows {
+		parquetRow := make(parquet.Row, 0, len(t.columnIndex))
+		for leaf, position := range t.columnIndex {
+			value, err := parquetValue(row[position], t.columnTypes[leaf])
+			if err != nil {
+				return nil, err
+			}
+
+			definitionLevel := 1
+			if value.IsNull() {
+				definitionLevel = 0
+			}
+			parquetRow = append(parquetRow, value.Level(0, definitionLevel, leaf))
+		}
+		parquetRows = append(parquetRows, parquetRow)
+	}
+
+	if _, err := writer.WriteRows(parquetRows); err != nil {
+		return nil, fmt.Errorf("failed to write parquet rows: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ParquetKey returns the object key of a dataset's Parquet file for a day
+func ParquetKey(dataset Dataset, date string) string {
+	return fmt.Sprintf("%s/date=%s/%s.parquet", dataset, date, dataset)
+}
+
+// ExportParquet writes a dataset as Parquet files partitioned by date, one
+// file per day under <dataset>/date=YYYY-MM-DD/. Days without data produce no
+// file; files of re-exported days are replaced. It returns the number of rows
+// and files written.
+func ExportParquet(ctx context.Context, repo repositories.Repository, dataset Dataset, filter Filter, store objectstore.Store) (int, int, error) {
+	fromDate, err := time.Parse("2006-01-02", filter.From)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid from date: %w", err)
+	}
+	toDate, err := time.Parse("2006-01-02", filter.To)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid to date: %w", err)
+	}
+
+	table := newParquetTable(dataset)
+	rowCount, fileCount := 0, 0
+
+	for day := fromDate; !day.After(toDate); day = day.AddDate(0, 0, 1) {
+		if err := ctx.Err(); err != nil {
+			return rowCount, fileCount, err
+		}
+
+		date := day.Format("2006-01-02")
+		rows, err := DayRows(ctx, repo, dataset, date, filter)
+		if err != nil {
+			return rowCount, fileCount, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		data, err := table.encode(rows)
+		if err != nil {
+			return rowCount, fileCount, fmt.Errorf("failed to encode %s for %s: %w", dataset, date, err)
+		}
+
+		if err := store.Put(ctx, ParquetKey(dataset, date), data, "application/vnd.apache.parquet"); err != nil {
+			return rowCount, fileCount, err
+		}
+
+		rowCount += len(rows)
+		fileCount++
+	}
+
+	return rowCount, fileCount, nil
+}
@@ -0,0 +1,349 @@
+package export
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// Dataset identifies a stored dataset that can be exported
+type Dataset string
+
+const (
+	DatasetMetrics    Dataset = "metrics"
+	DatasetUsage      Dataset = "usage"
+	DatasetSeats      Dataset = "seats"
+	DatasetSeatEvents Dataset = "seat_events"
+)
+
+// Datasets lists every exportable dataset
+var Datasets = []Dataset{DatasetMetrics, DatasetUsage, DatasetSeats, DatasetSeatEvents}
+
+// ParseDataset converts a dataset name to a Dataset
+func ParseDataset(name string) (Dataset, error) {
+	for _, dataset := range Datasets {
+		if string(dataset) == name {
+			return dataset, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported dataset: %s", name)
+}
+
+// ColumnType defines the type of the values of a column
+type ColumnType string
+
+const (
+	ColumnString    ColumnType = "string"
+	ColumnInteger   ColumnType = "integer"
+	ColumnBoolean   ColumnType = "boolean"
+	ColumnDate      ColumnType = "date"
+	ColumnTimestamp ColumnType = "timestamp"
+)
+
+// Column describes a column of an exported dataset
+type Column struct {
+	Name        string
+	Type        ColumnType
+	Description string
+}
+
+// Row holds the values of an exported row, in column order. Values are
+// string, int, bool, time.Time (timestamps) or nil for missing values; dates
+// are YYYY-MM-DD strings.
+type Row []interface{}
+
+// Metric surfaces used in the flattened metrics rows
+const (
+	SurfaceTotal              = "total"
+	SurfaceIdeCodeCompletions = "ide_code_completions"
+	SurfaceIdeChat            = "ide_chat"
+	SurfaceDotComChat         = "dotcom_chat"
+	SurfaceDotComPullRequests = "dotcom_pull_requests"
+)
+
+var metricsColumns = []Column{
+	{"date", ColumnDate, "Day the metrics apply to"},
+	{"enterprise", ColumnString, "Enterprise slug, for enterprise scope"},
+	{"organization", ColumnString, "Organization login, for organization scope"},
+	{"team", ColumnString, "Team slug, empty for organization/enterprise level metrics"},
+	{"surface", ColumnString, "total, ide_code_completions, ide_chat, dotcom_chat or dotcom_pull_requests"},
+	{"editor", ColumnString, "IDE name (ide_code_completions, ide_chat)"},
+	{"model", ColumnString, "Model name"},
+	{"is_custom_model", ColumnBoolean, "Whether the model is a custom model"},
+	{"language", ColumnString, "Language name (ide_code_completions)"},
+	{"repository", ColumnString, "Repository name (dotcom_pull_requests)"},
+	{"active_users", ColumnInteger, "Total active users (total rows only)"},
+	{"engaged_users", ColumnInteger, "Engaged users at the granularity of the row"},
+	{"code_suggestions", ColumnInteger, "Code suggestions shown"},
+	{"code_acceptances", ColumnInteger, "Code suggestions accepted"},
+	{"code_lines_suggested", ColumnInteger, "Lines of code suggested"},
+	{"code_lines_accepted", ColumnInteger, "Lines of code accepted"},
+	{"chats", ColumnInteger, "Chat conversations"},
+	{"chat_insertion_events", ColumnInteger, "Chat code insertions"},
+	{"chat_copy_events", ColumnInteger, "Chat code copies"},
+	{"pr_summaries_created", ColumnInteger, "Pull request summaries created"},
+	{"status", ColumnString, "reported, or suppressed (below the privacy threshold) / not_found for team days without metrics"},
+}
+
+var usageColumns = []Column{
+	{"day", ColumnDate, "Day the usage applies to"},
+	{"enterprise", ColumnString, "Enterprise slug, for enterprise scope"},
+	{"organization", ColumnString, "Organization login, for organization scope"},
+	{"team", ColumnString, "Team slug, empty for organization/enterprise level usage"},
+	{"language", ColumnString, "Language name"},
+	{"editor", ColumnString, "IDE name"},
+	{"suggestions_count", ColumnInteger, "Code suggestions shown"},
+	{"acceptances_count", ColumnInteger, "Code suggestions accepted"},
+	{"lines_suggested", ColumnInteger, "Lines of code suggested"},
+	{"lines_accepted", ColumnInteger, "Lines of code accepted"},
+	{"active_users", ColumnInteger, "Engaged users for the language and editor"},
+}
+
+var seatsColumns = []Column{
+	{"date", ColumnDate, "Day of the seats snapshot"},
+	{"enterprise", ColumnString, "Enterprise slug, for enterprise scope"},
+	{"organization", ColumnString, "Organization the seat belongs to"},
+	{"login", ColumnString, "Login of the seat assignee"},
+	{"plan_type", ColumnString, "Copilot plan type (business, enterprise)"},
+	{"assigning_team", ColumnString, "Slug of the assigning team, (direct) for directly assigned seats"},
+	{"created_at", ColumnTimestamp, "When the seat was assigned"},
+	{"updated_at", ColumnTimestamp, "When the seat was last updated"},
+	{"last_activity_at", ColumnTimestamp, "Last Copilot activity of the assignee"},
+	{"last_activity_editor", ColumnString, "Editor of the last activity"},
+	{"pending_cancellation_date", ColumnDate, "Date the seat will be cancelled"},
+}
+
+var seatEventsColumns = []Column{
+	{"date", ColumnDate, "Day the change was observed"},
+	{"observed_at", ColumnTimestamp, "Time of the snapshot the change was observed in"},
+	{"type", ColumnString, "seat_added, seat_removed, plan_changed, assigning_team_changed or pending_cancellation_set"},
+	{"enterprise", ColumnString, "Enterprise slug, for enterprise scope"},
+	{"organization", ColumnString, "Organization the seat belongs to"},
+	{"login", ColumnString, "Login of the seat assignee"},
+	{"previous_value", ColumnString, "Value before the change"},
+	{"new_value", ColumnString, "Value after the change"},
+}
+
+// Columns returns the column schema of a dataset
+func Columns(dataset Dataset) []Column {
+	switch dataset {
+	case DatasetMetrics:
+		return metricsColumns
+	case DatasetUsage:
+		return usageColumns
+	case DatasetSeats:
+		return seatsColumns
+	case DatasetSeatEvents:
+		return seatEventsColumns
+	default:
+		return nil
+	}
+}
+
+// metricsColumnIndex maps metrics column names to their position
+var metricsColumnIndex = columnIndex(metricsColumns)
+
+// columnIndex maps column names to their position in a schema
+func columnIndex(columns []Column) map[string]int {
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[column.Name] = i
+	}
+	return index
+}
+
+// metricsRowBuilder builds a flattened metrics row by column name
+type metricsRowBuilder Row
+
+// set sets the value of a metrics column
+func (b metricsRowBuilder) set(column string, value interface{}) {
+	b[metricsColumnIndex[column]] = value
+}
+
+// metricsRow builds a flattened metrics row; values missing for the surface stay nil
+func metricsRow(m *models.Metrics, surface string) metricsRowBuilder {
+	row := make(metricsRowBuilder, len(metricsColumns))
+	row.set("date", m.Date)
+	row.set("enterprise", m.Enterprise)
+	row.set("organization", m.Organization)
+	row.set("team", m.Team)
+	row.set("status", MetricsReported)
+	row.set("surface", surface)
+	return row
+}
+
+// MetricsReported is the status of metrics rows with data
+const MetricsReported = "reported"
+
+// MetricsMarkerRow returns the total row standing in for the missing metrics
+// of a team day, with the reason in the status column and no values
+func MetricsMarkerRow(marker *models.MetricsMarker) Row {
+	row := metricsRow(&models.Metrics{
+		Date:         marker.Date,
+		Enterprise:   marker.Enterprise,
+		Organization: marker.Organization,
+		Team:         marker.Team,
+	}, SurfaceTotal)
+	row.set("status", string(marker.Status))
+	return Row(row)
+}
+
+// MetricsRows flattens metrics to one row per day, scope, team, surface,
+// editor, model and language (or repository)
+func MetricsRows(m *models.Metrics) []Row {
+	total := metricsRow(m, SurfaceTotal)
+	total.set("active_users", m.TotalActiveUsers)
+	total.set("engaged_users", m.TotalEngagedUsers)
+	rows := []Row{Row(total)}
+
+	if m.CopilotIdeCodeCompletions != nil {
+		for _, editor := range m.CopilotIdeCodeCompletions.Editors {
+			for _, model := range editor.Models {
+				for _, language := range model.Languages {
+					row := metricsRow(m, SurfaceIdeCodeCompletions)
+					row.set("editor", editor.Name)
+					row.set("model", model.Name)
+					row.set("is_custom_model", model.IsCustomModel)
+					row.set("language", language.Name)
+					row.set("engaged_users", language.TotalEngagedUsers)
+					row.set("code_suggestions", language.TotalCodeSuggestions)
+					row.set("code_acceptances", language.TotalCodeAcceptances)
+					row.set("code_lines_suggested", language.TotalCodeLinesSuggested)
+					row.set("code_lines_accepted", language.TotalCodeLinesAccepted)
+					rows = append(rows, Row(row))
+				}
+			}
+		}
+	}
+
+	if m.IdeChat != nil {
+		for _, editor := range m.IdeChat.Editors {
+			for _, model := range editor.Models {
+				row := metricsRow(m, SurfaceIdeChat)
+				row.set("editor", editor.Name)
+				row.set("model", model.Name)
+				row.set("is_custom_model", model.IsCustomModel)
+				row.set("engaged_users", model.TotalEngagedUsers)
+				row.set("chats", model.TotalChats)
+				row.set("chat_insertion_events", model.TotalChatInsertionEvents)
+				row.set("chat_copy_events", model.TotalChatCopyEvents)
+				rows = append(rows, Row(row))
+			}
+		}
+	}
+
+	if m.DotComChat != nil {
+		for _, model := range m.DotComChat.Models {
+			row := metricsRow(m, SurfaceDotComChat)
+			row.set("model", model.Name)
+			row.set("is_custom_model", model.IsCustomModel)
+			row.set("engaged_users", model.TotalEngagedUsers)
+			row.set("chats", model.TotalChats)
+			rows = append(rows, Row(row))
+		}
+	}
+
+	if m.DotComPullRequests != nil {
+		for _, repository := range m.DotComPullRequests.Repositories {
+			for _, model := range repository.Models {
+				row := metricsRow(m, SurfaceDotComPullRequests)
+				row.set("model", model.Name)
+				row.set("is_custom_model", model.IsCustomModel)
+				row.set("repository", repository.Name)
+				row.set("engaged_users", model.TotalEngagedUsers)
+				row.set("pr_summaries_created", model.TotalPrSummariesCreated)
+				rows = append(rows, Row(row))
+			}
+		}
+	}
+
+	return rows
+}
+
+// UsageRows returns one row per usage breakdown entry
+func UsageRows(u *models.CopilotUsage) []Row {
+	rows := make([]Row, 0, len(u.Breakdown))
+	for _, breakdown := range u.Breakdown {
+		rows = append(rows, Row{
+			u.Day,
+			u.Enterprise,
+			u.Organization,
+			u.Team,
+			breakdown.Language,
+			breakdown.Editor,
+			breakdown.SuggestionsCount,
+			breakdown.AcceptancesCount,
+			breakdown.LinesSuggested,
+			breakdown.LinesAccepted,
+			breakdown.ActiveUsers,
+		})
+	}
+	return rows
+}
+
+// SeatsRows returns one row per seat of a snapshot
+func SeatsRows(s *models.CopilotAssignedSeats) []Row {
+	rows := make([]Row, 0, len(s.Seats))
+	for i := range s.Seats {
+		seat := &s.Seats[i]
+
+		var lastActivityAt interface{}
+		if seat.LastActivityAt != nil {
+			lastActivityAt = *seat.LastActivityAt
+		}
+
+		var pendingCancellationDate interface{}
+		if seat.PendingCancellationDate != "" {
+			pendingCancellationDate = seat.PendingCancellationDate
+		}
+
+		rows = append(rows, Row{
+			s.Date,
+			s.Enterprise,
+			analysis.SeatOrganization(seat, s),
+			seat.Assignee.Login,
+			seat.PlanType,
+			analysis.SeatTeam(seat),
+			seat.CreatedAt,
+			seat.UpdatedAt,
+			lastActivityAt,
+			seat.LastActivityEditor,
+			pendingCancellationDate,
+		})
+	}
+	return rows
+}
+
+// SeatEventRow returns the row of a seat event
+func SeatEventRow(e *models.SeatEvent) Row {
+	return Row{
+		e.Date,
+		e.ObservedAt,
+		string(e.Type),
+		e.Enterprise,
+		e.Organization,
+		e.Login,
+		e.PreviousValue,
+		e.NewValue,
+	}
+}
+
+// formatValue formats a row value as text
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return fmt.Sprintf("%d", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,134 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format defines the file format of an export
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat converts a format name to a Format
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON, "jsonl":
+		return FormatNDJSON, nil
+	case FormatParquet:
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", name)
+	}
+}
+
+// RowWriter writes exported rows
+type RowWriter interface {
+	// WriteRow writes a single row
+	WriteRow(row Row) error
+
+	// Close flushes any buffered rows
+	Close() error
+}
+
+// NewRowWriter creates a row writer for the given format
+func NewRowWriter(w io.Writer, format Format, columns []Column) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w, columns)
+	case FormatNDJSON:
+		return NewNDJSONWriter(w, columns), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// CSVWriter writes rows as CSV with a header row
+type CSVWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+// NewCSVWriter creates a CSV writer and writes the header row
+func NewCSVWriter(w io.Writer, columns []Column) (*CSVWriter, error) {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &CSVWriter{writer: writer, record: make([]string, len(columns))}, nil
+}
+
+// WriteRow writes a row as a CSV record
+func (c *CSVWriter) WriteRow(row Row) error {
+	for i, value := range row {
+		c.record[i] = formatValue(value)
+	}
+	return c.writer.Write(c.record)
+}
+
+// Close flushes the CSV writer
+func (c *CSVWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// NDJSONWriter writes rows as newline delimited JSON objects
+type NDJSONWriter struct {
+	writer  *bufio.Writer
+	columns []Column
+}
+
+// NewNDJSONWriter creates a newline delimited JSON writer
+func NewNDJSONWriter(w io.Writer, columns []Column) *NDJSONWriter {
+	return &NDJSONWriter{writer: bufio.NewWriter(w), columns: columns}
+}
+
+// WriteRow writes a row as a JSON object keyed by column name, keeping the
+// column order of the schema
+func (n *NDJSONWriter) WriteRow(row Row) error {
+	n.writer.WriteByte('{')
+	for i, column := range n.columns {
+		if i > 0 {
+			n.writer.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(column.Name)
+		n.writer.Write(name)
+		n.writer.WriteByte(':')
+
+		value := row[i]
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339)
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal column %s: %w", column.Name, err)
+		}
+		n.writer.Write(data)
+	}
+	n.writer.WriteString("}\n")
+	return nil
+}
+
+// Close flushes the buffered output
+func (n *NDJSONWriter) Close() error {
+	return n.writer.Flush()
+}
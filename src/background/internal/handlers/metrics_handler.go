@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/cardonator/copilot-metrics-dashboard/internal/anomaly"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/services"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/validation"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +25,17 @@ type MetricsHandler struct {
 	repository    repositories.Repository
 	teams         []string
 	useTestData   bool
+	validator     *validation.Validator
+	detector      *anomaly.Detector
+
+	// quarantinedIDs holds the IDs of the metrics quarantined by the last
+	// ingestion run, so that no usage is derived from them
+	quarantinedIDs map[string]bool
+	// run is the record of the run in progress, nil outside of Run
+	run *models.IngestionRun
+
+	saveRetries    int
+	saveRetryDelay time.Duration
 }
 
 // NewMetricsHandler creates a new metrics handler
@@ -37,6 +55,23 @@ func NewMetricsHandler(
 	}
 }
 
+// SetValidator enables validation of fetched metrics before they are saved
+func (h *MetricsHandler) SetValidator(validator *validation.Validator) {
+	h.validator = validator
+}
+
+// SetSaveRetries makes the handler retry the documents of a save that failed,
+// waiting delay before the first retry and twice as long before each further one
+func (h *MetricsHandler) SetSaveRetries(retries int, delay time.Duration) {
+	h.saveRetries = retries
+	h.saveRetryDelay = delay
+}
+
+// SetAnomalyDetector enables anomaly detection after each ingestion run
+func (h *MetricsHandler) SetAnomalyDetector(detector *anomaly.Detector) {
+	h.detector = detector
+}
+
 // ProcessUsage processes and stores usage data derived from metrics
 func (h *MetricsHandler) ProcessUsage(ctx context.Context) error {
 	h.logger.Info("Processing GitHub Copilot usage from metrics")
@@ -44,6 +79,13 @@ func (h *MetricsHandler) ProcessUsage(ctx context.Context) error {
 	var usageData []models.CopilotUsage
 	var err error
 
+	// Documents are counted in the record of the run in progress; counts of
+	// a call outside of Run are discarded
+	run := h.run
+	if run == nil {
+		run = &models.IngestionRun{}
+	}
+
 	if h.useTestData {
 		h.logger.Info("Using test data for usage processing")
 		usageData, err = h.metricsClient.LoadTestUsageData()
@@ -65,8 +107,26 @@ func (h *MetricsHandler) ProcessUsage(ctx context.Context) error {
 		return err
 	}
 
+	// Skip the usage of days whose metrics were quarantined
+	if len(h.quarantinedIDs) > 0 {
+		kept := usageData[:0]
+		for _, usage := range usageData {
+			if h.quarantinedIDs[usage.GetID()] {
+				h.logger.Warn("Skipping usage derived from quarantined metrics", zap.String("id", usage.GetID()))
+				run.Skipped++
+				continue
+			}
+			kept = append(kept, usage)
+		}
+		usageData = kept
+	}
+
 	h.logger.Info("Saving usage data", zap.Int("count", len(usageData)))
-	if err := h.repository.SaveUsage(ctx, usageData); err != nil {
+	result, err := saveWithRetry(ctx, h.logger, h.saveRetries, h.saveRetryDelay, "usage", usageData,
+		func(usage models.CopilotUsage) string { return usage.GetID() }, h.repository.SaveUsage)
+	run.Saved += len(result.Succeeded)
+	run.Failed += len(result.Failed)
+	if err != nil {
 		h.logger.Error("Failed to save usage data", zap.Error(err))
 		return err
 	}
@@ -75,18 +135,81 @@ func (h *MetricsHandler) ProcessUsage(ctx context.Context) error {
 	return nil
 }
 
-// Run runs the metrics ingestion process and also processes usage data
-func (h *MetricsHandler) Run(ctx context.Context) error {
+// Run runs the metrics ingestion process and also processes usage data, and
+// records the run in the run ledger. Documents that still fail to save after
+// their retries fail the run; the run is partial if others were saved.
+func (h *MetricsHandler) Run(ctx context.Context) (err error) {
+	h.run = startIngestionRun(jobMetrics)
+	calls := h.metricsClient.APICalls()
+	defer func() {
+		if h.run.Failed > 0 && h.run.Saved > 0 {
+			h.run.Status = models.RunPartial
+			if err == nil {
+				err = fmt.Errorf("failed to save %d of %d documents", h.run.Failed, h.run.Failed+h.run.Saved)
+			}
+		}
+		h.run.APICalls = h.metricsClient.APICalls() - calls
+		recordIngestionRun(ctx, h.logger, h.repository, h.run, err)
+		h.run = nil
+	}()
+
 	// Run the original metrics ingestion
 	if err := h.runMetricsIngestion(ctx); err != nil {
 		return err
 	}
 
 	// Process usage data from the metrics
-	return h.ProcessUsage(ctx)
+	if err := h.ProcessUsage(ctx); err != nil {
+		return err
+	}
+
+	h.detectAnomalies(ctx)
+	return nil
 }
 
-// runMetricsIngestion handles the original metrics ingestion process
+// anomalyWindowDays is the number of days, ending today, checked for
+// anomalies after each run; it matches the days returned by the metrics API
+const anomalyWindowDays = 28
+
+// detectAnomalies checks the recent stored metrics for anomalies and stores
+// them. Failures are logged, as detection must not fail the ingestion.
+func (h *MetricsHandler) detectAnomalies(ctx context.Context) {
+	if h.detector == nil || h.repository == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	to := now.Format("2006-01-02")
+	from := now.AddDate(0, 0, -(anomalyWindowDays - 1)).Format("2006-01-02")
+
+	anomalies, err := h.detector.DetectStored(ctx, h.repository, from, to, now)
+	if err != nil {
+		h.logger.Warn("Failed to detect anomalies", zap.Error(err))
+		return
+	}
+
+	for _, a := range anomalies {
+		h.logger.Warn("Metrics anomaly detected",
+			zap.String("date", a.Date),
+			zap.String("metric", a.Metric),
+			zap.String("team", a.Team),
+			zap.String("editor", a.Editor),
+			zap.String("direction", a.Direction),
+			zap.String("severity", a.Severity),
+			zap.Float64("value", a.Value),
+			zap.Float64("baseline", a.Baseline))
+	}
+
+	if len(anomalies) == 0 {
+		return
+	}
+	if err := h.repository.SaveAnomalies(ctx, anomalies); err != nil {
+		h.logger.Warn("Failed to save anomalies", zap.Error(err))
+	}
+}
+
+// runMetricsIngestion handles the original metrics ingestion process,
+// counting documents in the record of the run in progress
 func (h *MetricsHandler) runMetricsIngestion(ctx context.Context) error {
 	h.logger.Info("Running GitHub Copilot metrics ingestion")
 
@@ -100,25 +223,68 @@ func (h *MetricsHandler) runMetricsIngestion(ctx context.Context) error {
 	}
 	metrics = append(metrics, orgMetrics...)
 
-	// Process team metrics
-	if len(h.teams) > 0 {
-		for _, team := range h.teams {
-			teamMetrics, err := h.extractMetrics(team)
-			if err != nil {
-				h.logger.Warn("Failed to extract metrics for team", zap.String("team", team), zap.Error(err))
-				continue
-			}
-			metrics = append(metrics, teamMetrics...)
+	if len(orgMetrics) == 0 {
+		h.logger.Info("No metrics returned for the organization/enterprise")
+	}
+
+	// Process team metrics. Days the organization/enterprise reports but a
+	// team does not are recorded as markers, so that missing data can be
+	// told apart from zero activity.
+	var markers []models.MetricsMarker
+	observedAt := time.Now().UTC()
+	for _, team := range h.teams {
+		teamMetrics, err := h.extractMetrics(team)
+		if errors.Is(err, services.ErrTeamNotFound) {
+			h.logger.Warn("Team not found", zap.String("team", team))
+			markers = append(markers, teamMarkers(orgMetrics, team, nil, models.MetricsTeamNotFound, observedAt)...)
+			continue
+		}
+		if err != nil {
+			h.logger.Warn("Failed to extract metrics for team", zap.String("team", team), zap.Error(err))
+			continue
+		}
+
+		suppressed := teamMarkers(orgMetrics, team, teamMetrics, models.MetricsSuppressed, observedAt)
+		if len(suppressed) > 0 {
+			h.logger.Info("Team metrics suppressed below the privacy threshold",
+				zap.String("team", team),
+				zap.Int("days", len(suppressed)))
 		}
+		markers = append(markers, suppressed...)
+		metrics = append(metrics, teamMetrics...)
 	}
 
 	h.logger.Info("Metrics extracted", zap.Int("count", len(metrics)))
+	fetched := len(metrics)
+	h.run.Fetched += fetched
+
+	metrics, err = h.validateMetrics(ctx, metrics)
+	if err != nil {
+		return err
+	}
+	h.run.Skipped += fetched - len(metrics)
 
 	// Save metrics to repository if available
 	if h.repository != nil {
-		if err := h.repository.SaveMetrics(ctx, metrics); err != nil {
+		// A partially saved batch does not stop the run, so that the usage
+		// and markers of the saved days are stored too
+		result, err := saveWithRetry(ctx, h.logger, h.saveRetries, h.saveRetryDelay, "metrics", metrics,
+			func(metric models.Metrics) string { return metric.GetID() }, h.repository.SaveMetrics)
+		h.run.Saved += len(result.Succeeded)
+		h.run.Failed += len(result.Failed)
+		if err != nil {
 			h.logger.Error("Failed to save metrics", zap.Error(err))
-			return err
+			if len(result.Succeeded) == 0 {
+				return err
+			}
+		}
+		if len(markers) > 0 {
+			if err := h.repository.SaveMetricsMarkers(ctx, markers); err != nil {
+				h.logger.Error("Failed to save metrics markers", zap.Error(err))
+				h.run.Failed += len(markers)
+				return err
+			}
+			h.run.Saved += len(markers)
 		}
 	} else {
 		h.logger.Info("Repository not available, skipping save operation")
@@ -127,6 +293,66 @@ func (h *MetricsHandler) runMetricsIngestion(ctx context.Context) error {
 	return nil
 }
 
+// validateMetrics runs the data-quality rules over the fetched metrics and
+// returns the metrics to save, without the quarantined ones
+func (h *MetricsHandler) validateMetrics(ctx context.Context, metrics []models.Metrics) ([]models.Metrics, error) {
+	h.quarantinedIDs = nil
+	if h.validator == nil {
+		return metrics, nil
+	}
+
+	result := h.validator.ValidateMetrics(metrics)
+	recordValidation(ctx, h.logger, h.repository, result)
+	if len(result.Quarantined) == 0 || h.repository == nil {
+		return metrics, nil
+	}
+
+	h.quarantinedIDs = make(map[string]bool, len(result.Quarantined))
+	kept := make([]models.Metrics, 0, len(metrics))
+	for _, metric := range metrics {
+		if !result.IsQuarantined(metric.ID) {
+			kept = append(kept, metric)
+			continue
+		}
+
+		if err := quarantineDocument(ctx, h.repository, result, validation.KindMetrics, metric.ID,
+			metric.Date, metric.Enterprise, metric.Organization, metric.Team, metric); err != nil {
+			h.logger.Error("Failed to quarantine metrics", zap.String("id", metric.ID), zap.Error(err))
+			return nil, err
+		}
+		h.quarantinedIDs[metric.ID] = true
+	}
+
+	return kept, nil
+}
+
+// teamMarkers returns a marker with the given status for every day of the
+// organization/enterprise metrics missing from the metrics of a team
+func teamMarkers(orgMetrics []models.Metrics, team string, teamMetrics []models.Metrics, status models.MetricsStatus, observedAt time.Time) []models.MetricsMarker {
+	reported := make(map[string]bool, len(teamMetrics))
+	for _, metric := range teamMetrics {
+		reported[metric.Date] = true
+	}
+
+	var markers []models.MetricsMarker
+	for _, metric := range orgMetrics {
+		if metric.Team != "" || reported[metric.Date] {
+			continue
+		}
+		marker := models.MetricsMarker{
+			Date:         metric.Date,
+			Enterprise:   metric.Enterprise,
+			Organization: metric.Organization,
+			Team:         team,
+			Status:       status,
+			ObservedAt:   observedAt,
+		}
+		marker.ID = marker.GetID()
+		markers = append(markers, marker)
+	}
+	return markers
+}
+
 // extractMetrics extracts Copilot metrics for the given team or organization/enterprise
 func (h *MetricsHandler) extractMetrics(team string) ([]models.Metrics, error) {
 	if h.useTestData {
@@ -144,3 +370,230 @@ func (h *MetricsHandler) extractMetrics(team string) ([]models.Metrics, error) {
 	h.logger.Info("Fetching GitHub Copilot metrics for organization", zap.String("organization", organization), zap.String("team", team))
 	return h.metricsClient.GetCopilotMetricsForOrganization(organization, team)
 }
+
+// ReprocessResult summarizes a reprocessing run
+type ReprocessResult struct {
+	Responses   int  `json:"responses"`   // Archived metrics responses read
+	Skipped     int  `json:"skipped"`     // Responses skipped because of their status or an unreadable body
+	Metrics     int  `json:"metrics"`     // Metrics documents rebuilt
+	Quarantined int  `json:"quarantined"` // Rebuilt metrics quarantined by the data-quality rules
+	Usage       int  `json:"usage"`       // Usage documents rebuilt
+	Markers     int  `json:"markers"`     // Markers rebuilt for suppressed or unknown team days
+	Saved       bool `json:"saved"`       // Whether the rebuilt documents were saved
+	Failed      int  `json:"failed"`      // Documents that could not be saved after their retries
+}
+
+// Reprocess rebuilds metrics and usage from the metrics responses archived
+// between two fetch dates (YYYY-MM-DD, inclusive) using the current transform
+// code. When a day was fetched several times the latest response wins. The
+// rebuilt metrics are validated and saved with retries like fetched ones, and
+// quarantined metrics produce no usage. With dryRun the rebuilt documents are
+// not saved and nothing is recorded, the quarantined metrics are only counted.
+func (h *MetricsHandler) Reprocess(ctx context.Context, from, to string, dryRun bool) (*ReprocessResult, error) {
+	responses, err := h.repository.GetRawResponses(ctx, models.RawResponseMetrics, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archived responses: %w", err)
+	}
+
+	result := &ReprocessResult{Responses: len(responses)}
+
+	// Responses are ordered by fetch time, so later fetches replace earlier ones
+	metricsByID := make(map[string]models.Metrics)
+	fetches := newFetchGroups()
+	for i := range responses {
+		response := &responses[i]
+		if response.StatusCode == 404 && response.Team != "" {
+			fetches.notFound(response)
+		}
+		if response.StatusCode != 200 {
+			result.Skipped++
+			continue
+		}
+
+		metrics, err := services.ParseMetricsResponse(response)
+		if err != nil {
+			h.logger.Warn("Skipping unreadable archived response",
+				zap.String("id", response.ID),
+				zap.String("url", response.URL),
+				zap.Error(err))
+			result.Skipped++
+			continue
+		}
+
+		fetches.fetched(response, metrics)
+		for _, metric := range metrics {
+			metric.ID = metric.GetID()
+			metricsByID[metric.ID] = metric
+		}
+	}
+
+	// Markers are rebuilt per fetch, dropping days for which a team has metrics
+	var markers []models.MetricsMarker
+	for _, marker := range fetches.markers() {
+		if _, exists := metricsByID[marker.ID]; !exists {
+			markers = append(markers, marker)
+		}
+	}
+
+	metrics := make([]models.Metrics, 0, len(metricsByID))
+	for _, metric := range metricsByID {
+		metrics = append(metrics, metric)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].ID < metrics[j].ID
+	})
+
+	result.Metrics = len(metrics)
+	if dryRun {
+		if h.validator != nil {
+			validated := h.validator.ValidateMetrics(metrics)
+			metrics = slices.DeleteFunc(metrics, func(metric models.Metrics) bool {
+				return validated.IsQuarantined(metric.ID)
+			})
+		}
+	} else if metrics, err = h.validateMetrics(ctx, metrics); err != nil {
+		return nil, err
+	}
+	result.Quarantined = result.Metrics - len(metrics)
+
+	// Usage is derived from the organization/enterprise level metrics of each scope
+	scopes := make(map[string][]models.Metrics)
+	for _, metric := range metrics {
+		if metric.Team == "" {
+			scope := metric.Enterprise + "/" + metric.Organization
+			scopes[scope] = append(scopes[scope], metric)
+		}
+	}
+
+	var usage []models.CopilotUsage
+	for _, scopeMetrics := range scopes {
+		scopeUsage, err := h.metricsClient.GetCopilotUsageFromMetrics(scopeMetrics)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert metrics to usage: %w", err)
+		}
+		usage = append(usage, scopeUsage...)
+	}
+
+	result.Usage = len(usage)
+	result.Markers = len(markers)
+
+	if dryRun {
+		return result, nil
+	}
+
+	// As during ingestion, documents that fail are retried and a partially
+	// saved batch does not stop the others
+	saved := 0
+	metricsResult, metricsErr := saveWithRetry(ctx, h.logger, h.saveRetries, h.saveRetryDelay, "metrics", metrics,
+		func(metric models.Metrics) string { return metric.GetID() }, h.repository.SaveMetrics)
+	saved += len(metricsResult.Succeeded)
+	result.Failed += len(metricsResult.Failed)
+	if metricsErr != nil && len(metricsResult.Succeeded) == 0 {
+		return nil, fmt.Errorf("failed to save metrics: %w", metricsErr)
+	}
+
+	usageResult, usageErr := saveWithRetry(ctx, h.logger, h.saveRetries, h.saveRetryDelay, "usage", usage,
+		func(entry models.CopilotUsage) string { return entry.GetID() }, h.repository.SaveUsage)
+	saved += len(usageResult.Succeeded)
+	result.Failed += len(usageResult.Failed)
+
+	if len(markers) > 0 {
+		if err := h.repository.SaveMetricsMarkers(ctx, markers); err != nil {
+			return nil, fmt.Errorf("failed to save metrics markers: %w", err)
+		}
+	}
+	result.Saved = true
+
+	if result.Failed > 0 {
+		return result, fmt.Errorf("failed to save %d of %d documents: %w", result.Failed, result.Failed+saved,
+			errors.Join(metricsErr, usageErr))
+	}
+	return result, nil
+}
+
+// fetchGroups collects the archived metrics responses of each ingestion run,
+// keyed by fetch day and scope, to rebuild the markers of that run
+type fetchGroups struct {
+	order  []string
+	groups map[string]*fetchGroup
+}
+
+// fetchGroup holds the latest organization/enterprise and team responses of a fetch
+type fetchGroup struct {
+	orgMetrics []models.Metrics
+	teams      map[string][]models.Metrics // nil metrics for teams not found
+	teamOrder  []string
+	fetchedAt  time.Time
+}
+
+// newFetchGroups creates an empty set of fetch groups
+func newFetchGroups() *fetchGroups {
+	return &fetchGroups{groups: make(map[string]*fetchGroup)}
+}
+
+// group returns the fetch group of a response
+func (f *fetchGroups) group(response *models.RawResponse) *fetchGroup {
+	key := response.FetchedAt.UTC().Format("2006-01-02") + "/" + response.Enterprise + "/" + response.Organization
+	group, exists := f.groups[key]
+	if !exists {
+		group = &fetchGroup{teams: make(map[string][]models.Metrics)}
+		f.groups[key] = group
+		f.order = append(f.order, key)
+	}
+	group.fetchedAt = response.FetchedAt
+	return group
+}
+
+// fetched records the metrics of a successful response
+func (f *fetchGroups) fetched(response *models.RawResponse, metrics []models.Metrics) {
+	group := f.group(response)
+	if response.Team == "" {
+		group.orgMetrics = metrics
+		return
+	}
+	if _, exists := group.teams[response.Team]; !exists {
+		group.teamOrder = append(group.teamOrder, response.Team)
+	}
+	if metrics == nil {
+		metrics = []models.Metrics{}
+	}
+	group.teams[response.Team] = metrics
+}
+
+// notFound records a team response the API answered with 404
+func (f *fetchGroups) notFound(response *models.RawResponse) {
+	group := f.group(response)
+	if _, exists := group.teams[response.Team]; !exists {
+		group.teamOrder = append(group.teamOrder, response.Team)
+	}
+	group.teams[response.Team] = nil
+}
+
+// markers rebuilds the markers of every fetch, later fetches replacing the
+// markers of earlier ones
+func (f *fetchGroups) markers() []models.MetricsMarker {
+	byID := make(map[string]models.MetricsMarker)
+	var ids []string
+	for _, key := range f.order {
+		group := f.groups[key]
+		for _, team := range group.teamOrder {
+			teamMetrics := group.teams[team]
+			status := models.MetricsSuppressed
+			if teamMetrics == nil {
+				status = models.MetricsTeamNotFound
+			}
+			for _, marker := range teamMarkers(group.orgMetrics, team, teamMetrics, status, group.fetchedAt) {
+				if _, exists := byID[marker.ID]; !exists {
+					ids = append(ids, marker.ID)
+				}
+				byID[marker.ID] = marker
+			}
+		}
+	}
+
+	markers := make([]models.MetricsMarker, 0, len(ids))
+	for _, id := range ids {
+		markers = append(markers, byID[id])
+	}
+	return markers
+}
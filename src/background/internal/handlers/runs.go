@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// Ingestion jobs, as recorded in the run ledger
+const (
+	jobMetrics = "metrics"
+	jobSeats   = "seats"
+	jobTeams   = "teams"
+)
+
+// startIngestionRun starts the record of a run of a job for the configured scope
+func startIngestionRun(job string) *models.IngestionRun {
+	now := time.Now().UTC()
+	run := &models.IngestionRun{
+		Job:       job,
+		Date:      now.Format("2006-01-02"),
+		StartedAt: now,
+	}
+
+	if strings.ToLower(os.Getenv("GITHUB_API_SCOPE")) == "enterprise" {
+		run.Enterprise = os.Getenv("GITHUB_ENTERPRISE")
+	} else {
+		run.Organization = os.Getenv("GITHUB_ORGANIZATION")
+	}
+	return run
+}
+
+// recordIngestionRun finishes the record of a run with its outcome, logs it
+// and stores it. Storage failures are logged, as the ledger must not fail the
+// ingestion.
+func recordIngestionRun(ctx context.Context, logger *zap.Logger, repository repositories.Repository, run *models.IngestionRun, runErr error) {
+	run.FinishedAt = time.Now().UTC()
+	run.DurationMs = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	if runErr != nil {
+		run.Error = runErr.Error()
+		if run.Status == "" {
+			run.Status = models.RunFailed
+		}
+	} else if run.Status == "" {
+		run.Status = models.RunSucceeded
+	}
+
+	logger.Info("Ingestion run finished",
+		zap.String("job", run.Job),
+		zap.String("status", string(run.Status)),
+		zap.Int64("duration_ms", run.DurationMs),
+		zap.Int64("api_calls", run.APICalls),
+		zap.Int("fetched", run.Fetched),
+		zap.Int("saved", run.Saved),
+		zap.Int("skipped", run.Skipped),
+		zap.Int("failed", run.Failed))
+
+	if repository == nil {
+		return
+	}
+	// Record runs cancelled midway too, e.g. after losing their job lock
+	if err := repository.SaveIngestionRun(context.WithoutCancel(ctx), run); err != nil {
+		logger.Error("Failed to save ingestion run", zap.String("job", run.Job), zap.Error(err))
+	}
+}
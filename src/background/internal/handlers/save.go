@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// saveWithRetry saves a batch of documents and retries the documents that
+// failed up to retries times, waiting delay before the first retry and twice
+// as long before each further one. The result lists every document saved by
+// any attempt and the documents that failed the last one.
+func saveWithRetry[T any](ctx context.Context, logger *zap.Logger, retries int, delay time.Duration, kind string,
+	documents []T, id func(document T) string,
+	save func(ctx context.Context, documents []T) (*repositories.BatchResult, error)) (*repositories.BatchResult, error) {
+	result, err := save(ctx, documents)
+	if result == nil {
+		// The batch failed as a whole
+		result = &repositories.BatchResult{}
+		for _, document := range documents {
+			result.Failed = append(result.Failed, repositories.ItemError{ID: id(document), Err: err})
+		}
+	}
+	succeeded := result.Succeeded
+
+retry:
+	for attempt := 1; err != nil && len(result.Failed) > 0 && attempt <= retries; attempt++ {
+		failed := result.FailedIDs()
+		pending := make([]T, 0, len(failed))
+		for _, document := range documents {
+			if failed[id(document)] {
+				pending = append(pending, document)
+			}
+		}
+
+		logger.Warn("Retrying failed saves",
+			zap.String("kind", kind),
+			zap.Int("count", len(pending)),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+		select {
+		case <-ctx.Done():
+			break retry
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		retried, retryErr := save(ctx, pending)
+		if retried == nil {
+			err = retryErr
+			continue
+		}
+		succeeded = append(succeeded, retried.Succeeded...)
+		result, err = retried, retryErr
+	}
+
+	result = &repositories.BatchResult{Succeeded: succeeded, Failed: result.Failed}
+	return result, result.Err()
+}
@@ -6,9 +6,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/services"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/validation"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +20,7 @@ type SeatsHandler struct {
 	seatsClient *services.CopilotSeatsClient
 	repository  repositories.Repository
 	useTestData bool
+	validator   *validation.Validator
 }
 
 // NewSeatsHandler creates a new seats handler
@@ -35,10 +38,22 @@ func NewSeatsHandler(
 	}
 }
 
-// Run runs the seats ingestion process
-func (h *SeatsHandler) Run(ctx context.Context) error {
+// SetValidator enables validation of fetched seats before they are saved
+func (h *SeatsHandler) SetValidator(validator *validation.Validator) {
+	h.validator = validator
+}
+
+// Run runs the seats ingestion process and records the run in the run ledger
+func (h *SeatsHandler) Run(ctx context.Context) (err error) {
 	h.logger.Info("Running GitHub Copilot seats ingestion")
 
+	run := startIngestionRun(jobSeats)
+	calls := h.seatsClient.APICalls()
+	defer func() {
+		run.APICalls = h.seatsClient.APICalls() - calls
+		recordIngestionRun(ctx, h.logger, h.repository, run, err)
+	}()
+
 	// Check if seats ingestion is enabled
 	enableSeatsIngestionStr := os.Getenv("ENABLE_SEATS_INGESTION")
 	if enableSeatsIngestionStr == "" {
@@ -53,6 +68,7 @@ func (h *SeatsHandler) Run(ctx context.Context) error {
 
 	if !enableSeatsIngestion {
 		h.logger.Info("Seats ingestion is disabled")
+		run.Status = models.RunSkipped
 		return nil
 	}
 
@@ -81,17 +97,74 @@ func (h *SeatsHandler) Run(ctx context.Context) error {
 		return err
 	}
 
+	run.Fetched = 1
+
 	// Set ID if not already set
 	if seats.ID == "" {
 		seats.ID = seats.GetID()
 	}
 
+	if h.validator != nil {
+		result := h.validator.ValidateSeats(seats)
+		recordValidation(ctx, h.logger, h.repository, result)
+		if result.IsQuarantined(seats.ID) && h.repository != nil {
+			// A quarantined snapshot is neither saved nor compared, so that
+			// it produces no seat events or activity
+			if err := quarantineDocument(ctx, h.repository, result, validation.KindSeats, seats.ID,
+				seats.Date, seats.Enterprise, seats.Organization, "", seats); err != nil {
+				h.logger.Error("Failed to quarantine seats", zap.Error(err))
+				run.Failed = 1
+				return err
+			}
+			run.Skipped = 1
+			run.Status = models.RunSkipped
+			return nil
+		}
+	}
+
 	// Save to repository if available
 	if h.repository != nil {
+		// Load the previous snapshot before saving, as a run on the same day
+		// replaces the stored snapshot
+		previous, err := h.repository.GetLatestSeats(ctx)
+		if err != nil {
+			h.logger.Warn("Failed to load previous seats snapshot, comparing against an empty history", zap.Error(err))
+			previous = nil
+		}
+
 		if err := h.repository.SaveSeats(ctx, seats); err != nil {
 			h.logger.Error("Failed to save seats", zap.Error(err))
+			run.Failed++
 			return err
 		}
+		run.Saved++
+
+		if previous != nil && (previous.Enterprise != seats.Enterprise || previous.Organization != seats.Organization) {
+			previous = nil
+		}
+
+		activity := analysis.ExtractSeatActivity(previous, seats)
+		if len(activity) > 0 {
+			if err := h.repository.SaveSeatActivity(ctx, activity); err != nil {
+				h.logger.Error("Failed to save seat activity", zap.Error(err))
+				run.Failed += len(activity)
+				return err
+			}
+			run.Saved += len(activity)
+		}
+
+		if previous != nil {
+			events := analysis.DiffSeats(previous, seats)
+			h.logger.Info("Seat assignment changes detected", zap.Int("count", len(events)))
+			if len(events) > 0 {
+				if err := h.repository.SaveSeatEvents(ctx, events); err != nil {
+					h.logger.Error("Failed to save seat events", zap.Error(err))
+					run.Failed += len(events)
+					return err
+				}
+				run.Saved += len(events)
+			}
+		}
 	} else {
 		h.logger.Info("Repository not available, skipping save operation")
 	}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/services"
+	"go.uber.org/zap"
+)
+
+// TeamsHandler handles the processing of team memberships
+type TeamsHandler struct {
+	logger      *zap.Logger
+	teamsClient *services.TeamsClient
+	repository  repositories.Repository
+	useTestData bool
+}
+
+// NewTeamsHandler creates a new teams handler
+func NewTeamsHandler(
+	logger *zap.Logger,
+	teamsClient *services.TeamsClient,
+	repository repositories.Repository,
+	useTestData bool,
+) *TeamsHandler {
+	return &TeamsHandler{
+		logger:      logger,
+		teamsClient: teamsClient,
+		repository:  repository,
+		useTestData: useTestData,
+	}
+}
+
+// Run runs the team memberships ingestion process and records the run in the
+// run ledger
+func (h *TeamsHandler) Run(ctx context.Context) (err error) {
+	h.logger.Info("Running GitHub team memberships ingestion")
+
+	run := startIngestionRun(jobTeams)
+	calls := h.teamsClient.APICalls()
+	defer func() {
+		run.APICalls = h.teamsClient.APICalls() - calls
+		recordIngestionRun(ctx, h.logger, h.repository, run, err)
+	}()
+
+	// Check if team memberships ingestion is enabled
+	enableTeamsIngestionStr := os.Getenv("ENABLE_TEAMS_INGESTION")
+	if enableTeamsIngestionStr == "" {
+		enableTeamsIngestionStr = "true"
+	}
+
+	enableTeamsIngestion, err := strconv.ParseBool(enableTeamsIngestionStr)
+	if err != nil {
+		h.logger.Warn("Failed to parse ENABLE_TEAMS_INGESTION, defaulting to true", zap.Error(err))
+		enableTeamsIngestion = true
+	}
+
+	if !enableTeamsIngestion {
+		h.logger.Info("Team memberships ingestion is disabled")
+		run.Status = models.RunSkipped
+		return nil
+	}
+
+	var memberships *models.TeamMemberships
+
+	if h.useTestData {
+		h.logger.Info("Using test data for team memberships ingestion")
+		isEnterprise := strings.ToLower(os.Getenv("GITHUB_API_SCOPE")) == "enterprise"
+		memberships, err = h.teamsClient.LoadTestTeamMemberships(isEnterprise)
+	} else {
+		scope := os.Getenv("GITHUB_API_SCOPE")
+		if strings.ToLower(scope) == "enterprise" {
+			enterprise := os.Getenv("GITHUB_ENTERPRISE")
+			h.logger.Info("Fetching GitHub team memberships for enterprise", zap.String("enterprise", enterprise))
+			memberships, err = h.teamsClient.GetEnterpriseTeamMemberships(enterprise)
+		} else {
+			organization := os.Getenv("GITHUB_ORGANIZATION")
+			h.logger.Info("Fetching GitHub team memberships for organization", zap.String("organization", organization))
+			memberships, err = h.teamsClient.GetOrganizationTeamMemberships(organization)
+		}
+	}
+
+	if err != nil {
+		h.logger.Error("Failed to get team memberships", zap.Error(err))
+		return err
+	}
+
+	run.Fetched = 1
+
+	if memberships.ID == "" {
+		memberships.ID = memberships.GetID()
+	}
+
+	// Save to repository if available
+	if h.repository != nil {
+		if err := h.repository.SaveTeamMemberships(ctx, memberships); err != nil {
+			h.logger.Error("Failed to save team memberships", zap.Error(err))
+			run.Failed = 1
+			return err
+		}
+		run.Saved = 1
+	} else {
+		h.logger.Info("Repository not available, skipping save operation")
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/validation"
+	"go.uber.org/zap"
+)
+
+// recordValidation logs and stores the violations of a validation run.
+// Storage failures are logged, as validation must not block ingestion.
+func recordValidation(ctx context.Context, logger *zap.Logger, repository repositories.Repository, result *validation.Result) {
+	if len(result.Violations) == 0 {
+		return
+	}
+
+	for _, violation := range result.Violations {
+		logger.Warn("Data-quality rule violated",
+			zap.String("run", violation.RunID),
+			zap.String("rule", violation.Rule),
+			zap.String("severity", violation.Severity),
+			zap.String("document", violation.DocumentID),
+			zap.String("message", violation.Message),
+			zap.Bool("quarantined", violation.Quarantined))
+	}
+
+	if repository == nil {
+		return
+	}
+	if err := repository.SaveViolations(ctx, result.Violations); err != nil {
+		logger.Error("Failed to save validation violations", zap.Error(err))
+	}
+}
+
+// quarantineDocument stores a document held back by validation in place of
+// saving it. It returns an error if the document could not be stored, so that
+// the caller does not lose it silently.
+func quarantineDocument(ctx context.Context, repository repositories.Repository, result *validation.Result,
+	kind, documentID, date, enterprise, organization, team string, document interface{}) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	return repository.SaveQuarantinedDocument(ctx, &models.QuarantinedDocument{
+		Date:          date,
+		RunID:         result.RunID,
+		DocumentKind:  kind,
+		DocumentID:    documentID,
+		Enterprise:    enterprise,
+		Organization:  organization,
+		Team:          team,
+		Rules:         result.Quarantined[documentID],
+		Document:      data,
+		QuarantinedAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,165 @@
+package locking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// cosmosLease is the lease document of a lock
+type cosmosLease struct {
+	ID        string    `json:"id"` // Lock name
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CosmosLocker keeps leases as documents of a Cosmos DB container. Updates
+// are conditional on the document's ETag, so two instances never both take an
+// expired lease over.
+type CosmosLocker struct {
+	client    *azcosmos.Client
+	database  string
+	container string
+	owner     string
+}
+
+// NewCosmosLocker creates a locker on a Cosmos DB container partitioned by /id
+func NewCosmosLocker(client *azcosmos.Client, database, container, owner string) *CosmosLocker {
+	return &CosmosLocker{client: client, database: database, container: container, owner: owner}
+}
+
+// Initialize creates the database and the lease container if they do not exist
+func (l *CosmosLocker) Initialize(ctx context.Context) error {
+	database, err := l.client.NewDatabase(l.database)
+	if err != nil {
+		return err
+	}
+
+	if _, err := database.Read(ctx, nil); hasStatus(err, http.StatusNotFound) {
+		_, err = l.client.CreateDatabase(ctx, azcosmos.DatabaseProperties{ID: l.database}, nil)
+		if err != nil && !hasStatus(err, http.StatusConflict) {
+			return fmt.Errorf("failed to create database %s: %w", l.database, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to read database %s: %w", l.database, err)
+	}
+
+	container, err := database.NewContainer(l.container)
+	if err != nil {
+		return err
+	}
+	if _, err := container.Read(ctx, nil); err == nil {
+		return nil
+	} else if !hasStatus(err, http.StatusNotFound) {
+		return fmt.Errorf("failed to read lock container: %w", err)
+	}
+
+	_, err = database.CreateContainer(ctx, azcosmos.ContainerProperties{
+		ID: l.container,
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+			Paths: []string{"/id"},
+		},
+	}, nil)
+	if err != nil && !hasStatus(err, http.StatusConflict) {
+		return fmt.Errorf("failed to create lock container: %w", err)
+	}
+	return nil
+}
+
+// Acquire creates the lease document, or replaces it if this instance holds
+// it or it expired, as long as nobody changed it since it was read
+func (l *CosmosLocker) Acquire(ctx context.Context, name string, ttl time.Duration) error {
+	container, err := l.client.NewContainer(l.database, l.container)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	data, err := json.Marshal(cosmosLease{ID: name, Owner: l.owner, ExpiresAt: now.Add(ttl)})
+	if err != nil {
+		return err
+	}
+	partitionKey := azcosmos.NewPartitionKeyString(name)
+
+	response, err := container.ReadItem(ctx, partitionKey, name, nil)
+	if hasStatus(err, http.StatusNotFound) {
+		_, err = container.CreateItem(ctx, partitionKey, data, nil)
+		if hasStatus(err, http.StatusConflict) {
+			return ErrLockHeld
+		}
+		if err != nil {
+			return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lock %s: %w", name, err)
+	}
+
+	var lease cosmosLease
+	if err := json.Unmarshal(response.Value, &lease); err != nil {
+		return fmt.Errorf("failed to decode lock %s: %w", name, err)
+	}
+	if lease.Owner != l.owner && lease.ExpiresAt.After(now) {
+		return ErrLockHeld
+	}
+
+	etag := response.ETag
+	_, err = container.ReplaceItem(ctx, partitionKey, name, data, &azcosmos.ItemOptions{IfMatchEtag: &etag})
+	if hasStatus(err, http.StatusPreconditionFailed) {
+		return ErrLockHeld
+	}
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// Release deletes the lease document if this instance holds it
+func (l *CosmosLocker) Release(ctx context.Context, name string) error {
+	container, err := l.client.NewContainer(l.database, l.container)
+	if err != nil {
+		return err
+	}
+	partitionKey := azcosmos.NewPartitionKeyString(name)
+
+	response, err := container.ReadItem(ctx, partitionKey, name, nil)
+	if hasStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lock %s: %w", name, err)
+	}
+
+	var lease cosmosLease
+	if err := json.Unmarshal(response.Value, &lease); err != nil {
+		return fmt.Errorf("failed to decode lock %s: %w", name, err)
+	}
+	if lease.Owner != l.owner {
+		return nil
+	}
+
+	etag := response.ETag
+	_, err = container.DeleteItem(ctx, partitionKey, name, &azcosmos.ItemOptions{IfMatchEtag: &etag})
+	if err != nil && !hasStatus(err, http.StatusNotFound) && !hasStatus(err, http.StatusPreconditionFailed) {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close releases nothing; the Cosmos DB client holds no connections to close
+func (l *CosmosLocker) Close() error {
+	return nil
+}
+
+// hasStatus reports whether an error is a Cosmos DB response with a status code
+func hasStatus(err error, statusCode int) bool {
+	var responseErr *azcore.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == statusCode
+}
@@ -0,0 +1,47 @@
+package locking
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/cosmosauth"
+	"go.uber.org/zap"
+)
+
+// CreateLocker creates the locker of the configured lock type, or returns
+// nil if jobs are not locked
+func CreateLocker(cfg *config.Config, owner string, logger *zap.Logger) (Locker, error) {
+	var locker Locker
+	var err error
+
+	switch cfg.LockType {
+	case config.LockNone:
+		return nil, nil
+	case config.LockSQLite:
+		logger.Info("Creating SQLite job locker", zap.String("path", cfg.LockSQLitePath))
+		locker, err = NewSQLiteLocker(cfg.LockSQLitePath, owner)
+	case config.LockPostgres:
+		logger.Info("Creating Postgres job locker", zap.String("driver", cfg.LockPostgresDriver))
+		locker, err = NewPostgresLocker(cfg.LockPostgresDriver, cfg.LockPostgresURL)
+	case config.LockCosmos:
+		logger.Info("Creating Cosmos DB job locker", zap.String("endpoint", cfg.CosmosDBEndpoint))
+		var client *azcosmos.Client
+		client, err = cosmosauth.NewClient(context.Background(), cosmosauth.ConfigFrom(cfg), logger)
+		if err == nil {
+			locker = NewCosmosLocker(client, cfg.CosmosDatabase, "job_locks", owner)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported lock type: %s", cfg.LockType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := locker.Initialize(context.Background()); err != nil {
+		locker.Close()
+		return nil, err
+	}
+	return locker, nil
+}
@@ -0,0 +1,168 @@
+package locking
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrLockHeld is returned when another instance holds an unexpired lease
+var ErrLockHeld = errors.New("lock is held by another instance")
+
+// Locker grants exclusive, expiring leases on named locks, so that only one
+// of several instances runs a scheduled job
+type Locker interface {
+	// Initialize creates the storage of the leases
+	Initialize(ctx context.Context) error
+	// Acquire takes the lease of a lock, or extends it if this instance
+	// already holds it, until ttl from now. It returns ErrLockHeld if another
+	// instance holds an unexpired lease.
+	Acquire(ctx context.Context, name string, ttl time.Duration) error
+	// Release gives up the lease if this instance holds it
+	Release(ctx context.Context, name string) error
+	// Close releases the resources of the locker
+	Close() error
+}
+
+// NewOwnerID returns an identifier of this instance, unique across restarts
+func NewOwnerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	random := make([]byte, 4)
+	_, _ = rand.Read(random)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(random))
+}
+
+// Runner runs jobs under their lock. It renews the lease while a job runs,
+// and keeps it for one more lease after the job finishes, so that other
+// instances triggered for the same run, e.g. after a random jitter, skip it.
+type Runner struct {
+	locker Locker
+	ttl    time.Duration
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	releases map[string]*time.Timer // Pending releases of finished jobs, by lock name
+}
+
+// NewRunner creates a runner holding leases for ttl
+func NewRunner(locker Locker, ttl time.Duration, logger *zap.Logger) *Runner {
+	return &Runner{
+		locker:   locker,
+		ttl:      ttl,
+		logger:   logger,
+		releases: make(map[string]*time.Timer),
+	}
+}
+
+// Run runs a job if no other instance holds its lock and reports whether it
+// ran. The job's context is cancelled if the lease is lost while it runs. A
+// nil runner runs every job.
+func (r *Runner) Run(ctx context.Context, name string, run func(ctx context.Context)) bool {
+	if r == nil {
+		run(ctx)
+		return true
+	}
+
+	r.mu.Lock()
+	if timer, exists := r.releases[name]; exists {
+		timer.Stop()
+		delete(r.releases, name)
+	}
+	r.mu.Unlock()
+
+	if err := r.locker.Acquire(ctx, name, r.ttl); err != nil {
+		if errors.Is(err, ErrLockHeld) {
+			r.logger.Info("Job is running on another instance, skipping", zap.String("job", name))
+		} else {
+			r.logger.Error("Failed to acquire job lock, skipping", zap.String("job", name), zap.Error(err))
+		}
+		return false
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go r.renew(jobCtx, cancel, name, done)
+
+	run(jobCtx)
+	close(done)
+	cancel()
+
+	// Hold the lease for one more period before handing it over
+	if err := r.locker.Acquire(ctx, name, r.ttl); err != nil {
+		r.logger.Warn("Failed to extend job lock after run", zap.String("job", name), zap.Error(err))
+		return true
+	}
+	r.mu.Lock()
+	r.releases[name] = time.AfterFunc(r.ttl, func() {
+		r.mu.Lock()
+		delete(r.releases, name)
+		r.mu.Unlock()
+		r.release(context.Background(), name)
+	})
+	r.mu.Unlock()
+
+	return true
+}
+
+// renew extends the lease of a running job every third of the lease, and
+// cancels the job if another instance took the lease over
+func (r *Runner) renew(ctx context.Context, cancel context.CancelFunc, name string, done <-chan struct{}) {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := r.locker.Acquire(ctx, name, r.ttl)
+			if errors.Is(err, ErrLockHeld) {
+				r.logger.Error("Job lock lost to another instance, cancelling the run", zap.String("job", name))
+				cancel()
+				return
+			}
+			if err != nil {
+				r.logger.Warn("Failed to renew job lock", zap.String("job", name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// release gives up a lease, logging failures
+func (r *Runner) release(ctx context.Context, name string) {
+	if err := r.locker.Release(ctx, name); err != nil {
+		r.logger.Warn("Failed to release job lock", zap.String("job", name), zap.Error(err))
+	}
+}
+
+// Close releases the leases still held, so that another instance can take
+// the jobs over right away, and closes the locker
+func (r *Runner) Close(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	names := make([]string, 0, len(r.releases))
+	for name, timer := range r.releases {
+		timer.Stop()
+		names = append(names, name)
+	}
+	r.releases = make(map[string]*time.Timer)
+	r.mu.Unlock()
+
+	for _, name := range names {
+		r.release(ctx, name)
+	}
+	return r.locker.Close()
+}
@@ -0,0 +1,121 @@
+package locking
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PostgresLocker holds session-level Postgres advisory locks. A lock lives as
+// long as the session that took it, so the lease of a crashed instance ends
+// when its connection drops rather than after a ttl.
+type PostgresLocker struct {
+	db *sql.DB
+
+	mu    sync.Mutex
+	conns map[string]*sql.Conn // Sessions holding a lock, by lock name
+}
+
+// NewPostgresLocker connects to Postgres through a database/sql driver
+// registered under driverName, e.g. "pgx", which the binary registers
+func NewPostgresLocker(driverName, dataSourceName string) (*PostgresLocker, error) {
+	if dataSourceName == "" {
+		return nil, fmt.Errorf("Postgres connection string is not specified")
+	}
+	if !slices.Contains(sql.Drivers(), driverName) {
+		return nil, fmt.Errorf("no database/sql driver registered as %q, set LOCK_POSTGRES_DRIVER to a registered driver", driverName)
+	}
+
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Postgres connection: %w", err)
+	}
+
+	return &PostgresLocker{db: db, conns: make(map[string]*sql.Conn)}, nil
+}
+
+// Initialize checks the connection; advisory locks need no schema
+func (l *PostgresLocker) Initialize(ctx context.Context) error {
+	if err := l.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+	return nil
+}
+
+// advisoryKey maps a lock name to the 64-bit key of an advisory lock
+func advisoryKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("copilot-metrics-dashboard/" + name))
+	return int64(hash.Sum64())
+}
+
+// Acquire takes the advisory lock on a dedicated session. A lock already held
+// by this instance stays held while its session is alive, so ttl is unused.
+func (l *PostgresLocker) Acquire(ctx context.Context, name string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if conn, exists := l.conns[name]; exists {
+		if err := conn.PingContext(ctx); err == nil {
+			return nil
+		}
+		// The session, and with it the lock, is gone
+		conn.Close()
+		delete(l.conns, name)
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open Postgres session: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryKey(name)).Scan(&acquired); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !acquired {
+		conn.Close()
+		return ErrLockHeld
+	}
+
+	l.conns[name] = conn
+	return nil
+}
+
+// Release unlocks the advisory lock and returns its session to the pool
+func (l *PostgresLocker) Release(ctx context.Context, name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	conn, exists := l.conns[name]
+	if !exists {
+		return nil
+	}
+	delete(l.conns, name)
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryKey(name)); err != nil {
+		// Closing the session is the other way to release the lock; a
+		// pooled session would keep holding it
+		_ = conn.Raw(func(driverConn any) error { return driver.ErrBadConn })
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close closes the sessions, releasing their locks, and the connection pool
+func (l *PostgresLocker) Close() error {
+	l.mu.Lock()
+	for name, conn := range l.conns {
+		conn.Close()
+		delete(l.conns, name)
+	}
+	l.mu.Unlock()
+	return l.db.Close()
+}
@@ -0,0 +1,100 @@
+package locking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS job_locks (
+    name TEXT PRIMARY KEY,
+    owner TEXT NOT NULL,
+    expires_at INTEGER NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// SQLiteLocker keeps leases as rows of a job_locks table, for instances
+// sharing a SQLite database file
+type SQLiteLocker struct {
+	db    *sql.DB
+	owner string
+}
+
+// NewSQLiteLocker opens the lock table of a SQLite database
+func NewSQLiteLocker(dbPath, owner string) (*SQLiteLocker, error) {
+	if dbPath == "" {
+		return nil, fmt.Errorf("SQLite database path is not specified")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Wait for writers of other instances instead of failing with SQLITE_BUSY
+	connString := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dbPath)
+	db, err := sql.Open("sqlite", connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return &SQLiteLocker{db: db, owner: owner}, nil
+}
+
+// Initialize creates the lock table
+func (l *SQLiteLocker) Initialize(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("failed to initialize lock table: %w", err)
+	}
+	return nil
+}
+
+// Acquire takes or extends the lease in a single upsert, which only replaces
+// a row held by this instance or expired
+func (l *SQLiteLocker) Acquire(ctx context.Context, name string, ttl time.Duration) error {
+	now := time.Now()
+	result, err := l.db.ExecContext(ctx, `
+		INSERT INTO job_locks (name, owner, expires_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(name) DO UPDATE SET
+			owner = excluded.owner,
+			expires_at = excluded.expires_at,
+			updated_at = excluded.updated_at
+		WHERE job_locks.owner = excluded.owner OR job_locks.expires_at <= ?
+	`, name, l.owner, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if affected == 0 {
+		return ErrLockHeld
+	}
+	return nil
+}
+
+// Release deletes the lease if this instance holds it
+func (l *SQLiteLocker) Release(ctx context.Context, name string) error {
+	if _, err := l.db.ExecContext(ctx, "DELETE FROM job_locks WHERE name = ? AND owner = ?", name, l.owner); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close closes the database connection
+func (l *SQLiteLocker) Close() error {
+	return l.db.Close()
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AlertStatus is the state of an alert
+type AlertStatus string
+
+const (
+	AlertFiring   AlertStatus = "firing"
+	AlertResolved AlertStatus = "resolved"
+)
+
+// Alert is an episode of an alerting rule firing for a subject, from the
+// evaluation it started firing until the evaluation it was resolved
+type Alert struct {
+	ID             string      `json:"id,omitempty"`
+	Date           string      `json:"date"` // Day the alert started firing
+	Rule           string      `json:"rule"`
+	Severity       string      `json:"severity"`
+	Status         AlertStatus `json:"status"`
+	Subject        string      `json:"subject"` // Scope, team or job the rule was evaluated for
+	Enterprise     string      `json:"enterprise,omitempty"`
+	Organization   string      `json:"organization,omitempty"`
+	Team           string      `json:"team,omitempty"`
+	Value          float64     `json:"value"`
+	Threshold      float64     `json:"threshold"`
+	Message        string      `json:"message"`
+	FiredAt        time.Time   `json:"fired_at"`
+	ResolvedAt     *time.Time  `json:"resolved_at,omitempty"`
+	LastNotifiedAt *time.Time  `json:"last_notified_at,omitempty"`
+	Notifications  int         `json:"notifications"`
+}
+
+// GetID generates an ID for the alert episode
+func (a *Alert) GetID() string {
+	return fmt.Sprintf("%s-%s-%d", a.Rule, a.Subject, a.FiredAt.Unix())
+}
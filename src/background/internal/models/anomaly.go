@@ -0,0 +1,37 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Anomaly is a day on which a stored daily metrics series deviates from its
+// baseline, e.g. a drop in engaged users after a broken IDE plugin rollout
+type Anomaly struct {
+	ID           string    `json:"id,omitempty"`
+	Date         string    `json:"date"`
+	Enterprise   string    `json:"enterprise,omitempty"`
+	Organization string    `json:"organization,omitempty"`
+	Team         string    `json:"team,omitempty"`
+	Editor       string    `json:"editor,omitempty"`
+	Metric       string    `json:"metric"`
+	Value        float64   `json:"value"`
+	Baseline     float64   `json:"baseline"`  // Median of the baseline days
+	Score        float64   `json:"score"`     // Robust z-score of the value against the baseline
+	Direction    string    `json:"direction"` // drop or spike
+	Severity     string    `json:"severity"`  // warning or critical
+	Samples      int       `json:"samples"`   // Number of baseline days
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
+// GetID generates an ID for the anomaly. Detecting the same series and day
+// again replaces the anomaly.
+func (a *Anomaly) GetID() string {
+	scope := "XXX"
+	if a.Organization != "" {
+		scope = "ORG-" + a.Organization
+	} else if a.Enterprise != "" {
+		scope = "ENT-" + a.Enterprise
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s", a.Date, scope, a.Team, a.Editor, a.Metric)
+}
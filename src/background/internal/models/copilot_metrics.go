@@ -19,6 +19,7 @@ type Metrics struct {
 	Organization              string              `json:"organization,omitempty"`
 	Team                      string              `json:"team,omitempty"`
 	LastUpdate                time.Time           `json:"last_update"`
+	Extensions                Extensions          `json:"-"` // Fields not mapped by the model
 }
 
 // GetID generates an ID for the metrics data
@@ -44,12 +45,14 @@ type IdeCodeCompletions struct {
 	TotalEngagedUsers int                         `json:"total_engaged_users"`
 	Languages         []IdeCodeCompletionLanguage `json:"languages"`
 	Editors           []IdeCodeCompletionEditor   `json:"editors"`
+	Extensions        Extensions                  `json:"-"` // Fields not mapped by the model
 }
 
 // IdeCodeCompletionLanguage represents language-specific IDE code completion metrics
 type IdeCodeCompletionLanguage struct {
-	Name              string `json:"name"`
-	TotalEngagedUsers int    `json:"total_engaged_users"`
+	Name              string     `json:"name"`
+	TotalEngagedUsers int        `json:"total_engaged_users"`
+	Extensions        Extensions `json:"-"` // Fields not mapped by the model
 }
 
 // IdeCodeCompletionEditor represents editor-specific IDE code completion metrics
@@ -57,6 +60,7 @@ type IdeCodeCompletionEditor struct {
 	Name              string                   `json:"name"`
 	TotalEngagedUsers int                      `json:"total_engaged_users"`
 	Models            []IdeCodeCompletionModel `json:"models"`
+	Extensions        Extensions               `json:"-"` // Fields not mapped by the model
 }
 
 // IdeCodeCompletionModel represents model-specific IDE code completion metrics
@@ -66,22 +70,25 @@ type IdeCodeCompletionModel struct {
 	CustomModelTrainingDate *string                          `json:"custom_model_training_date,omitempty"`
 	TotalEngagedUsers       int                              `json:"total_engaged_users"`
 	Languages               []IdeCodeCompletionModelLanguage `json:"languages"`
+	Extensions              Extensions                       `json:"-"` // Fields not mapped by the model
 }
 
 // IdeCodeCompletionModelLanguage represents language-specific model metrics for IDE code completions
 type IdeCodeCompletionModelLanguage struct {
-	Name                    string `json:"name"`
-	TotalEngagedUsers       int    `json:"total_engaged_users"`
-	TotalCodeSuggestions    int    `json:"total_code_suggestions"`
-	TotalCodeAcceptances    int    `json:"total_code_acceptances"`
-	TotalCodeLinesSuggested int    `json:"total_code_lines_suggested"`
-	TotalCodeLinesAccepted  int    `json:"total_code_lines_accepted"`
+	Name                    string     `json:"name"`
+	TotalEngagedUsers       int        `json:"total_engaged_users"`
+	TotalCodeSuggestions    int        `json:"total_code_suggestions"`
+	TotalCodeAcceptances    int        `json:"total_code_acceptances"`
+	TotalCodeLinesSuggested int        `json:"total_code_lines_suggested"`
+	TotalCodeLinesAccepted  int        `json:"total_code_lines_accepted"`
+	Extensions              Extensions `json:"-"` // Fields not mapped by the model
 }
 
 // IdeChat represents IDE chat metrics
 type IdeChat struct {
 	TotalEngagedUsers int             `json:"total_engaged_users"`
 	Editors           []IdeChatEditor `json:"editors"`
+	Extensions        Extensions      `json:"-"` // Fields not mapped by the model
 }
 
 // IdeChatEditor represents editor-specific IDE chat metrics
@@ -89,38 +96,43 @@ type IdeChatEditor struct {
 	Name              string         `json:"name"`
 	TotalEngagedUsers int            `json:"total_engaged_users"`
 	Models            []IdeChatModel `json:"models"`
+	Extensions        Extensions     `json:"-"` // Fields not mapped by the model
 }
 
 // IdeChatModel represents model-specific IDE chat metrics
 type IdeChatModel struct {
-	Name                     string  `json:"name"`
-	IsCustomModel            bool    `json:"is_custom_model"`
-	CustomModelTrainingDate  *string `json:"custom_model_training_date,omitempty"`
-	TotalEngagedUsers        int     `json:"total_engaged_users"`
-	TotalChats               int     `json:"total_chats"`
-	TotalChatInsertionEvents int     `json:"total_chat_insertion_events"`
-	TotalChatCopyEvents      int     `json:"total_chat_copy_events"`
+	Name                     string     `json:"name"`
+	IsCustomModel            bool       `json:"is_custom_model"`
+	CustomModelTrainingDate  *string    `json:"custom_model_training_date,omitempty"`
+	TotalEngagedUsers        int        `json:"total_engaged_users"`
+	TotalChats               int        `json:"total_chats"`
+	TotalChatInsertionEvents int        `json:"total_chat_insertion_events"`
+	TotalChatCopyEvents      int        `json:"total_chat_copy_events"`
+	Extensions               Extensions `json:"-"` // Fields not mapped by the model
 }
 
 // DotComChat represents GitHub.com chat metrics
 type DotComChat struct {
 	TotalEngagedUsers int               `json:"total_engaged_users"`
 	Models            []DotComChatModel `json:"models"`
+	Extensions        Extensions        `json:"-"` // Fields not mapped by the model
 }
 
 // DotComChatModel represents model-specific GitHub.com chat metrics
 type DotComChatModel struct {
-	Name                    string  `json:"name"`
-	IsCustomModel           bool    `json:"is_custom_model"`
-	CustomModelTrainingDate *string `json:"custom_model_training_date,omitempty"`
-	TotalEngagedUsers       int     `json:"total_engaged_users"`
-	TotalChats              int     `json:"total_chats"`
+	Name                    string     `json:"name"`
+	IsCustomModel           bool       `json:"is_custom_model"`
+	CustomModelTrainingDate *string    `json:"custom_model_training_date,omitempty"`
+	TotalEngagedUsers       int        `json:"total_engaged_users"`
+	TotalChats              int        `json:"total_chats"`
+	Extensions              Extensions `json:"-"` // Fields not mapped by the model
 }
 
 // DotComPullRequest represents GitHub.com pull request metrics
 type DotComPullRequest struct {
 	TotalEngagedUsers int                           `json:"total_engaged_users"`
 	Repositories      []DotComPullRequestRepository `json:"repositories"`
+	Extensions        Extensions                    `json:"-"` // Fields not mapped by the model
 }
 
 // DotComPullRequestRepository represents repository-specific GitHub.com pull request metrics
@@ -128,15 +140,17 @@ type DotComPullRequestRepository struct {
 	Name              string                             `json:"name"`
 	TotalEngagedUsers int                                `json:"total_engaged_users"`
 	Models            []DotComPullRequestRepositoryModel `json:"models"`
+	Extensions        Extensions                         `json:"-"` // Fields not mapped by the model
 }
 
 // DotComPullRequestRepositoryModel represents model-specific GitHub.com pull request metrics
 type DotComPullRequestRepositoryModel struct {
-	Name                    string  `json:"name"`
-	IsCustomModel           bool    `json:"is_custom_model"`
-	CustomModelTrainingDate *string `json:"custom_model_training_date,omitempty"`
-	TotalEngagedUsers       int     `json:"total_engaged_users"`
-	TotalPrSummariesCreated int     `json:"total_pr_summaries_created"`
+	Name                    string     `json:"name"`
+	IsCustomModel           bool       `json:"is_custom_model"`
+	CustomModelTrainingDate *string    `json:"custom_model_training_date,omitempty"`
+	TotalEngagedUsers       int        `json:"total_engaged_users"`
+	TotalPrSummariesCreated int        `json:"total_pr_summaries_created"`
+	Extensions              Extensions `json:"-"` // Fields not mapped by the model
 }
 
 // CopilotUsage represents GitHub Copilot usage statistics
@@ -0,0 +1,200 @@
+package models
+
+// JSON methods of the metrics models keep the fields the models do not map in
+// their Extensions, see unmarshalWithExtensions.
+
+// UnmarshalJSON unmarshals Metrics and keeps unknown fields in its extensions
+func (m *Metrics) UnmarshalJSON(data []byte) error {
+	type plain Metrics
+	extensions, err := unmarshalWithExtensions(data, (*plain)(m))
+	m.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals Metrics together with its extensions
+func (m Metrics) MarshalJSON() ([]byte, error) {
+	type plain Metrics
+	return marshalWithExtensions(plain(m), m.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeCodeCompletions and keeps unknown fields in its extensions
+func (i *IdeCodeCompletions) UnmarshalJSON(data []byte) error {
+	type plain IdeCodeCompletions
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeCodeCompletions together with its extensions
+func (i IdeCodeCompletions) MarshalJSON() ([]byte, error) {
+	type plain IdeCodeCompletions
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeCodeCompletionLanguage and keeps unknown fields in its extensions
+func (i *IdeCodeCompletionLanguage) UnmarshalJSON(data []byte) error {
+	type plain IdeCodeCompletionLanguage
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeCodeCompletionLanguage together with its extensions
+func (i IdeCodeCompletionLanguage) MarshalJSON() ([]byte, error) {
+	type plain IdeCodeCompletionLanguage
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeCodeCompletionEditor and keeps unknown fields in its extensions
+func (i *IdeCodeCompletionEditor) UnmarshalJSON(data []byte) error {
+	type plain IdeCodeCompletionEditor
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeCodeCompletionEditor together with its extensions
+func (i IdeCodeCompletionEditor) MarshalJSON() ([]byte, error) {
+	type plain IdeCodeCompletionEditor
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeCodeCompletionModel and keeps unknown fields in its extensions
+func (i *IdeCodeCompletionModel) UnmarshalJSON(data []byte) error {
+	type plain IdeCodeCompletionModel
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeCodeCompletionModel together with its extensions
+func (i IdeCodeCompletionModel) MarshalJSON() ([]byte, error) {
+	type plain IdeCodeCompletionModel
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeCodeCompletionModelLanguage and keeps unknown fields in its extensions
+func (i *IdeCodeCompletionModelLanguage) UnmarshalJSON(data []byte) error {
+	type plain IdeCodeCompletionModelLanguage
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeCodeCompletionModelLanguage together with its extensions
+func (i IdeCodeCompletionModelLanguage) MarshalJSON() ([]byte, error) {
+	type plain IdeCodeCompletionModelLanguage
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeChat and keeps unknown fields in its extensions
+func (i *IdeChat) UnmarshalJSON(data []byte) error {
+	type plain IdeChat
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeChat together with its extensions
+func (i IdeChat) MarshalJSON() ([]byte, error) {
+	type plain IdeChat
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeChatEditor and keeps unknown fields in its extensions
+func (i *IdeChatEditor) UnmarshalJSON(data []byte) error {
+	type plain IdeChatEditor
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeChatEditor together with its extensions
+func (i IdeChatEditor) MarshalJSON() ([]byte, error) {
+	type plain IdeChatEditor
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals IdeChatModel and keeps unknown fields in its extensions
+func (i *IdeChatModel) UnmarshalJSON(data []byte) error {
+	type plain IdeChatModel
+	extensions, err := unmarshalWithExtensions(data, (*plain)(i))
+	i.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals IdeChatModel together with its extensions
+func (i IdeChatModel) MarshalJSON() ([]byte, error) {
+	type plain IdeChatModel
+	return marshalWithExtensions(plain(i), i.Extensions)
+}
+
+// UnmarshalJSON unmarshals DotComChat and keeps unknown fields in its extensions
+func (d *DotComChat) UnmarshalJSON(data []byte) error {
+	type plain DotComChat
+	extensions, err := unmarshalWithExtensions(data, (*plain)(d))
+	d.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals DotComChat together with its extensions
+func (d DotComChat) MarshalJSON() ([]byte, error) {
+	type plain DotComChat
+	return marshalWithExtensions(plain(d), d.Extensions)
+}
+
+// UnmarshalJSON unmarshals DotComChatModel and keeps unknown fields in its extensions
+func (d *DotComChatModel) UnmarshalJSON(data []byte) error {
+	type plain DotComChatModel
+	extensions, err := unmarshalWithExtensions(data, (*plain)(d))
+	d.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals DotComChatModel together with its extensions
+func (d DotComChatModel) MarshalJSON() ([]byte, error) {
+	type plain DotComChatModel
+	return marshalWithExtensions(plain(d), d.Extensions)
+}
+
+// UnmarshalJSON unmarshals DotComPullRequest and keeps unknown fields in its extensions
+func (d *DotComPullRequest) UnmarshalJSON(data []byte) error {
+	type plain DotComPullRequest
+	extensions, err := unmarshalWithExtensions(data, (*plain)(d))
+	d.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals DotComPullRequest together with its extensions
+func (d DotComPullRequest) MarshalJSON() ([]byte, error) {
+	type plain DotComPullRequest
+	return marshalWithExtensions(plain(d), d.Extensions)
+}
+
+// UnmarshalJSON unmarshals DotComPullRequestRepository and keeps unknown fields in its extensions
+func (d *DotComPullRequestRepository) UnmarshalJSON(data []byte) error {
+	type plain DotComPullRequestRepository
+	extensions, err := unmarshalWithExtensions(data, (*plain)(d))
+	d.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals DotComPullRequestRepository together with its extensions
+func (d DotComPullRequestRepository) MarshalJSON() ([]byte, error) {
+	type plain DotComPullRequestRepository
+	return marshalWithExtensions(plain(d), d.Extensions)
+}
+
+// UnmarshalJSON unmarshals DotComPullRequestRepositoryModel and keeps unknown fields in its extensions
+func (d *DotComPullRequestRepositoryModel) UnmarshalJSON(data []byte) error {
+	type plain DotComPullRequestRepositoryModel
+	extensions, err := unmarshalWithExtensions(data, (*plain)(d))
+	d.Extensions = extensions
+	return err
+}
+
+// MarshalJSON marshals DotComPullRequestRepositoryModel together with its extensions
+func (d DotComPullRequestRepositoryModel) MarshalJSON() ([]byte, error) {
+	type plain DotComPullRequestRepositoryModel
+	return marshalWithExtensions(plain(d), d.Extensions)
+}
@@ -38,3 +38,53 @@ type Seat struct {
 	AssigningTeam           *Team         `json:"assigning_team,omitempty"`
 	Organization            *Organization `json:"organization,omitempty"`
 }
+
+// SeatEventType defines the kind of change observed between two seats snapshots
+type SeatEventType string
+
+const (
+	SeatEventAdded                  SeatEventType = "seat_added"
+	SeatEventRemoved                SeatEventType = "seat_removed"
+	SeatEventPlanChanged            SeatEventType = "plan_changed"
+	SeatEventAssigningTeamChanged   SeatEventType = "assigning_team_changed"
+	SeatEventPendingCancellationSet SeatEventType = "pending_cancellation_set"
+)
+
+// SeatEvent represents a change in seat assignment between two consecutive seats snapshots
+type SeatEvent struct {
+	ID                 string        `json:"id,omitempty"`
+	Date               string        `json:"date"`
+	Type               SeatEventType `json:"type"`
+	Login              string        `json:"login"`
+	Enterprise         string        `json:"enterprise,omitempty"`
+	Organization       string        `json:"organization,omitempty"`
+	PreviousValue      string        `json:"previous_value,omitempty"`
+	NewValue           string        `json:"new_value,omitempty"`
+	PreviousSnapshotID string        `json:"previous_snapshot_id,omitempty"`
+	SnapshotID         string        `json:"snapshot_id"`
+	ObservedAt         time.Time     `json:"observed_at"`
+}
+
+// GetID generates an ID for the seat event
+func (e *SeatEvent) GetID() string {
+	return fmt.Sprintf("%s-%s-%s-%s-%d", e.Date, e.Organization, e.Login, e.Type, e.ObservedAt.Unix())
+}
+
+// SeatActivity represents an observed change of a seat's last activity
+type SeatActivity struct {
+	ID                 string    `json:"id,omitempty"`
+	Date               string    `json:"date"`
+	Login              string    `json:"login"`
+	Enterprise         string    `json:"enterprise,omitempty"`
+	Organization       string    `json:"organization,omitempty"`
+	Team               string    `json:"team,omitempty"`
+	LastActivityAt     time.Time `json:"last_activity_at"`
+	LastActivityEditor string    `json:"last_activity_editor,omitempty"`
+	ObservedAt         time.Time `json:"observed_at"`
+}
+
+// GetID generates an ID for the seat activity, so that repeated observations
+// of the same activity are stored once
+func (a *SeatActivity) GetID() string {
+	return fmt.Sprintf("%s-%s-%d", a.Organization, a.Login, a.LastActivityAt.Unix())
+}
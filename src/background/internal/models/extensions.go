@@ -0,0 +1,171 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Extensions holds JSON fields of an API object that the models do not map,
+// keyed by field name. They are written back next to the known fields so that
+// they survive a round trip through storage.
+type Extensions map[string]json.RawMessage
+
+// knownFieldsCache caches the JSON field names of the model types
+var knownFieldsCache sync.Map
+
+// knownFields returns the JSON field names mapped by a struct type
+func knownFields(t reflect.Type) map[string]bool {
+	if cached, ok := knownFieldsCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = true
+	}
+
+	knownFieldsCache.Store(t, fields)
+	return fields
+}
+
+// unmarshalWithExtensions unmarshals data into v, a pointer to a struct
+// without custom JSON methods, and returns the fields v does not map
+func unmarshalWithExtensions(data []byte, v interface{}) (Extensions, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// Not an object (e.g. null); nothing to keep
+		return nil, nil
+	}
+
+	known := knownFields(reflect.TypeOf(v).Elem())
+	var extensions Extensions
+	for name, value := range fields {
+		if known[name] {
+			continue
+		}
+		if extensions == nil {
+			extensions = make(Extensions)
+		}
+		extensions[name] = value
+	}
+
+	return extensions, nil
+}
+
+// marshalWithExtensions marshals v, a struct without custom JSON methods,
+// and appends the extension fields that do not collide with known fields
+func marshalWithExtensions(v interface{}, extensions Extensions) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extensions) == 0 {
+		return data, err
+	}
+
+	known := knownFields(reflect.TypeOf(v))
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return data, nil
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	buffer.Write(data[:len(data)-1])
+	for _, name := range names {
+		if buffer.Len() > 1 {
+			buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(extensions[name])
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+// ExtensionPaths returns the sorted, distinct paths of every extension field
+// found in a model, e.g. copilot_ide_chat.editors[].models[].new_field
+func ExtensionPaths(v interface{}) []string {
+	seen := make(map[string]bool)
+	collectExtensionPaths(reflect.ValueOf(v), "", seen)
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// extensionsType is the type of the Extensions fields
+var extensionsType = reflect.TypeOf(Extensions(nil))
+
+// collectExtensionPaths walks a value and records the paths of its extension fields
+func collectExtensionPaths(v reflect.Value, prefix string, seen map[string]bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectExtensionPaths(v.Elem(), prefix, seen)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectExtensionPaths(v.Index(i), prefix+"[]", seen)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if field.Type == extensionsType {
+				for name := range v.Field(i).Interface().(Extensions) {
+					seen[joinPath(prefix, name)] = true
+				}
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			collectExtensionPaths(v.Field(i), joinPath(prefix, name), seen)
+		}
+	}
+}
+
+// joinPath appends a field name to a path
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
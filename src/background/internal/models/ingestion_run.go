@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// RunStatus is the outcome of an ingestion run
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	// RunPartial marks a failed run that saved some of its documents
+	RunPartial RunStatus = "partial"
+	// RunSkipped marks a run that saved nothing on purpose, e.g. because the
+	// job is disabled or its data was quarantined
+	RunSkipped RunStatus = "skipped"
+)
+
+// IngestionRun records what a run of an ingestion job did
+type IngestionRun struct {
+	ID           string    `json:"id,omitempty"`
+	Date         string    `json:"date"` // Day the run started
+	Job          string    `json:"job"`  // metrics, seats or teams
+	Enterprise   string    `json:"enterprise,omitempty"`
+	Organization string    `json:"organization,omitempty"`
+	Status       RunStatus `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	DurationMs   int64     `json:"duration_ms"`
+	APICalls     int64     `json:"api_calls"` // GitHub API requests sent
+	Fetched      int       `json:"fetched"`   // Documents fetched or loaded
+	Saved        int       `json:"saved"`     // Documents saved to the repository
+	Skipped      int       `json:"skipped"`   // Documents held back, e.g. quarantined
+	Failed       int       `json:"failed"`    // Documents that could not be saved
+}
+
+// GetID generates an ID for the run
+func (r *IngestionRun) GetID() string {
+	return fmt.Sprintf("%s-%s-%d", r.Date, r.Job, r.StartedAt.UnixNano())
+}
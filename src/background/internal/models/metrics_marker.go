@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// MetricsStatus explains why a team has no metrics for a day
+type MetricsStatus string
+
+const (
+	// MetricsSuppressed marks a day the API omitted for a team, as GitHub
+	// only reports team metrics for days with five or more engaged users
+	MetricsSuppressed MetricsStatus = "suppressed"
+	// MetricsTeamNotFound marks a day fetched for a team the API does not know
+	MetricsTeamNotFound MetricsStatus = "not_found"
+)
+
+// MetricsMarker records that the metrics of a team are missing for a day and
+// why. A marker shares the ID of the metrics it stands in for.
+type MetricsMarker struct {
+	ID           string        `json:"id,omitempty"`
+	Date         string        `json:"date"`
+	Enterprise   string        `json:"enterprise,omitempty"`
+	Organization string        `json:"organization,omitempty"`
+	Team         string        `json:"team"`
+	Status       MetricsStatus `json:"status"`
+	ObservedAt   time.Time     `json:"observed_at"`
+}
+
+// GetID generates an ID for the marker, equal to the ID of the missing metrics
+func (m *MetricsMarker) GetID() string {
+	metrics := Metrics{Date: m.Date, Enterprise: m.Enterprise, Organization: m.Organization, Team: m.Team}
+	return metrics.GetID()
+}
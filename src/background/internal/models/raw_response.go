@@ -0,0 +1,42 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// RawResponseKind identifies the endpoint family a raw response was fetched from
+type RawResponseKind string
+
+const (
+	RawResponseMetrics     RawResponseKind = "metrics"
+	RawResponseSeats       RawResponseKind = "seats"
+	RawResponseTeams       RawResponseKind = "teams"
+	RawResponseTeamMembers RawResponseKind = "team_members"
+)
+
+// RawResponse is an archived GitHub API response body, kept so that data can
+// be audited and rebuilt with newer transform code
+type RawResponse struct {
+	ID           string          `json:"id,omitempty"`
+	Date         string          `json:"date"`
+	Kind         RawResponseKind `json:"kind"`
+	Method       string          `json:"method"`
+	URL          string          `json:"url"`
+	StatusCode   int             `json:"status_code"`
+	APIVersion   string          `json:"api_version"`
+	FetchedAt    time.Time       `json:"fetched_at"`
+	Enterprise   string          `json:"enterprise,omitempty"`
+	Organization string          `json:"organization,omitempty"`
+	Team         string          `json:"team,omitempty"`
+	Body         string          `json:"body"`
+}
+
+// GetID generates an ID for the raw response. The URL hash keeps the pages of
+// a paginated fetch apart.
+func (r *RawResponse) GetID() string {
+	hash := sha1.Sum([]byte(r.URL))
+	return fmt.Sprintf("%s-%s-%d-%s", r.Date, r.Kind, r.FetchedAt.UnixNano(), hex.EncodeToString(hash[:4]))
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// TeamMemberships represents a snapshot of the members of every team within an organization or enterprise
+type TeamMemberships struct {
+	ID           string        `json:"id,omitempty"`
+	Date         string        `json:"date"`
+	Enterprise   string        `json:"enterprise,omitempty"`
+	Organization string        `json:"organization,omitempty"`
+	Teams        []TeamMembers `json:"teams"`
+	LastUpdate   time.Time     `json:"last_update"`
+}
+
+// GetID generates an ID for the team memberships data
+func (t *TeamMemberships) GetID() string {
+	if t.Organization != "" {
+		return fmt.Sprintf("%s-ORG-%s", t.Date, t.Organization)
+	} else if t.Enterprise != "" {
+		return fmt.Sprintf("%s-ENT-%s", t.Date, t.Enterprise)
+	}
+	return fmt.Sprintf("%s-XXX", t.Date)
+}
+
+// TeamMembers represents the members of a single team
+type TeamMembers struct {
+	ID      int      `json:"id"`
+	Slug    string   `json:"slug"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
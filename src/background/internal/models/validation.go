@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Violation is a data-quality rule violation found in an ingested document
+type Violation struct {
+	ID           string    `json:"id,omitempty"`
+	Date         string    `json:"date"`
+	RunID        string    `json:"run_id"`
+	Rule         string    `json:"rule"`
+	Severity     string    `json:"severity"`
+	DocumentKind string    `json:"document_kind"`
+	DocumentID   string    `json:"document_id"`
+	Enterprise   string    `json:"enterprise,omitempty"`
+	Organization string    `json:"organization,omitempty"`
+	Team         string    `json:"team,omitempty"`
+	Message      string    `json:"message"`
+	Quarantined  bool      `json:"quarantined"`
+	ObservedAt   time.Time `json:"observed_at"`
+}
+
+// GetID generates an ID for the violation. The index keeps apart several
+// violations of the same rule in one document.
+func (v *Violation) GetID(index int) string {
+	return fmt.Sprintf("%s-%s-%s-%d", v.RunID, v.DocumentID, v.Rule, index)
+}
+
+// QuarantinedDocument is an ingested document held back from storage
+// because it failed validation
+type QuarantinedDocument struct {
+	ID            string          `json:"id,omitempty"`
+	Date          string          `json:"date"`
+	RunID         string          `json:"run_id"`
+	DocumentKind  string          `json:"document_kind"`
+	DocumentID    string          `json:"document_id"`
+	Enterprise    string          `json:"enterprise,omitempty"`
+	Organization  string          `json:"organization,omitempty"`
+	Team          string          `json:"team,omitempty"`
+	Rules         []string        `json:"rules"`
+	Document      json.RawMessage `json:"document"`
+	QuarantinedAt time.Time       `json:"quarantined_at"`
+}
+
+// GetID generates an ID for the quarantined document
+func (q *QuarantinedDocument) GetID() string {
+	return fmt.Sprintf("%s-%s", q.RunID, q.DocumentID)
+}
@@ -0,0 +1,149 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.uber.org/zap"
+)
+
+// S3Config holds the connection settings of an S3-compatible bucket
+type S3Config struct {
+	Endpoint  string // Host and port, optionally with an http:// or https:// scheme
+	Region    string
+	Bucket    string
+	Prefix    string // Key prefix under which all objects are stored
+	AccessKey string // Falls back to AWS environment variables and IAM roles if empty
+	SecretKey string
+	UseSSL    bool // Ignored when the endpoint has a scheme
+}
+
+// S3Store implements Store on an S3-compatible bucket such as AWS S3 or MinIO
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	logger *zap.Logger
+}
+
+// NewS3Store creates a store for an S3-compatible bucket
+func NewS3Store(cfg S3Config, logger *zap.Logger) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is not specified")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+
+	secure := cfg.UseSSL
+	if strings.HasPrefix(endpoint, "https://") {
+		endpoint, secure = strings.TrimPrefix(endpoint, "https://"), true
+	} else if strings.HasPrefix(endpoint, "http://") {
+		endpoint, secure = strings.TrimPrefix(endpoint, "http://"), false
+	}
+
+	var creds *credentials.Credentials
+	if cfg.AccessKey != "" {
+		creds = credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, "")
+	} else {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.IAM{},
+		})
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: secure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	return &S3Store{
+		client: client,
+		bucket: cfg.Bucket,
+		prefix: strings.Trim(cfg.Prefix, "/"),
+		logger: logger,
+	}, nil
+}
+
+// objectKey prepends the store prefix to a key
+func (s *S3Store) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return path.Join(s.prefix, key)
+}
+
+// Put uploads an object to the bucket
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.objectKey(key), bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", s.objectKey(key), err)
+	}
+	return nil
+}
+
+// Get downloads an object from the bucket
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, s.objectKey(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", s.objectKey(key), err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to download %s: %w", s.objectKey(key), err)
+	}
+	return data, nil
+}
+
+// Delete removes an object from the bucket
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, s.objectKey(key), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", s.objectKey(key), err)
+	}
+	return nil
+}
+
+// List lists the objects and common prefixes directly below a prefix
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	listPrefix := s.objectKey(prefix)
+	if prefix == "" && s.prefix != "" {
+		listPrefix = s.prefix + "/"
+	} else if strings.HasSuffix(prefix, "/") && !strings.HasSuffix(listPrefix, "/") {
+		listPrefix += "/"
+	}
+
+	var keys []string
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: listPrefix}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", listPrefix, object.Err)
+		}
+		keys = append(keys, prefix+strings.TrimPrefix(object.Key, listPrefix))
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Location returns the S3 URL of the store
+func (s *S3Store) Location() string {
+	return "s3://" + path.Join(s.bucket, s.prefix)
+}
@@ -0,0 +1,245 @@
+// Package s3test provides an in-memory S3-compatible server for tests, a
+// stand-in for a local MinIO that implements the object operations used by
+// objectstore.S3Store: put, get, delete and delimited listing.
+package s3test
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+)
+
+// Server is an S3-compatible server keeping objects in memory. Requests are
+// not authenticated.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+// NewServer starts a server with the given, empty buckets. The caller closes
+// it when done.
+func NewServer(buckets ...string) *Server {
+	s := &Server{buckets: make(map[string]map[string][]byte)}
+	for _, bucket := range buckets {
+		s.buckets[bucket] = make(map[string][]byte)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Config returns the settings of a store on a bucket of the server
+func (s *Server) Config(bucket, prefix string) objectstore.S3Config {
+	return objectstore.S3Config{
+		Endpoint:  s.URL,
+		Region:    "us-east-1",
+		Bucket:    bucket,
+		Prefix:    prefix,
+		AccessKey: "test",
+		SecretKey: "testsecret",
+	}
+}
+
+// Keys returns the sorted keys of the objects of a bucket
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Object returns the content of an object and whether it exists
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, exists := s.buckets[bucket][key]
+	return data, exists
+}
+
+// handle serves the requests of a path-style S3 client
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects, exists := s.buckets[bucket]
+	if !exists {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", bucket)
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.list(w, r, bucket, objects)
+	case key != "" && r.Method == http.MethodPut:
+		data, err := readBody(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		objects[key] = data
+		w.Header().Set("ETag", etag(data))
+		w.WriteHeader(http.StatusOK)
+	case key != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		data, exists := objects[key]
+		if !exists {
+			writeError(w, http.StatusNotFound, "NoSuchKey", key)
+			return
+		}
+		w.Header().Set("ETag", etag(data))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case key != "" && r.Method == http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+// listResult is the response of a ListObjectsV2 request
+type listResult struct {
+	XMLName        xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name           string         `xml:"Name"`
+	Prefix         string         `xml:"Prefix"`
+	Delimiter      string         `xml:"Delimiter,omitempty"`
+	KeyCount       int            `xml:"KeyCount"`
+	MaxKeys        int            `xml:"MaxKeys"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	Contents       []listObject   `xml:"Contents"`
+	CommonPrefixes []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type listObject struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int    `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// list lists the objects below a prefix, grouping keys under the delimiter
+// into common prefixes. Every result fits in one page.
+func (s *Server) list(w http.ResponseWriter, r *http.Request, bucket string, objects map[string][]byte) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	result := listResult{Name: bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: 1000}
+
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	seen := make(map[string]bool)
+	modified := time.Now().UTC().Format(time.RFC3339)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if index := strings.Index(key[len(prefix):], delimiter); index >= 0 {
+				common := key[:len(prefix)+index+len(delimiter)]
+				if !seen[common] {
+					seen[common] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: common})
+				}
+				continue
+			}
+		}
+		result.Contents = append(result.Contents, listObject{
+			Key:          key,
+			LastModified: modified,
+			ETag:         etag(objects[key]),
+			Size:         len(objects[key]),
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(result)
+}
+
+// readBody reads the content of an upload, decoding the aws-chunked encoding
+// that clients use for streaming signatures over plain HTTP
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk header: %w", err)
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk size %q: %w", sizeHex, err)
+		}
+		if size == 0 {
+			return data, nil
+		}
+
+		chunk := make([]byte, size+2) // Chunk data and its trailing CRLF
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, fmt.Errorf("truncated chunk: %w", err)
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+// etag returns the quoted MD5 hash of an object, as S3 computes it for
+// single-part uploads
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// errorResponse is the body of an S3 error response
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+// writeError writes an S3 error response
+func writeError(w http.ResponseWriter, status int, code, resource string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(errorResponse{Code: code, Message: http.StatusText(status), Resource: resource})
+}
@@ -0,0 +1,141 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Store is a minimal object storage abstraction over local directories and
+// S3-compatible buckets. Keys use forward slashes as separators.
+type Store interface {
+	// Put writes an object, replacing any existing object with the same key
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+
+	// Get reads an object, returning ErrNotFound if it does not exist
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete removes an object; removing a missing object is not an error
+	Delete(ctx context.Context, key string) error
+
+	// List returns the direct children of a prefix ending with "/" (or of the
+	// root for an empty prefix), sorted: object keys, and sub-prefixes ending
+	// with "/"
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// Location describes where the store writes objects, for logging
+	Location() string
+}
+
+// ErrNotFound is returned by Get for missing objects
+var ErrNotFound = errors.New("object not found")
+
+// Open opens a store for a location, which is either an S3 URL
+// (s3://bucket/prefix) or a local directory path
+func Open(location string, cfg S3Config, logger *zap.Logger) (Store, error) {
+	if strings.HasPrefix(location, "s3://") {
+		parsed, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 location %s: %w", location, err)
+		}
+		cfg.Bucket = parsed.Host
+		cfg.Prefix = strings.Trim(parsed.Path, "/")
+		return NewS3Store(cfg, logger)
+	}
+
+	return NewLocalStore(location)
+}
+
+// LocalStore implements Store on a local directory
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates a store writing below the given directory
+func NewLocalStore(root string) (*LocalStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local store directory is not specified")
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return &LocalStore{root: root}, nil
+}
+
+// Put writes an object to a file below the root directory
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Get reads an object from a file below the root directory
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.root, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete removes a file below the root directory
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(filepath.Join(s.root, filepath.FromSlash(key)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// List returns the files and directories of a directory below the root
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, filepath.FromSlash(prefix)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			keys = append(keys, prefix+name+"/")
+		} else if !strings.HasSuffix(name, ".tmp") {
+			keys = append(keys, prefix+name)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Location returns the root directory of the store
+func (s *LocalStore) Location() string {
+	return s.root
+}
@@ -0,0 +1,221 @@
+package objectstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore/s3test"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.uber.org/zap"
+)
+
+// testStore checks the behavior every Store implementation shares
+func testStore(t *testing.T, store objectstore.Store) {
+	ctx := context.Background()
+
+	objects := map[string]string{
+		"org-acme/metrics/2024-06-01/a.json":    `{"id":"a"}`,
+		"org-acme/metrics/2024-06-01/b.json.gz": "compressed",
+		"org-acme/metrics/2024-06-02/c.json":    `{"id":"c"}`,
+		"org-acme/seats/2024-06-01/s.json":      `{"id":"s"}`,
+		"ent-big/usage/2024-06-01/u.json":       `{"id":"u"}`,
+	}
+	for key, data := range objects {
+		if err := store.Put(ctx, key, []byte(data), "application/json"); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+
+	for key, want := range objects {
+		got, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", key, err)
+		}
+		if string(got) != want {
+			t.Errorf("Get(%s) = %q, want %q", key, got, want)
+		}
+	}
+
+	if _, err := store.Get(ctx, "org-acme/metrics/2024-06-01/missing.json"); !errors.Is(err, objectstore.ErrNotFound) {
+		t.Errorf("Get of a missing object: got error %v, want ErrNotFound", err)
+	}
+
+	// Listing returns the direct children of a prefix, sub-prefixes ending with "/"
+	listings := map[string][]string{
+		"":                  {"ent-big/", "org-acme/"},
+		"org-acme/":         {"org-acme/metrics/", "org-acme/seats/"},
+		"org-acme/metrics/": {"org-acme/metrics/2024-06-01/", "org-acme/metrics/2024-06-02/"},
+		"org-acme/metrics/2024-06-01/": {
+			"org-acme/metrics/2024-06-01/a.json", "org-acme/metrics/2024-06-01/b.json.gz",
+		},
+		"org-acme/anomalies/": nil,
+	}
+	for prefix, want := range listings {
+		got, err := store.List(ctx, prefix)
+		if err != nil {
+			t.Fatalf("List(%q): %v", prefix, err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("List(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+
+	// Put replaces an existing object
+	key := "org-acme/metrics/2024-06-01/a.json"
+	if err := store.Put(ctx, key, []byte(`{"id":"a","v":2}`), "application/json"); err != nil {
+		t.Fatalf("Put(%s) again: %v", key, err)
+	}
+	if got, _ := store.Get(ctx, key); string(got) != `{"id":"a","v":2}` {
+		t.Errorf("Get(%s) after replacing it = %q", key, got)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete(%s): %v", key, err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, objectstore.ErrNotFound) {
+		t.Errorf("Get after Delete: got error %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+	got, err := store.List(ctx, "org-acme/metrics/2024-06-01/")
+	if err != nil {
+		t.Fatalf("List after Delete: %v", err)
+	}
+	if want := []string{"org-acme/metrics/2024-06-01/b.json.gz"}; !slices.Equal(got, want) {
+		t.Errorf("List after Delete = %q, want %q", got, want)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	root := t.TempDir()
+	store, err := objectstore.NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, store)
+
+	if _, err := os.Stat(filepath.Join(root, "org-acme", "seats", "2024-06-01", "s.json")); err != nil {
+		t.Errorf("object not stored as a file below the root: %v", err)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	server := s3test.NewServer("metrics")
+	defer server.Close()
+
+	store, err := objectstore.NewS3Store(server.Config("metrics", "copilot/exports"), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, store)
+
+	if _, exists := server.Object("metrics", "copilot/exports/org-acme/seats/2024-06-01/s.json"); !exists {
+		t.Errorf("object not stored below the prefix, bucket keys: %q", server.Keys("metrics"))
+	}
+	if got, want := store.Location(), "s3://metrics/copilot/exports"; got != want {
+		t.Errorf("Location() = %q, want %q", got, want)
+	}
+}
+
+func TestS3StoreMissingBucket(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+
+	store, err := objectstore.NewS3Store(server.Config("missing", ""), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(context.Background(), "a.json", []byte("{}"), "application/json"); err == nil {
+		t.Error("Put to a missing bucket succeeded")
+	}
+}
+
+// TestMinIO runs the store checks against a MinIO server, such as the one of
+// the minio profile of docker-compose.yml, when MINIO_TEST_ENDPOINT is set
+func TestMinIO(t *testing.T) {
+	endpoint := os.Getenv("MINIO_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_TEST_ENDPOINT is not set")
+	}
+	accessKey, secretKey := os.Getenv("MINIO_ROOT_USER"), os.Getenv("MINIO_ROOT_PASSWORD")
+	if accessKey == "" {
+		accessKey, secretKey = "minioadmin", "minioadmin"
+	}
+
+	cfg := objectstore.S3Config{
+		Endpoint:  endpoint,
+		Region:    "us-east-1",
+		Bucket:    fmt.Sprintf("objectstore-test-%d", time.Now().UnixNano()),
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	}
+	store, err := objectstore.NewS3Store(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newMinIOClient(t, cfg)
+	ctx := context.Background()
+	if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+		t.Fatalf("failed to create bucket %s: %v", cfg.Bucket, err)
+	}
+	defer func() {
+		for object := range client.ListObjects(ctx, cfg.Bucket, minio.ListObjectsOptions{Recursive: true}) {
+			client.RemoveObject(ctx, cfg.Bucket, object.Key, minio.RemoveObjectOptions{})
+		}
+		client.RemoveBucket(ctx, cfg.Bucket)
+	}()
+
+	testStore(t, store)
+}
+
+// newMinIOClient creates a client to set up the bucket of a test
+func newMinIOClient(t *testing.T, cfg objectstore.S3Config) *minio.Client {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Host == "" {
+		t.Fatalf("MINIO_TEST_ENDPOINT must be a URL such as http://localhost:9000, got %q", cfg.Endpoint)
+	}
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: u.Scheme == "https",
+		Region: cfg.Region,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestOpen(t *testing.T) {
+	server := s3test.NewServer("bucket")
+	defer server.Close()
+
+	store, err := objectstore.Open("s3://bucket/some/prefix/", server.Config("", ""), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.Location(), "s3://bucket/some/prefix"; got != want {
+		t.Errorf("Location() of an S3 location = %q, want %q", got, want)
+	}
+
+	root := filepath.Join(t.TempDir(), "exports")
+	store, err = objectstore.Open(root, objectstore.S3Config{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.(*objectstore.LocalStore); !ok || store.Location() != root {
+		t.Errorf("Open(%q) = %T at %s, want a local store", root, store, store.Location())
+	}
+}
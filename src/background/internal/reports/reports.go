@@ -0,0 +1,79 @@
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format defines the output format of a report
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatCSV   Format = "csv"
+	FormatJSON  Format = "json"
+)
+
+// ParseFormat converts a format name to a Format
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatTable:
+		return FormatTable, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported report format: %s", name)
+	}
+}
+
+// Table holds the tabular representation of a report
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// NewTable creates a new table with the given column headers
+func NewTable(headers ...string) *Table {
+	return &Table{Headers: headers}
+}
+
+// AddRow appends a row of values to the table
+func (t *Table) AddRow(values ...string) {
+	t.Rows = append(t.Rows, values)
+}
+
+// Write renders a report in the requested format. Table and CSV output are
+// rendered from the table, while JSON output encodes data as is so that
+// consumers get typed values.
+func Write(w io.Writer, format Format, table *Table, data interface{}) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(table.Headers); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(table.Rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	case FormatTable:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(table.Headers, "\t"))
+		for _, row := range table.Rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SaveMode defines how a batch save handles documents that cannot be saved
+type SaveMode string
+
+const (
+	// SaveBestEffort saves every document it can and reports the others
+	SaveBestEffort SaveMode = "best-effort"
+	// SaveAllOrNothing saves either every document of a batch or none of them
+	SaveAllOrNothing SaveMode = "all-or-nothing"
+)
+
+// ErrBatchAborted is the error of the documents of an all-or-nothing batch
+// that were not saved because another document failed
+var ErrBatchAborted = errors.New("batch aborted after another document failed")
+
+// ItemError is a document of a batch that was not saved
+type ItemError struct {
+	ID  string `json:"id"`
+	Err error  `json:"-"`
+}
+
+// BatchResult reports which documents of a batch save were stored
+type BatchResult struct {
+	Succeeded []string    `json:"succeeded"`
+	Failed    []ItemError `json:"failed"`
+}
+
+// succeed records a saved document
+func (r *BatchResult) succeed(id string) {
+	r.Succeeded = append(r.Succeeded, id)
+}
+
+// fail records a document that was not saved
+func (r *BatchResult) fail(id string, err error) {
+	r.Failed = append(r.Failed, ItemError{ID: id, Err: err})
+}
+
+// abort turns the result of a batch into a failure of every document, as
+// when an all-or-nothing batch is rolled back or the batch failed as a whole.
+// Documents without an error of their own fail with err.
+func (r *BatchResult) abort(ids []string, err error) {
+	failed := make(map[string]bool, len(r.Failed))
+	for _, item := range r.Failed {
+		failed[item.ID] = true
+	}
+	for _, id := range ids {
+		if !failed[id] {
+			r.fail(id, err)
+		}
+	}
+	r.Succeeded = nil
+}
+
+// FailedIDs returns the IDs of the documents that were not saved
+func (r *BatchResult) FailedIDs() map[string]bool {
+	ids := make(map[string]bool, len(r.Failed))
+	for _, item := range r.Failed {
+		ids[item.ID] = true
+	}
+	return ids
+}
+
+// Err returns a *BatchError if any document was not saved
+func (r *BatchResult) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	return &BatchError{Failed: r.Failed, Total: len(r.Succeeded) + len(r.Failed)}
+}
+
+// BatchError reports the documents of a batch that were not saved
+type BatchError struct {
+	Failed []ItemError
+	Total  int
+}
+
+func (e *BatchError) Error() string {
+	// Report the document that caused an aborted batch rather than the others
+	first := e.Failed[0]
+	for _, item := range e.Failed {
+		if !errors.Is(item.Err, ErrBatchAborted) {
+			first = item
+			break
+		}
+	}
+	return fmt.Sprintf("failed to save %d of %d documents, first %s: %v", len(e.Failed), e.Total, first.ID, first.Err)
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, item := range e.Failed {
+		errs[i] = item.Err
+	}
+	return errs
+}
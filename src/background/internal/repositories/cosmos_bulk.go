@@ -0,0 +1,317 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxBatchOperations is the Cosmos DB limit of operations in a transactional batch
+	maxBatchOperations = 100
+	// maxBatchBytes is the Cosmos DB limit of the request payload of a
+	// transactional batch
+	maxBatchBytes = 2 * 1024 * 1024
+	// batchOperationBytes is the room left for the envelope of each operation
+	// of a transactional batch, on top of its document
+	batchOperationBytes = 256
+	// DefaultCosmosBulkConcurrency is the number of transactional batches
+	// written at once when none is configured
+	DefaultCosmosBulkConcurrency = 4
+	// maxThrottleRetries is the number of times a request throttled by Cosmos
+	// DB is retried, on top of the retries of the SDK
+	maxThrottleRetries = 5
+	// defaultRetryAfter is the wait after a throttled request without a retry-after header
+	defaultRetryAfter = time.Second
+)
+
+// bulkStats accumulates the cost of the requests of a bulk save
+type bulkStats struct {
+	requestCharge float32
+	throttled     int
+}
+
+func (s *bulkStats) add(other bulkStats) {
+	s.requestCharge += other.requestCharge
+	s.throttled += other.throttled
+}
+
+// upsertBatch upserts a batch of documents into a container. Documents are
+// grouped by partition key into transactional batches of up to 100
+// documents and 2 MB, which are written concurrently. In best-effort mode the documents of a failed
+// transactional batch are upserted one by one, so only the documents that
+// fail are left out. In all-or-nothing mode every document is marshaled
+// before any is written and the first failed transactional batch stops the
+// batch; a transactional batch is atomic within its partition, but Cosmos DB
+// cannot roll back the batches of other partitions committed before it,
+// which are reported as saved.
+func upsertBatch[T any](ctx context.Context, r *CosmosRepository, containerName, kind string, documents []T,
+	key func(document *T) (id, enterprise, organization string)) (*BatchResult, error) {
+	start := time.Now()
+	result := &BatchResult{}
+	ids := make([]string, len(documents))
+	items := make([]cosmosItem, 0, len(documents))
+	var marshalErr error
+	for i := range documents {
+		var enterprise, organization string
+		ids[i], enterprise, organization = key(&documents[i])
+
+		item, err := r.marshalItem(ids[i], documents[i], enterprise, organization)
+		if err != nil {
+			r.logger.Warn("Failed to marshal "+kind, zap.String("id", ids[i]), zap.Error(err))
+			result.fail(ids[i], err)
+			marshalErr = err
+			continue
+		}
+		items = append(items, item)
+	}
+	if marshalErr != nil && r.saveMode == SaveAllOrNothing {
+		result.abort(ids, ErrBatchAborted)
+		return result, result.Err()
+	}
+
+	container, err := r.container(containerName)
+	if err != nil {
+		result.abort(ids, err)
+		return result, err
+	}
+
+	batches := partitionBatches(items)
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		stats   bulkStats
+		aborted bool
+	)
+	concurrency := make(chan struct{}, r.bulkConcurrency)
+	for _, batch := range batches {
+		concurrency <- struct{}{}
+		wg.Add(1)
+		go func(batch []cosmosItem) {
+			defer wg.Done()
+			defer func() { <-concurrency }()
+
+			mu.Lock()
+			stopped := aborted
+			mu.Unlock()
+			if stopped || batchCtx.Err() != nil {
+				err := batchCtx.Err()
+				if stopped {
+					err = ErrBatchAborted
+				}
+				mu.Lock()
+				for _, item := range batch {
+					result.fail(item.id, err)
+				}
+				mu.Unlock()
+				return
+			}
+
+			batchStats, failed, err := r.executeBatch(batchCtx, container, batch)
+			if err != nil {
+				r.logger.Warn("Failed to write transactional batch of "+kind,
+					zap.String("container", r.containerName(containerName)),
+					zap.String("partitionKey", batch[0].partition),
+					zap.Int("count", len(batch)),
+					zap.Error(err))
+			}
+			if err != nil && r.saveMode != SaveAllOrNothing && batchCtx.Err() == nil {
+				var upsertStats bulkStats
+				upsertStats, failed = r.upsertEach(batchCtx, container, kind, batch)
+				batchStats.add(upsertStats)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			stats.add(batchStats)
+			if err != nil && r.saveMode == SaveAllOrNothing {
+				aborted = true
+				cancel()
+			}
+			for _, item := range batch {
+				if itemErr, exists := failed[item.id]; exists {
+					result.fail(item.id, itemErr)
+				} else {
+					result.succeed(item.id)
+				}
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	r.logger.Info("Saved "+kind+" batch",
+		zap.String("container", r.containerName(containerName)),
+		zap.Int("saved", len(result.Succeeded)),
+		zap.Int("failed", len(result.Failed)),
+		zap.Int("transactionalBatches", len(batches)),
+		zap.Float32("requestCharge", stats.requestCharge),
+		zap.Int("throttled", stats.throttled),
+		zap.Duration("duration", time.Since(start)))
+
+	return result, result.Err()
+}
+
+// partitionBatches groups items by partition key into batches of at most
+// maxBatchOperations items and maxBatchBytes, keeping the order of the items
+// within a partition. An item too large for the payload limit on its own gets
+// a batch of its own, which Cosmos DB rejects as it would the single upsert.
+func partitionBatches(items []cosmosItem) [][]cosmosItem {
+	var partitions []string
+	byPartition := make(map[string][]cosmosItem)
+	for _, item := range items {
+		if _, exists := byPartition[item.partition]; !exists {
+			partitions = append(partitions, item.partition)
+		}
+		byPartition[item.partition] = append(byPartition[item.partition], item)
+	}
+
+	var batches [][]cosmosItem
+	for _, partition := range partitions {
+		var batch []cosmosItem
+		size := 0
+		for _, item := range byPartition[partition] {
+			itemSize := len(item.data) + batchOperationBytes
+			if len(batch) > 0 && (len(batch) == maxBatchOperations || size+itemSize > maxBatchBytes) {
+				batches = append(batches, batch)
+				batch, size = nil, 0
+			}
+			batch = append(batch, item)
+			size += itemSize
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// executeBatch upserts items of one partition in a transactional batch,
+// waiting and retrying while Cosmos DB throttles it. When the batch fails the
+// cause is returned as the error of the item that failed and every other
+// item fails with ErrBatchAborted.
+func (r *CosmosRepository) executeBatch(ctx context.Context, container *azcosmos.ContainerClient, items []cosmosItem) (bulkStats, map[string]error, error) {
+	var stats bulkStats
+	batch := container.NewTransactionalBatch(items[0].partitionKey)
+	for _, item := range items {
+		batch.UpsertItem(item.data, nil)
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := container.ExecuteTransactionalBatch(ctx, batch, nil)
+		var wait time.Duration
+		if err == nil {
+			stats.requestCharge += response.RequestCharge
+			if response.Success {
+				return stats, nil, nil
+			}
+
+			index, status := batchFailure(response)
+			if status != http.StatusTooManyRequests {
+				err = fmt.Errorf("transactional batch failed with status %d on document %s", status, items[index].id)
+				return stats, abortedItems(items, index, err), err
+			}
+			wait = retryAfter(response.RawResponse)
+		} else {
+			var responseErr *azcore.ResponseError
+			if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusTooManyRequests {
+				return stats, abortedItems(items, -1, err), err
+			}
+			wait = retryAfter(responseErr.RawResponse)
+		}
+
+		if attempt == maxThrottleRetries {
+			err = fmt.Errorf("transactional batch still throttled after %d retries", maxThrottleRetries)
+			return stats, abortedItems(items, -1, err), err
+		}
+		stats.throttled++
+		if err := waitRetry(ctx, wait); err != nil {
+			return stats, abortedItems(items, -1, err), err
+		}
+	}
+}
+
+// upsertEach upserts items one by one, waiting and retrying while Cosmos DB
+// throttles them, and returns the errors of the items that failed
+func (r *CosmosRepository) upsertEach(ctx context.Context, container *azcosmos.ContainerClient, kind string, items []cosmosItem) (bulkStats, map[string]error) {
+	var stats bulkStats
+	failed := make(map[string]error)
+	for _, item := range items {
+		for attempt := 0; ; attempt++ {
+			response, err := container.UpsertItem(ctx, item.partitionKey, item.data, nil)
+			if err == nil {
+				stats.requestCharge += response.RequestCharge
+				break
+			}
+
+			var responseErr *azcore.ResponseError
+			if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusTooManyRequests || attempt == maxThrottleRetries {
+				r.logger.Warn("Failed to upsert "+kind, zap.String("id", item.id), zap.Error(err))
+				failed[item.id] = err
+				break
+			}
+			stats.throttled++
+			if err := waitRetry(ctx, retryAfter(responseErr.RawResponse)); err != nil {
+				failed[item.id] = err
+				break
+			}
+		}
+	}
+	return stats, failed
+}
+
+// batchFailure returns the index and status of the operation that failed a
+// transactional batch; the other operations fail with 424 Failed Dependency
+func batchFailure(response azcosmos.TransactionalBatchResponse) (int, int) {
+	for i, result := range response.OperationResults {
+		if result.StatusCode != http.StatusFailedDependency {
+			return i, int(result.StatusCode)
+		}
+	}
+	return 0, http.StatusFailedDependency
+}
+
+// abortedItems returns the errors of the items of a failed transactional
+// batch: the item at index fails with err and the others with
+// ErrBatchAborted, or every item fails with err when index is -1
+func abortedItems(items []cosmosItem, index int, err error) map[string]error {
+	failed := make(map[string]error, len(items))
+	for i, item := range items {
+		if index == -1 || i == index {
+			failed[item.id] = err
+		} else {
+			failed[item.id] = ErrBatchAborted
+		}
+	}
+	return failed
+}
+
+// retryAfter returns how long Cosmos DB asked a throttled client to wait
+func retryAfter(response *http.Response) time.Duration {
+	if response == nil {
+		return defaultRetryAfter
+	}
+	ms, err := strconv.ParseFloat(response.Header.Get("x-ms-retry-after-ms"), 64)
+	if err != nil || ms <= 0 {
+		return defaultRetryAfter
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// waitRetry waits before retrying a throttled request, unless the context ends first
+func waitRetry(ctx context.Context, wait time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"go.uber.org/zap"
+)
+
+// CosmosOptions configures the database and containers of the Cosmos DB repository
+type CosmosOptions struct {
+	Database       string            // Database name (default: platform-engineering)
+	ContainerNames map[string]string // Container names by default name, for containers named differently
+	SaveMode       SaveMode
+	// BulkConcurrency is the number of transactional batches written at once
+	// (default: 4)
+	BulkConcurrency int
+}
+
+// DefaultCosmosDatabase is the database used when none is configured
+const DefaultCosmosDatabase = "platform-engineering"
+
+// scopePartitionKeyPath is the partition key path of the containers created by
+// the repository. Every document is written with a scope property, ent-<slug>
+// or org-<slug>, so that the documents of a scope share a logical partition.
+const scopePartitionKeyPath = "/scope"
+
+// ErrPartitionKeyMismatch is returned by Initialize when an existing container
+// is not partitioned by /scope
+var ErrPartitionKeyMismatch = errors.New("container is not partitioned by " + scopePartitionKeyPath)
+
+// cosmosContainer describes a container of the repository
+type cosmosContainer struct {
+	name     string   // Default container name
+	excluded []string // Paths left out of the index, such as large payloads
+}
+
+// cosmosContainers lists the containers of the repository. Queries filter on
+// top-level properties only, so nested payloads are not indexed.
+var cosmosContainers = []cosmosContainer{
+	{name: "metrics_history", excluded: []string{
+		"/copilot_ide_code_completions/*", "/copilot_ide_chat/*", "/copilot_dotcom_chat/*", "/copilot_dotcom_pull_requests/*",
+	}},
+	{name: "usage_history", excluded: []string{"/breakdown/*"}},
+	{name: "seats_history", excluded: []string{"/seats/*"}},
+	{name: "team_memberships_history", excluded: []string{"/teams/*"}},
+	{name: "seat_events"},
+	{name: "seat_activity"},
+	{name: "raw_responses", excluded: []string{"/body/?"}},
+	{name: "validation_violations"},
+	{name: "quarantined_documents", excluded: []string{"/document/*"}},
+	{name: "metrics_markers"},
+	{name: "anomalies"},
+	{name: "alerts"},
+	{name: "ingestion_runs"},
+}
+
+// indexingPolicy returns the indexing policy a container is created with
+func (c cosmosContainer) indexingPolicy() *azcosmos.IndexingPolicy {
+	excluded := []azcosmos.ExcludedPath{{Path: `/"_etag"/?`}}
+	for _, path := range c.excluded {
+		excluded = append(excluded, azcosmos.ExcludedPath{Path: path})
+	}
+	return &azcosmos.IndexingPolicy{
+		Automatic:     true,
+		IndexingMode:  azcosmos.IndexingModeConsistent,
+		IncludedPaths: []azcosmos.IncludedPath{{Path: "/*"}},
+		ExcludedPaths: excluded,
+	}
+}
+
+// Initialize creates the database and the missing containers, and checks that
+// existing containers are partitioned by /scope. A container with another
+// partition key, such as /date from older infrastructure templates, fails
+// initialization: the partition key of a container cannot be changed, so its
+// documents must be migrated to a container partitioned by /scope. The
+// repository is initialized nonetheless and the error wraps
+// ErrPartitionKeyMismatch, so that reads can still serve such a migration. A
+// container that cannot be created, for instance because metadata writes are
+// restricted to the control plane, is logged and its saves fail until it is
+// provisioned.
+func (r *CosmosRepository) Initialize(ctx context.Context) error {
+	database, err := r.client.NewDatabase(r.database)
+	if err != nil {
+		return err
+	}
+
+	if _, err := database.Read(ctx, nil); hasStatus(err, http.StatusNotFound) {
+		r.logger.Info("Creating Cosmos DB database", zap.String("database", r.database))
+		_, err = r.client.CreateDatabase(ctx, azcosmos.DatabaseProperties{ID: r.database}, nil)
+		if err != nil && !hasStatus(err, http.StatusConflict) {
+			return fmt.Errorf("failed to create database %s: %w", r.database, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to read database %s: %w", r.database, err)
+	}
+
+	var mismatches []error
+	for _, spec := range cosmosContainers {
+		name := r.containerName(spec.name)
+		container, err := database.NewContainer(name)
+		if err != nil {
+			return err
+		}
+
+		response, err := container.Read(ctx, nil)
+		if err == nil {
+			paths := response.ContainerProperties.PartitionKeyDefinition.Paths
+			if len(paths) != 1 || paths[0] != scopePartitionKeyPath {
+				mismatches = append(mismatches, fmt.Errorf("%w: %s is partitioned by %s",
+					ErrPartitionKeyMismatch, name, strings.Join(paths, ",")))
+			}
+			continue
+		}
+		if !hasStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("failed to read container %s: %w", name, err)
+		}
+
+		r.logger.Info("Creating Cosmos DB container",
+			zap.String("container", name),
+			zap.String("partitionKey", scopePartitionKeyPath))
+		_, err = database.CreateContainer(ctx, azcosmos.ContainerProperties{
+			ID: name,
+			PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+				Paths: []string{scopePartitionKeyPath},
+			},
+			IndexingPolicy: spec.indexingPolicy(),
+		}, nil)
+		if err != nil && !hasStatus(err, http.StatusConflict) {
+			r.logger.Warn("Failed to create Cosmos DB container, saves to it fail until it is provisioned",
+				zap.String("container", name),
+				zap.String("partitionKey", scopePartitionKeyPath),
+				zap.Error(err))
+		}
+	}
+
+	return errors.Join(mismatches...)
+}
+
+// containerName returns the configured name of a container
+func (r *CosmosRepository) containerName(name string) string {
+	if configured, exists := r.containerNames[name]; exists {
+		return configured
+	}
+	return name
+}
+
+// container returns a client of a container, by default name
+func (r *CosmosRepository) container(name string) (*azcosmos.ContainerClient, error) {
+	return r.client.NewContainer(r.database, r.containerName(name))
+}
+
+// item marshals a document, adding its scope property, and returns it with
+// its partition key
+func (r *CosmosRepository) item(document any, enterprise, organization string) ([]byte, azcosmos.PartitionKey, error) {
+	item, err := r.marshalItem("", document, enterprise, organization)
+	return item.data, item.partitionKey, err
+}
+
+// cosmosItem is a document marshaled for a container partitioned by /scope
+type cosmosItem struct {
+	id           string
+	data         []byte
+	partitionKey azcosmos.PartitionKey
+	partition    string // Scope of the document, to group items by partition
+}
+
+// marshalItem marshals a document, adding its scope property, which is also
+// its partition key
+func (r *CosmosRepository) marshalItem(id string, document any, enterprise, organization string) (cosmosItem, error) {
+	item := cosmosItem{id: id}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return item, err
+	}
+
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return item, err
+	}
+	scope := scopeSegment(enterprise, organization)
+	properties["scope"], _ = json.Marshal(scope)
+	item.data, err = json.Marshal(properties)
+	if err != nil {
+		return item, err
+	}
+
+	item.partitionKey = azcosmos.NewPartitionKeyString(scope)
+	item.partition = scope
+	return item, nil
+}
+
+// cosmosItemProperties are the properties of a stored item that are not part
+// of the document: the system properties of Cosmos DB and the scope property
+// added by marshalItem. They are dropped on read so that they do not end up
+// in the Extensions of a model and get copied to other documents or storages.
+var cosmosItemProperties = []string{"_rid", "_self", "_etag", "_attachments", "_ts", "scope"}
+
+// stripItem removes the cosmosItemProperties from a stored item
+func stripItem(data []byte) ([]byte, error) {
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil || properties == nil {
+		// Not an object, e.g. the value of a SELECT VALUE query
+		return data, nil
+	}
+
+	stripped := false
+	for _, name := range cosmosItemProperties {
+		if _, exists := properties[name]; exists {
+			delete(properties, name)
+			stripped = true
+		}
+	}
+	if !stripped {
+		return data, nil
+	}
+	return json.Marshal(properties)
+}
@@ -3,8 +3,12 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
 	"go.uber.org/zap"
@@ -12,130 +16,717 @@ import (
 
 // CosmosRepository implements Repository using Azure Cosmos DB
 type CosmosRepository struct {
-	client *azcosmos.Client
-	logger *zap.Logger
+	client         *azcosmos.Client
+	logger         *zap.Logger
+	database       string
+	containerNames map[string]string
+	saveMode       SaveMode
+	// bulkConcurrency is the number of transactional batches written at once
+	bulkConcurrency int
 }
 
-// NewCosmosRepository creates a new Cosmos DB repository
-func NewCosmosRepository(endpoint, key string, logger *zap.Logger) (*CosmosRepository, error) {
-	if endpoint == "" {
-		return nil, fmt.Errorf("Cosmos DB endpoint is not specified")
+// NewCosmosRepository creates a new Cosmos DB repository on a client created
+// by cosmosauth.NewClient
+func NewCosmosRepository(client *azcosmos.Client, options CosmosOptions, logger *zap.Logger) *CosmosRepository {
+	if options.Database == "" {
+		options.Database = DefaultCosmosDatabase
+	}
+	if options.BulkConcurrency <= 0 {
+		options.BulkConcurrency = DefaultCosmosBulkConcurrency
 	}
 
-	if key == "" {
-		return nil, fmt.Errorf("Cosmos DB key is not specified")
+	return &CosmosRepository{
+		client:          client,
+		logger:          logger,
+		database:        options.Database,
+		containerNames:  options.ContainerNames,
+		saveMode:        options.SaveMode,
+		bulkConcurrency: options.BulkConcurrency,
 	}
+}
+
+// SaveMetrics stores metrics data in Cosmos DB
+func (r *CosmosRepository) SaveMetrics(ctx context.Context, metrics []models.Metrics) (*BatchResult, error) {
+	return upsertBatch(ctx, r, "metrics_history", "metric", metrics, func(metric *models.Metrics) (string, string, string) {
+		// Set ID if not already set
+		if metric.ID == "" {
+			metric.ID = metric.GetID()
+		}
+		return metric.ID, metric.Enterprise, metric.Organization
+	})
+}
 
-	cred, err := azcosmos.NewKeyCredential(key)
+// SaveSeats stores seats data in Cosmos DB
+func (r *CosmosRepository) SaveSeats(ctx context.Context, seats *models.CopilotAssignedSeats) error {
+	container, err := r.container("seats_history")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Cosmos DB credentials: %w", err)
+		return err
 	}
 
-	client, err := azcosmos.NewClientWithKey(endpoint, cred, nil)
+	if seats.ID == "" {
+		seats.ID = seats.GetID()
+	}
+
+	data, partitionKey, err := r.item(seats, seats.Enterprise, seats.Organization)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Cosmos DB client: %w", err)
+		return err
 	}
 
-	return &CosmosRepository{
-		client: client,
-		logger: logger,
-	}, nil
+	_, err = container.UpsertItem(ctx, partitionKey, data, nil)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("Saved seats", zap.String("id", seats.ID), zap.Int("totalSeats", seats.TotalSeats))
+
+	return nil
+}
+
+// SaveUsage stores usage data in Cosmos DB
+func (r *CosmosRepository) SaveUsage(ctx context.Context, usageData []models.CopilotUsage) (*BatchResult, error) {
+	return upsertBatch(ctx, r, "usage_history", "usage data", usageData, func(usage *models.CopilotUsage) (string, string, string) {
+		if usage.ID == "" {
+			usage.ID = usage.GetID()
+		}
+		return usage.ID, usage.Enterprise, usage.Organization
+	})
+}
+
+// GetMetrics returns metrics between two dates from Cosmos DB
+func (r *CosmosRepository) GetMetrics(ctx context.Context, from, to string) ([]models.Metrics, error) {
+	container, err := r.container("metrics_history")
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := queryItems[models.Metrics](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Date != metrics[j].Date {
+			return metrics[i].Date < metrics[j].Date
+		}
+		return metrics[i].ID < metrics[j].ID
+	})
+
+	return metrics, nil
 }
 
-// Initialize sets up the necessary containers
-func (r *CosmosRepository) Initialize(ctx context.Context) error {
-	// Cosmos DB containers are created automatically in the handlers
-	// via the CreateIfNotExists option
+// GetUsage returns usage data between two days from Cosmos DB
+func (r *CosmosRepository) GetUsage(ctx context.Context, from, to string) ([]models.CopilotUsage, error) {
+	container, err := r.container("usage_history")
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := queryItems[models.CopilotUsage](ctx, container,
+		"SELECT * FROM c WHERE c.day >= @from AND c.day <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Day != usage[j].Day {
+			return usage[i].Day < usage[j].Day
+		}
+		return usage[i].ID < usage[j].ID
+	})
+
+	return usage, nil
+}
+
+// GetSeatsHistory returns the seats snapshots between two dates from Cosmos DB
+func (r *CosmosRepository) GetSeatsHistory(ctx context.Context, from, to string) ([]models.CopilotAssignedSeats, error) {
+	container, err := r.container("seats_history")
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := queryItems[models.CopilotAssignedSeats](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seats: %w", err)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Date != snapshots[j].Date {
+			return snapshots[i].Date < snapshots[j].Date
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+
+	return snapshots, nil
+}
+
+// GetLatestSeats returns the most recent seats snapshot from Cosmos DB
+func (r *CosmosRepository) GetLatestSeats(ctx context.Context) (*models.CopilotAssignedSeats, error) {
+	container, err := r.container("seats_history")
+	if err != nil {
+		return nil, err
+	}
+
+	// The gateway cannot serve cross-partition ORDER BY queries, so the
+	// latest snapshot is selected client side from the lightweight headers
+	headers, err := queryItems[models.CopilotAssignedSeats](ctx, container, "SELECT c.id, c.date, c.last_update FROM c", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seats: %w", err)
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	latest := headers[0]
+	for _, header := range headers[1:] {
+		if header.LastUpdate.After(latest.LastUpdate) {
+			latest = header
+		}
+	}
+
+	snapshots, err := queryItems[models.CopilotAssignedSeats](ctx, container,
+		"SELECT * FROM c WHERE c.id = @id",
+		[]azcosmos.QueryParameter{{Name: "@id", Value: latest.ID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seats: %w", err)
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	return &snapshots[0], nil
+}
+
+// SaveTeamMemberships stores a team memberships snapshot in Cosmos DB
+func (r *CosmosRepository) SaveTeamMemberships(ctx context.Context, memberships *models.TeamMemberships) error {
+	container, err := r.container("team_memberships_history")
+	if err != nil {
+		return err
+	}
+
+	if memberships.ID == "" {
+		memberships.ID = memberships.GetID()
+	}
+
+	data, partitionKey, err := r.item(memberships, memberships.Enterprise, memberships.Organization)
+	if err != nil {
+		return err
+	}
+
+	_, err = container.UpsertItem(ctx, partitionKey, data, nil)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("Saved team memberships", zap.String("id", memberships.ID), zap.Int("teams", len(memberships.Teams)))
 	return nil
 }
 
-// SaveMetrics stores metrics data in Cosmos DB
-func (r *CosmosRepository) SaveMetrics(ctx context.Context, metrics []models.Metrics) error {
-	container, err := r.client.NewContainer("platform-engineering", "metrics_history")
+// GetTeamMemberships returns the team memberships snapshots between two dates from Cosmos DB
+func (r *CosmosRepository) GetTeamMemberships(ctx context.Context, from, to string) ([]models.TeamMemberships, error) {
+	container, err := r.container("team_memberships_history")
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := queryItems[models.TeamMemberships](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team memberships: %w", err)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Date != snapshots[j].Date {
+			return snapshots[i].Date < snapshots[j].Date
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+
+	return snapshots, nil
+}
+
+// SaveSeatEvents stores seat events in Cosmos DB
+func (r *CosmosRepository) SaveSeatEvents(ctx context.Context, events []models.SeatEvent) error {
+	container, err := r.container("seat_events")
 	if err != nil {
 		return err
 	}
 
-	for _, metric := range metrics {
-		// Set ID if not already set
-		if metric.ID == "" {
-			metric.ID = metric.GetID()
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = event.GetID()
 		}
 
-		data, err := json.Marshal(metric)
+		data, partitionKey, err := r.item(event, event.Enterprise, event.Organization)
 		if err != nil {
-			r.logger.Warn("Failed to marshal metric", zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal seat event: %w", err)
 		}
 
-		_, err = container.UpsertItem(ctx, azcosmos.PartitionKey{}, data, nil)
+		if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+			return fmt.Errorf("failed to upsert seat event %s: %w", event.ID, err)
+		}
+	}
+
+	r.logger.Info("Saved seat events", zap.Int("count", len(events)))
+	return nil
+}
+
+// GetSeatEvents returns seat events between two dates from Cosmos DB
+func (r *CosmosRepository) GetSeatEvents(ctx context.Context, from, to string) ([]models.SeatEvent, error) {
+	container, err := r.container("seat_events")
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := queryItems[models.SeatEvent](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seat events: %w", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Date != events[j].Date {
+			return events[i].Date < events[j].Date
+		}
+		return events[i].Login < events[j].Login
+	})
+
+	return events, nil
+}
+
+// SaveSeatActivity stores seat activity in Cosmos DB. Activity that was
+// already recorded is kept with its original observation time.
+func (r *CosmosRepository) SaveSeatActivity(ctx context.Context, activity []models.SeatActivity) error {
+	container, err := r.container("seat_activity")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range activity {
+		if entry.ID == "" {
+			entry.ID = entry.GetID()
+		}
+
+		data, partitionKey, err := r.item(entry, entry.Enterprise, entry.Organization)
 		if err != nil {
-			r.logger.Warn("Failed to upsert metric", zap.String("id", metric.ID), zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal seat activity: %w", err)
 		}
 
-		r.logger.Info("Saved metric", zap.String("id", metric.ID))
+		if _, err := container.CreateItem(ctx, partitionKey, data, nil); err != nil && !isConflict(err) {
+			return fmt.Errorf("failed to create seat activity %s: %w", entry.ID, err)
+		}
 	}
 
+	r.logger.Info("Saved seat activity", zap.Int("count", len(activity)))
 	return nil
 }
 
-// SaveSeats stores seats data in Cosmos DB
-func (r *CosmosRepository) SaveSeats(ctx context.Context, seats *models.CopilotAssignedSeats) error {
-	container, err := r.client.NewContainer("platform-engineering", "seats_history")
+// GetSeatActivity returns seat activity between two dates from Cosmos DB
+func (r *CosmosRepository) GetSeatActivity(ctx context.Context, from, to string) ([]models.SeatActivity, error) {
+	container, err := r.container("seat_activity")
+	if err != nil {
+		return nil, err
+	}
+
+	activity, err := queryItems[models.SeatActivity](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seat activity: %w", err)
+	}
+
+	sort.Slice(activity, func(i, j int) bool {
+		return activity[i].LastActivityAt.Before(activity[j].LastActivityAt)
+	})
+
+	return activity, nil
+}
+
+// SaveRawResponse archives a raw GitHub API response in Cosmos DB
+func (r *CosmosRepository) SaveRawResponse(ctx context.Context, response *models.RawResponse) error {
+	container, err := r.container("raw_responses")
 	if err != nil {
 		return err
 	}
 
-	if seats.ID == "" {
-		seats.ID = seats.GetID()
+	if response.ID == "" {
+		response.ID = response.GetID()
+	}
+
+	data, partitionKey, err := r.item(response, response.Enterprise, response.Organization)
+	if err != nil {
+		return fmt.Errorf("failed to marshal raw response: %w", err)
+	}
+
+	if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+		return fmt.Errorf("failed to upsert raw response %s: %w", response.ID, err)
 	}
 
-	data, err := json.Marshal(seats)
+	return nil
+}
+
+// GetRawResponses returns the archived responses of a kind between two dates from Cosmos DB
+func (r *CosmosRepository) GetRawResponses(ctx context.Context, kind models.RawResponseKind, from, to string) ([]models.RawResponse, error) {
+	container, err := r.container("raw_responses")
+	if err != nil {
+		return nil, err
+	}
+
+	params := append(dateRangeParameters(from, to), azcosmos.QueryParameter{Name: "@kind", Value: string(kind)})
+	responses, err := queryItems[models.RawResponse](ctx, container,
+		"SELECT * FROM c WHERE c.kind = @kind AND c.date >= @from AND c.date <= @to",
+		params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query raw responses: %w", err)
+	}
+
+	sort.Slice(responses, func(i, j int) bool {
+		return responses[i].FetchedAt.Before(responses[j].FetchedAt)
+	})
+
+	return responses, nil
+}
+
+// SaveViolations stores validation violations in Cosmos DB
+func (r *CosmosRepository) SaveViolations(ctx context.Context, violations []models.Violation) error {
+	container, err := r.container("validation_violations")
 	if err != nil {
 		return err
 	}
 
-	_, err = container.UpsertItem(ctx, azcosmos.PartitionKey{}, data, nil)
+	for _, violation := range violations {
+		data, partitionKey, err := r.item(violation, violation.Enterprise, violation.Organization)
+		if err != nil {
+			return fmt.Errorf("failed to marshal violation: %w", err)
+		}
+
+		if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+			return fmt.Errorf("failed to upsert violation %s: %w", violation.ID, err)
+		}
+	}
+
+	r.logger.Info("Saved validation violations", zap.Int("count", len(violations)))
+	return nil
+}
+
+// GetViolations returns validation violations between two dates from Cosmos DB
+func (r *CosmosRepository) GetViolations(ctx context.Context, from, to string) ([]models.Violation, error) {
+	container, err := r.container("validation_violations")
+	if err != nil {
+		return nil, err
+	}
+
+	violations, err := queryItems[models.Violation](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query violations: %w", err)
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Date != violations[j].Date {
+			return violations[i].Date < violations[j].Date
+		}
+		return violations[i].ID < violations[j].ID
+	})
+
+	return violations, nil
+}
+
+// SaveQuarantinedDocument stores a quarantined document in Cosmos DB
+func (r *CosmosRepository) SaveQuarantinedDocument(ctx context.Context, document *models.QuarantinedDocument) error {
+	container, err := r.container("quarantined_documents")
 	if err != nil {
 		return err
 	}
 
-	r.logger.Info("Saved seats", zap.String("id", seats.ID), zap.Int("totalSeats", seats.TotalSeats))
+	if document.ID == "" {
+		document.ID = document.GetID()
+	}
+
+	data, partitionKey, err := r.item(document, document.Enterprise, document.Organization)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantined document: %w", err)
+	}
+
+	if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+		return fmt.Errorf("failed to upsert quarantined document %s: %w", document.ID, err)
+	}
 
+	r.logger.Info("Quarantined document", zap.String("id", document.DocumentID), zap.Strings("rules", document.Rules))
 	return nil
 }
 
-// SaveUsage stores usage data in Cosmos DB
-func (r *CosmosRepository) SaveUsage(ctx context.Context, usageData []models.CopilotUsage) error {
-	container, err := r.client.NewContainer("platform-engineering", "usage_history")
+// GetQuarantinedDocuments returns quarantined documents between two dates from Cosmos DB
+func (r *CosmosRepository) GetQuarantinedDocuments(ctx context.Context, from, to string) ([]models.QuarantinedDocument, error) {
+	container, err := r.container("quarantined_documents")
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := queryItems[models.QuarantinedDocument](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarantined documents: %w", err)
+	}
+
+	sort.Slice(documents, func(i, j int) bool {
+		if documents[i].Date != documents[j].Date {
+			return documents[i].Date < documents[j].Date
+		}
+		return documents[i].ID < documents[j].ID
+	})
+
+	return documents, nil
+}
+
+// isConflict reports whether an error is a Cosmos DB conflict (409) response
+func isConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// hasStatus reports whether an error is a Cosmos DB response with a status code
+func hasStatus(err error, statusCode int) bool {
+	var responseErr *azcore.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == statusCode
+}
+
+// dateRangeParameters builds the query parameters for a date range query
+func dateRangeParameters(from, to string) []azcosmos.QueryParameter {
+	return []azcosmos.QueryParameter{
+		{Name: "@from", Value: from},
+		{Name: "@to", Value: to},
+	}
+}
+
+// queryItems runs a cross-partition query and unmarshals every returned item,
+// without the properties added by Cosmos DB and the repository
+func queryItems[T any](ctx context.Context, container *azcosmos.ContainerClient, query string, params []azcosmos.QueryParameter) ([]T, error) {
+	pager := container.NewQueryItemsPager(query, azcosmos.NewPartitionKey(), &azcosmos.QueryOptions{
+		QueryParameters: params,
+	})
+
+	var items []T
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, raw := range page.Items {
+			data, err := stripItem(raw)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal item: %w", err)
+			}
+
+			var item T
+			if err := json.Unmarshal(data, &item); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal item: %w", err)
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// SaveMetricsMarkers stores metrics markers in Cosmos DB
+func (r *CosmosRepository) SaveMetricsMarkers(ctx context.Context, markers []models.MetricsMarker) error {
+	container, err := r.container("metrics_markers")
 	if err != nil {
 		return err
 	}
 
-	for _, usage := range usageData {
-		if usage.ID == "" {
-			usage.ID = usage.GetID()
+	for _, marker := range markers {
+		if marker.ID == "" {
+			marker.ID = marker.GetID()
 		}
 
-		data, err := json.Marshal(usage)
+		data, partitionKey, err := r.item(marker, marker.Enterprise, marker.Organization)
 		if err != nil {
-			r.logger.Warn("Failed to marshal usage data", zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal metrics marker: %w", err)
+		}
+
+		if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+			return fmt.Errorf("failed to upsert metrics marker %s: %w", marker.ID, err)
 		}
+	}
+
+	r.logger.Info("Saved metrics markers", zap.Int("count", len(markers)))
+	return nil
+}
 
-		_, err = container.UpsertItem(ctx, azcosmos.PartitionKey{}, data, nil)
+// GetMetricsMarkers returns metrics markers between two dates from Cosmos DB
+func (r *CosmosRepository) GetMetricsMarkers(ctx context.Context, from, to string) ([]models.MetricsMarker, error) {
+	container, err := r.container("metrics_markers")
+	if err != nil {
+		return nil, err
+	}
+
+	markers, err := queryItems[models.MetricsMarker](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics markers: %w", err)
+	}
+
+	sort.Slice(markers, func(i, j int) bool {
+		if markers[i].Date != markers[j].Date {
+			return markers[i].Date < markers[j].Date
+		}
+		return markers[i].ID < markers[j].ID
+	})
+
+	return markers, nil
+}
+
+// SaveAnomalies stores anomalies in Cosmos DB
+func (r *CosmosRepository) SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error {
+	container, err := r.container("anomalies")
+	if err != nil {
+		return err
+	}
+
+	for _, anomaly := range anomalies {
+		if anomaly.ID == "" {
+			anomaly.ID = anomaly.GetID()
+		}
+
+		data, partitionKey, err := r.item(anomaly, anomaly.Enterprise, anomaly.Organization)
 		if err != nil {
-			r.logger.Warn("Failed to upsert usage data", zap.String("id", usage.ID), zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal anomaly: %w", err)
 		}
 
-		r.logger.Info("Saved usage data", zap.String("id", usage.ID), zap.String("day", usage.Day))
+		if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+			return fmt.Errorf("failed to upsert anomaly %s: %w", anomaly.ID, err)
+		}
 	}
 
+	r.logger.Info("Saved anomalies", zap.Int("count", len(anomalies)))
 	return nil
 }
 
+// GetAnomalies returns anomalies between two dates from Cosmos DB
+func (r *CosmosRepository) GetAnomalies(ctx context.Context, from, to string) ([]models.Anomaly, error) {
+	container, err := r.container("anomalies")
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies, err := queryItems[models.Anomaly](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anomalies: %w", err)
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Date != anomalies[j].Date {
+			return anomalies[i].Date < anomalies[j].Date
+		}
+		return anomalies[i].ID < anomalies[j].ID
+	})
+
+	return anomalies, nil
+}
+
+// SaveAlert stores an alert in Cosmos DB
+func (r *CosmosRepository) SaveAlert(ctx context.Context, alert *models.Alert) error {
+	container, err := r.container("alerts")
+	if err != nil {
+		return err
+	}
+
+	if alert.ID == "" {
+		alert.ID = alert.GetID()
+	}
+
+	data, partitionKey, err := r.item(alert, alert.Enterprise, alert.Organization)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+		return fmt.Errorf("failed to upsert alert %s: %w", alert.ID, err)
+	}
+
+	return nil
+}
+
+// GetAlerts returns alerts between two dates from Cosmos DB
+func (r *CosmosRepository) GetAlerts(ctx context.Context, from, to string) ([]models.Alert, error) {
+	container, err := r.container("alerts")
+	if err != nil {
+		return nil, err
+	}
+
+	alerts, err := queryItems[models.Alert](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Date != alerts[j].Date {
+			return alerts[i].Date < alerts[j].Date
+		}
+		return alerts[i].ID < alerts[j].ID
+	})
+
+	return alerts, nil
+}
+
+// SaveIngestionRun stores an ingestion run in Cosmos DB
+func (r *CosmosRepository) SaveIngestionRun(ctx context.Context, run *models.IngestionRun) error {
+	container, err := r.container("ingestion_runs")
+	if err != nil {
+		return err
+	}
+
+	if run.ID == "" {
+		run.ID = run.GetID()
+	}
+
+	data, partitionKey, err := r.item(run, run.Enterprise, run.Organization)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ingestion run: %w", err)
+	}
+
+	if _, err := container.UpsertItem(ctx, partitionKey, data, nil); err != nil {
+		return fmt.Errorf("failed to upsert ingestion run %s: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+// GetIngestionRuns returns ingestion runs between two dates from Cosmos DB
+func (r *CosmosRepository) GetIngestionRuns(ctx context.Context, from, to string) ([]models.IngestionRun, error) {
+	container, err := r.container("ingestion_runs")
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := queryItems[models.IngestionRun](ctx, container,
+		"SELECT * FROM c WHERE c.date >= @from AND c.date <= @to",
+		dateRangeParameters(from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingestion runs: %w", err)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+	return runs, nil
+}
+
 // Close cleans up resources
 func (r *CosmosRepository) Close() error {
 	// Cosmos DB client doesn't require explicit closing
@@ -0,0 +1,264 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/cosmosauth"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestMarshalItem(t *testing.T) {
+	repo := &CosmosRepository{}
+	metric := models.Metrics{ID: "2024-06-01-ORG-acme", Date: "2024-06-01", Organization: "acme", TotalActiveUsers: 4}
+
+	item, err := repo.marshalItem(metric.ID, metric, metric.Enterprise, metric.Organization)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.id != metric.ID || item.partition != "org-acme" {
+		t.Errorf("item id and partition = %q, %q, want %q, org-acme", item.id, item.partition, metric.ID)
+	}
+	if want := azcosmos.NewPartitionKeyString("org-acme"); !reflect.DeepEqual(item.partitionKey, want) {
+		t.Errorf("partition key is not the scope of the document")
+	}
+
+	var properties map[string]any
+	if err := json.Unmarshal(item.data, &properties); err != nil {
+		t.Fatal(err)
+	}
+	if properties["scope"] != "org-acme" || properties["id"] != metric.ID || properties["total_active_users"] != 4.0 {
+		t.Errorf("marshaled item = %s", item.data)
+	}
+
+	item, err = repo.marshalItem("", models.Metrics{Date: "2024-06-01", Enterprise: "big"}, "big", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.partition != "ent-big" {
+		t.Errorf("partition of an enterprise document = %q, want ent-big", item.partition)
+	}
+}
+
+func TestStripItem(t *testing.T) {
+	stored := `{"id":"a","date":"2024-06-01","scope":"org-acme","_rid":"x","_self":"dbs/x","_etag":"\"0\"","_attachments":"attachments/","_ts":1717200000,"future_field":1}`
+	stripped, err := stripItem([]byte(stored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var properties map[string]any
+	if err := json.Unmarshal(stripped, &properties); err != nil {
+		t.Fatal(err)
+	}
+	if len(properties) != 3 || properties["id"] != "a" || properties["future_field"] != 1.0 {
+		t.Errorf("stripItem(%s) = %s, want the document properties only", stored, stripped)
+	}
+
+	for _, value := range []string{`"2024-06-01"`, `42`, `{"id":"a"}`} {
+		if stripped, err := stripItem([]byte(value)); err != nil || string(stripped) != value {
+			t.Errorf("stripItem(%s) = %s, %v, want it unchanged", value, stripped, err)
+		}
+	}
+}
+
+func TestPartitionBatches(t *testing.T) {
+	var items []cosmosItem
+	for i := range 250 {
+		partition := "org-acme"
+		if i%5 == 0 {
+			partition = "org-globex"
+		}
+		items = append(items, cosmosItem{id: fmt.Sprint(i), partition: partition})
+	}
+
+	// Batches follow the order in which partitions first appear
+	batches := partitionBatches(items)
+	if len(batches) != 3 {
+		t.Fatalf("partitionBatches returned %d batches, want 3", len(batches))
+	}
+	for i, want := range []struct {
+		partition string
+		size      int
+		first     string
+	}{{"org-globex", 50, "0"}, {"org-acme", 100, "1"}, {"org-acme", 100, "126"}} {
+		batch := batches[i]
+		if len(batch) != want.size || batch[0].id != want.first {
+			t.Errorf("batch %d holds %d items starting at %s, want %d starting at %s", i, len(batch), batch[0].id, want.size, want.first)
+		}
+		for _, item := range batch {
+			if item.partition != want.partition {
+				t.Errorf("batch %d mixes partitions %s and %s", i, want.partition, item.partition)
+			}
+		}
+	}
+	if batches[0][49].id != "245" {
+		t.Errorf("last item of org-globex = %s, want the items in order", batches[0][49].id)
+	}
+}
+
+func TestPartitionBatchesPayloadLimit(t *testing.T) {
+	// Enterprise metrics with many teams and editors run to hundreds of KB
+	large := make([]byte, 700*1024)
+	var items []cosmosItem
+	for i := range 5 {
+		items = append(items, cosmosItem{id: fmt.Sprint(i), partition: "ent-big", data: large})
+	}
+	items = append(items, cosmosItem{id: "huge", partition: "ent-big", data: make([]byte, 3*1024*1024)})
+	items = append(items, cosmosItem{id: "small", partition: "ent-big", data: []byte(`{}`)})
+
+	var sizes []int
+	for _, batch := range partitionBatches(items) {
+		sizes = append(sizes, len(batch))
+		payload := 0
+		for _, item := range batch {
+			payload += len(item.data) + batchOperationBytes
+		}
+		if payload > maxBatchBytes && len(batch) > 1 {
+			t.Errorf("batch of %d items holds %d bytes, over the %d bytes limit", len(batch), payload, maxBatchBytes)
+		}
+	}
+	if want := []int{2, 2, 1, 1, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("batch sizes = %v, want %v", sizes, want)
+	}
+}
+
+// emulatorKey is the well-known account key of the Cosmos DB emulator
+const emulatorKey = "C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XdIrDw=="
+
+// newEmulatorClient returns a client of the Cosmos DB emulator at
+// COSMOS_EMULATOR_ENDPOINT, such as the one of the cosmos profile of
+// docker-compose.yml, and skips the test when it is not set
+func newEmulatorClient(t *testing.T) *azcosmos.Client {
+	endpoint := os.Getenv("COSMOS_EMULATOR_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("COSMOS_EMULATOR_ENDPOINT is not set")
+	}
+	key := os.Getenv("COSMOS_EMULATOR_KEY")
+	if key == "" {
+		key = emulatorKey
+	}
+
+	client, err := cosmosauth.NewClient(context.Background(), cosmosauth.Config{Endpoint: endpoint, Key: key, Method: cosmosauth.Key}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// newEmulatorDatabase returns the name of a database deleted when the test ends
+func newEmulatorDatabase(t *testing.T, client *azcosmos.Client) string {
+	name := fmt.Sprintf("repositories-test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		if database, err := client.NewDatabase(name); err == nil {
+			database.Delete(context.Background(), nil)
+		}
+	})
+	return name
+}
+
+func TestCosmosEmulator(t *testing.T) {
+	ctx := context.Background()
+	client := newEmulatorClient(t)
+	repo := NewCosmosRepository(client, CosmosOptions{Database: newEmulatorDatabase(t, client), SaveMode: SaveBestEffort}, zap.NewNop())
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A second initialization finds the database and containers
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize of an initialized database: %v", err)
+	}
+
+	updated := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+	var metrics []models.Metrics
+	for i := range 120 {
+		organization := "acme"
+		if i%2 == 1 {
+			organization = "globex"
+		}
+		date := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i/2).Format("2006-01-02")
+		metrics = append(metrics, models.Metrics{Date: date, Organization: organization, TotalActiveUsers: i, LastUpdate: updated})
+	}
+	result, err := repo.SaveMetrics(ctx, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Succeeded) != len(metrics) {
+		t.Fatalf("SaveMetrics saved %d of %d documents", len(result.Succeeded), len(metrics))
+	}
+
+	stored, err := repo.GetMetrics(ctx, "2024-02-01", "2024-02-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 6 {
+		t.Fatalf("GetMetrics of three days returned %d documents, want 6", len(stored))
+	}
+	for _, metric := range stored {
+		if metric.ID != metric.GetID() || !metric.LastUpdate.Equal(updated) {
+			t.Errorf("stored metrics = %+v, want the saved document", metric)
+		}
+		if len(metric.Extensions) != 0 {
+			t.Errorf("stored metrics %s carry item properties in their extensions: %v", metric.ID, metric.Extensions)
+		}
+	}
+
+	seats := &models.CopilotAssignedSeats{Date: "2024-06-02", Organization: "acme", TotalSeats: 3, LastUpdate: updated}
+	if err := repo.SaveSeats(ctx, seats); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := repo.GetLatestSeats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.ID != "2024-06-02-ORG-acme" || latest.TotalSeats != 3 {
+		t.Errorf("GetLatestSeats = %+v, want the saved snapshot", latest)
+	}
+}
+
+func TestCosmosEmulatorPartitionKeyMismatch(t *testing.T) {
+	ctx := context.Background()
+	client := newEmulatorClient(t)
+	name := newEmulatorDatabase(t, client)
+
+	if _, err := client.CreateDatabase(ctx, azcosmos.DatabaseProperties{ID: name}, nil); err != nil {
+		t.Fatal(err)
+	}
+	database, err := client.NewDatabase(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = database.CreateContainer(ctx, azcosmos.ContainerProperties{
+		ID:                     "metrics_history",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/date"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewCosmosRepository(client, CosmosOptions{Database: name, SaveMode: SaveBestEffort}, zap.NewNop())
+	err = repo.Initialize(ctx)
+	if !errors.Is(err, ErrPartitionKeyMismatch) {
+		t.Fatalf("Initialize with a container partitioned by /date: got error %v, want ErrPartitionKeyMismatch", err)
+	}
+
+	// The other containers are created nonetheless
+	container, err := database.NewContainer("usage_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := container.Read(ctx, nil)
+	if err != nil {
+		t.Fatalf("usage_history was not created: %v", err)
+	}
+	if paths := response.ContainerProperties.PartitionKeyDefinition.Paths; len(paths) != 1 || paths[0] != scopePartitionKeyPath {
+		t.Errorf("usage_history is partitioned by %v, want %s", paths, scopePartitionKeyPath)
+	}
+}
@@ -2,25 +2,19 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"slices"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/cardonator/copilot-metrics-dashboard/internal/config"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/cosmosauth"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
 	"go.uber.org/zap"
 )
 
 // CreateRepository creates a repository based on the provided configuration
 func CreateRepository(cfg *config.Config, logger *zap.Logger) (Repository, error) {
-	var repo Repository
-	var err error
-
-	switch cfg.StorageType {
-	case config.StorageSQLite:
-		logger.Info("Creating SQLite repository", zap.String("path", cfg.SQLitePath))
-		repo, err = NewSQLiteRepository(cfg.SQLitePath, logger)
-	case config.StorageCosmos:
-		logger.Info("Creating Cosmos DB repository", zap.String("endpoint", cfg.CosmosDBEndpoint))
-		repo, err = NewCosmosRepository(cfg.CosmosDBEndpoint, cfg.CosmosDBKey, logger)
-	}
-
+	repo, err := NewRepository(cfg, cfg.StorageType, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -35,3 +29,80 @@ func CreateRepository(cfg *config.Config, logger *zap.Logger) (Repository, error
 
 	return repo, nil
 }
+
+// S3ConfigFrom returns the S3 connection settings of the application
+// configuration; the bucket and prefix come from the store location
+func S3ConfigFrom(cfg *config.Config) objectstore.S3Config {
+	return objectstore.S3Config{
+		Endpoint:  cfg.ObjectStoreEndpoint,
+		Region:    cfg.ObjectStoreRegion,
+		AccessKey: cfg.ObjectStoreAccessKey,
+		SecretKey: cfg.ObjectStoreSecretKey,
+		UseSSL:    cfg.ObjectStoreUseSSL,
+	}
+}
+
+// NewRepository creates an uninitialized repository of a storage type. Fan-out
+// storage creates a repository for each of the configured backends.
+func NewRepository(cfg *config.Config, storageType config.StorageType, logger *zap.Logger) (Repository, error) {
+	var repo Repository
+	var err error
+
+	switch storageType {
+	case config.StorageSQLite:
+		logger.Info("Creating SQLite repository", zap.String("path", cfg.SQLitePath))
+		repo, err = NewSQLiteRepository(cfg.SQLitePath, SaveMode(cfg.SaveMode), logger)
+	case config.StorageCosmos:
+		logger.Info("Creating Cosmos DB repository", zap.String("endpoint", cfg.CosmosDBEndpoint))
+		var client *azcosmos.Client
+		client, err = cosmosauth.NewClient(context.Background(), cosmosauth.ConfigFrom(cfg), logger)
+		if err == nil {
+			repo = NewCosmosRepository(client, CosmosOptions{
+				Database:        cfg.CosmosDatabase,
+				ContainerNames:  cfg.CosmosContainerNames,
+				SaveMode:        SaveMode(cfg.SaveMode),
+				BulkConcurrency: cfg.CosmosBulkConcurrency,
+			}, logger)
+		}
+	case config.StorageObjectStore:
+		logger.Info("Creating object store repository", zap.String("location", cfg.ObjectStoreLocation))
+		var store objectstore.Store
+		store, err = objectstore.Open(cfg.ObjectStoreLocation, S3ConfigFrom(cfg), logger)
+		if err == nil {
+			repo = NewObjectStoreRepository(store, cfg.ObjectStoreGzip, SaveMode(cfg.SaveMode), logger)
+		}
+	case config.StorageFanout:
+		if len(cfg.StorageBackends) == 0 {
+			return nil, fmt.Errorf("no fan-out storage backends configured")
+		}
+		if !slices.ContainsFunc(cfg.StorageBackends, func(backend config.StorageBackend) bool { return backend.Required }) {
+			return nil, fmt.Errorf("no required fan-out storage backend configured, reads are served by one")
+		}
+		backends := make([]FanoutBackend, 0, len(cfg.StorageBackends))
+		for _, backend := range cfg.StorageBackends {
+			backendRepo, err := NewRepository(cfg, backend.Type, logger)
+			if err != nil && !backend.Required {
+				logger.Warn("Failed to create best-effort storage backend, skipping it",
+					zap.String("backend", string(backend.Type)), zap.Error(err))
+				continue
+			}
+			if err != nil {
+				for _, created := range backends {
+					created.Repository.Close()
+				}
+				return nil, fmt.Errorf("failed to create %s backend: %w", backend.Type, err)
+			}
+			backends = append(backends, FanoutBackend{
+				Name:       string(backend.Type),
+				Repository: backendRepo,
+				Required:   backend.Required,
+			})
+		}
+		repo = NewFanoutRepository(backends, logger)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
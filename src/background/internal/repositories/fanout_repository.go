@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"go.uber.org/zap"
+)
+
+// FanoutBackend is a repository written to by a FanoutRepository
+type FanoutBackend struct {
+	Name       string
+	Repository Repository
+	// Required backends fail a save when they fail; failures of the other,
+	// best-effort backends are logged
+	Required bool
+}
+
+// FanoutRepository implements Repository by writing every save to several
+// backends, e.g. both storages during a migration or a local SQLite cache
+// next to cloud storage. Reads are served by the first required backend, as
+// best-effort backends may have missed saves.
+type FanoutRepository struct {
+	backends []FanoutBackend
+	logger   *zap.Logger
+}
+
+// NewFanoutRepository creates a repository writing to the given backends
+func NewFanoutRepository(backends []FanoutBackend, logger *zap.Logger) *FanoutRepository {
+	return &FanoutRepository{backends: backends, logger: logger}
+}
+
+// Backends returns the backends of the repository
+func (r *FanoutRepository) Backends() []FanoutBackend {
+	return r.backends
+}
+
+// Initialize initializes every backend. A best-effort backend that fails to
+// initialize is logged and left out.
+func (r *FanoutRepository) Initialize(ctx context.Context) error {
+	backends := r.backends[:0]
+	for _, backend := range r.backends {
+		if err := backend.Repository.Initialize(ctx); err != nil {
+			if backend.Required {
+				return fmt.Errorf("failed to initialize %s backend: %w", backend.Name, err)
+			}
+			r.logger.Warn("Failed to initialize best-effort storage backend, leaving it out",
+				zap.String("backend", backend.Name), zap.Error(err))
+			backend.Repository.Close()
+			continue
+		}
+		backends = append(backends, backend)
+	}
+	r.backends = backends
+
+	if len(r.backends) == 0 {
+		return fmt.Errorf("no storage backend available")
+	}
+	return nil
+}
+
+// fanOut runs a save on every backend. The failures of required backends are
+// returned, those of best-effort backends logged.
+func (r *FanoutRepository) fanOut(kind string, save func(repository Repository) error) error {
+	var errs []error
+	for _, backend := range r.backends {
+		if err := save(backend.Repository); err != nil {
+			if backend.Required {
+				errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+				continue
+			}
+			r.logger.Warn("Failed to save "+kind+" to best-effort storage backend",
+				zap.String("backend", backend.Name), zap.Error(err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fanOutBatch runs a batch save on every backend and merges the results: a
+// document is saved when every required backend saved it, or when any backend
+// did if none is required. Documents that failed on best-effort backends are
+// logged.
+func fanOutBatch[T any](r *FanoutRepository, kind string, documents []T, id func(document T) string,
+	save func(repository Repository) (*BatchResult, error)) (*BatchResult, error) {
+	failed := make(map[string]error)
+	savedByAny := make(map[string]bool)
+	required := false
+	for _, backend := range r.backends {
+		result, err := save(backend.Repository)
+		if result == nil {
+			// The batch failed as a whole
+			result = &BatchResult{}
+			for _, document := range documents {
+				result.fail(id(document), err)
+			}
+		}
+		for _, saved := range result.Succeeded {
+			savedByAny[saved] = true
+		}
+
+		if !backend.Required {
+			if len(result.Failed) > 0 {
+				r.logger.Warn("Failed to save "+kind+" to best-effort storage backend",
+					zap.String("backend", backend.Name),
+					zap.Int("failed", len(result.Failed)),
+					zap.Error(err))
+			}
+			continue
+		}
+		required = true
+		for _, item := range result.Failed {
+			if _, exists := failed[item.ID]; !exists {
+				failed[item.ID] = fmt.Errorf("%s: %w", backend.Name, item.Err)
+			}
+		}
+	}
+
+	result := &BatchResult{}
+	for _, document := range documents {
+		documentID := id(document)
+		if err, exists := failed[documentID]; exists {
+			result.fail(documentID, err)
+		} else if required || savedByAny[documentID] {
+			result.succeed(documentID)
+		} else {
+			result.fail(documentID, fmt.Errorf("not saved to any storage backend"))
+		}
+	}
+	return result, result.Err()
+}
+
+// primary returns the backend serving reads, the first required one
+func (r *FanoutRepository) primary() Repository {
+	for _, backend := range r.backends {
+		if backend.Required {
+			return backend.Repository
+		}
+	}
+	return r.backends[0].Repository
+}
+
+// SaveMetrics stores metrics in every backend
+func (r *FanoutRepository) SaveMetrics(ctx context.Context, metrics []models.Metrics) (*BatchResult, error) {
+	return fanOutBatch(r, "metrics", metrics, func(metric models.Metrics) string {
+		if metric.ID == "" {
+			return metric.GetID()
+		}
+		return metric.ID
+	}, func(repository Repository) (*BatchResult, error) {
+		return repository.SaveMetrics(ctx, metrics)
+	})
+}
+
+// SaveSeats stores seats data in every backend
+func (r *FanoutRepository) SaveSeats(ctx context.Context, seats *models.CopilotAssignedSeats) error {
+	return r.fanOut("seats", func(repository Repository) error {
+		return repository.SaveSeats(ctx, seats)
+	})
+}
+
+// SaveUsage stores usage data in every backend
+func (r *FanoutRepository) SaveUsage(ctx context.Context, usage []models.CopilotUsage) (*BatchResult, error) {
+	return fanOutBatch(r, "usage data", usage, func(entry models.CopilotUsage) string {
+		if entry.ID == "" {
+			return entry.GetID()
+		}
+		return entry.ID
+	}, func(repository Repository) (*BatchResult, error) {
+		return repository.SaveUsage(ctx, usage)
+	})
+}
+
+// GetMetrics returns metrics between two dates from the first backend
+func (r *FanoutRepository) GetMetrics(ctx context.Context, from, to string) ([]models.Metrics, error) {
+	return r.primary().GetMetrics(ctx, from, to)
+}
+
+// GetUsage returns usage data between two days from the first backend
+func (r *FanoutRepository) GetUsage(ctx context.Context, from, to string) ([]models.CopilotUsage, error) {
+	return r.primary().GetUsage(ctx, from, to)
+}
+
+// GetSeatsHistory returns the seats snapshots between two dates from the first backend
+func (r *FanoutRepository) GetSeatsHistory(ctx context.Context, from, to string) ([]models.CopilotAssignedSeats, error) {
+	return r.primary().GetSeatsHistory(ctx, from, to)
+}
+
+// GetLatestSeats returns the most recent seats snapshot from the first backend
+func (r *FanoutRepository) GetLatestSeats(ctx context.Context) (*models.CopilotAssignedSeats, error) {
+	return r.primary().GetLatestSeats(ctx)
+}
+
+// SaveTeamMemberships stores a team memberships snapshot in every backend
+func (r *FanoutRepository) SaveTeamMemberships(ctx context.Context, memberships *models.TeamMemberships) error {
+	return r.fanOut("team memberships", func(repository Repository) error {
+		return repository.SaveTeamMemberships(ctx, memberships)
+	})
+}
+
+// GetTeamMemberships returns the team memberships snapshots between two dates from the first backend
+func (r *FanoutRepository) GetTeamMemberships(ctx context.Context, from, to string) ([]models.TeamMemberships, error) {
+	return r.primary().GetTeamMemberships(ctx, from, to)
+}
+
+// SaveSeatEvents stores seat events in every backend
+func (r *FanoutRepository) SaveSeatEvents(ctx context.Context, events []models.SeatEvent) error {
+	return r.fanOut("seat events", func(repository Repository) error {
+		return repository.SaveSeatEvents(ctx, events)
+	})
+}
+
+// GetSeatEvents returns seat events between two dates from the first backend
+func (r *FanoutRepository) GetSeatEvents(ctx context.Context, from, to string) ([]models.SeatEvent, error) {
+	return r.primary().GetSeatEvents(ctx, from, to)
+}
+
+// SaveSeatActivity stores seat activity in every backend
+func (r *FanoutRepository) SaveSeatActivity(ctx context.Context, activity []models.SeatActivity) error {
+	return r.fanOut("seat activity", func(repository Repository) error {
+		return repository.SaveSeatActivity(ctx, activity)
+	})
+}
+
+// GetSeatActivity returns seat activity between two dates from the first backend
+func (r *FanoutRepository) GetSeatActivity(ctx context.Context, from, to string) ([]models.SeatActivity, error) {
+	return r.primary().GetSeatActivity(ctx, from, to)
+}
+
+// SaveRawResponse archives a raw GitHub API response in every backend
+func (r *FanoutRepository) SaveRawResponse(ctx context.Context, response *models.RawResponse) error {
+	return r.fanOut("raw response", func(repository Repository) error {
+		return repository.SaveRawResponse(ctx, response)
+	})
+}
+
+// GetRawResponses returns archived responses from the first backend
+func (r *FanoutRepository) GetRawResponses(ctx context.Context, kind models.RawResponseKind, from, to string) ([]models.RawResponse, error) {
+	return r.primary().GetRawResponses(ctx, kind, from, to)
+}
+
+// SaveViolations stores data-quality violations in every backend
+func (r *FanoutRepository) SaveViolations(ctx context.Context, violations []models.Violation) error {
+	return r.fanOut("violations", func(repository Repository) error {
+		return repository.SaveViolations(ctx, violations)
+	})
+}
+
+// GetViolations returns violations between two dates from the first backend
+func (r *FanoutRepository) GetViolations(ctx context.Context, from, to string) ([]models.Violation, error) {
+	return r.primary().GetViolations(ctx, from, to)
+}
+
+// SaveQuarantinedDocument stores a quarantined document in every backend
+func (r *FanoutRepository) SaveQuarantinedDocument(ctx context.Context, document *models.QuarantinedDocument) error {
+	return r.fanOut("quarantined document", func(repository Repository) error {
+		return repository.SaveQuarantinedDocument(ctx, document)
+	})
+}
+
+// GetQuarantinedDocuments returns quarantined documents between two dates from the first backend
+func (r *FanoutRepository) GetQuarantinedDocuments(ctx context.Context, from, to string) ([]models.QuarantinedDocument, error) {
+	return r.primary().GetQuarantinedDocuments(ctx, from, to)
+}
+
+// SaveMetricsMarkers stores metrics markers in every backend
+func (r *FanoutRepository) SaveMetricsMarkers(ctx context.Context, markers []models.MetricsMarker) error {
+	return r.fanOut("metrics markers", func(repository Repository) error {
+		return repository.SaveMetricsMarkers(ctx, markers)
+	})
+}
+
+// GetMetricsMarkers returns markers between two dates from the first backend
+func (r *FanoutRepository) GetMetricsMarkers(ctx context.Context, from, to string) ([]models.MetricsMarker, error) {
+	return r.primary().GetMetricsMarkers(ctx, from, to)
+}
+
+// SaveAnomalies stores anomalies in every backend
+func (r *FanoutRepository) SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error {
+	return r.fanOut("anomalies", func(repository Repository) error {
+		return repository.SaveAnomalies(ctx, anomalies)
+	})
+}
+
+// GetAnomalies returns anomalies between two dates from the first backend
+func (r *FanoutRepository) GetAnomalies(ctx context.Context, from, to string) ([]models.Anomaly, error) {
+	return r.primary().GetAnomalies(ctx, from, to)
+}
+
+// SaveAlert stores an alert in every backend
+func (r *FanoutRepository) SaveAlert(ctx context.Context, alert *models.Alert) error {
+	return r.fanOut("alert", func(repository Repository) error {
+		return repository.SaveAlert(ctx, alert)
+	})
+}
+
+// GetAlerts returns alerts between two dates from the first backend
+func (r *FanoutRepository) GetAlerts(ctx context.Context, from, to string) ([]models.Alert, error) {
+	return r.primary().GetAlerts(ctx, from, to)
+}
+
+// SaveIngestionRun stores the record of an ingestion run in every backend
+func (r *FanoutRepository) SaveIngestionRun(ctx context.Context, run *models.IngestionRun) error {
+	return r.fanOut("ingestion run", func(repository Repository) error {
+		return repository.SaveIngestionRun(ctx, run)
+	})
+}
+
+// GetIngestionRuns returns the runs between two dates from the first backend
+func (r *FanoutRepository) GetIngestionRuns(ctx context.Context, from, to string) ([]models.IngestionRun, error) {
+	return r.primary().GetIngestionRuns(ctx, from, to)
+}
+
+// Close closes every backend
+func (r *FanoutRepository) Close() error {
+	var errs []error
+	for _, backend := range r.backends {
+		if err := backend.Repository.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"go.uber.org/zap"
+)
+
+// newTestSQLite returns an initialized SQLite repository in a temporary directory
+func newTestSQLite(t *testing.T) *SQLiteRepository {
+	t.Helper()
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "metrics.db"), SaveBestEffort, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	if err := repo.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestFanoutRepositoryReadsFromRequiredBackend(t *testing.T) {
+	ctx := context.Background()
+	cache, cloud := newTestSQLite(t), newTestSQLite(t)
+	repo := NewFanoutRepository([]FanoutBackend{
+		{Name: "cache", Repository: cache},
+		{Name: "cloud", Repository: cloud, Required: true},
+	}, zap.NewNop())
+
+	metric := models.Metrics{Date: "2024-06-01", Organization: "acme", TotalActiveUsers: 4}
+	if _, err := repo.SaveMetrics(ctx, []models.Metrics{metric}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A save the best-effort cache missed is still read
+	missed := models.Metrics{Date: "2024-06-02", Organization: "acme", TotalActiveUsers: 5}
+	if _, err := cloud.SaveMetrics(ctx, []models.Metrics{missed}); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.GetMetrics(ctx, "2024-06-01", "2024-06-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Errorf("GetMetrics returned %d documents, want the 2 of the required backend", len(stored))
+	}
+}
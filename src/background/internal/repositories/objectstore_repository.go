@@ -0,0 +1,594 @@
+package repositories
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"go.uber.org/zap"
+)
+
+// Document kinds of the object store repository, used as key segments
+const (
+	kindMetrics         = "metrics"
+	kindUsage           = "usage"
+	kindSeats           = "seats"
+	kindTeamMemberships = "team_memberships"
+	kindSeatEvents      = "seat_events"
+	kindSeatActivity    = "seat_activity"
+	kindRawResponses    = "raw_responses"
+	kindViolations      = "validation_violations"
+	kindQuarantined     = "quarantined_documents"
+	kindMetricsMarkers  = "metrics_markers"
+	kindAnomalies       = "anomalies"
+	kindAlerts          = "alerts"
+	kindIngestionRuns   = "ingestion_runs"
+)
+
+// ObjectStoreRepository implements Repository on an object store, such as
+// an S3-compatible bucket, without a database. Every document is stored as a
+// JSON object under scope/kind/date/id.json (id.json.gz when compressed),
+// where scope is ent-<enterprise> or org-<organization>. Raw responses are
+// kept under the raw_responses kind, keyed by fetch date.
+type ObjectStoreRepository struct {
+	store    objectstore.Store
+	gzip     bool
+	saveMode SaveMode
+	logger   *zap.Logger
+}
+
+// NewObjectStoreRepository creates a new object store repository. With gzip
+// enabled, documents are written compressed; both forms are always readable,
+// and saving a document removes its object in the other form, so toggling
+// gzip does not leave two versions of a document behind.
+func NewObjectStoreRepository(store objectstore.Store, gzip bool, saveMode SaveMode, logger *zap.Logger) *ObjectStoreRepository {
+	return &ObjectStoreRepository{
+		store:    store,
+		gzip:     gzip,
+		saveMode: saveMode,
+		logger:   logger,
+	}
+}
+
+// Initialize checks that the object store is reachable
+func (r *ObjectStoreRepository) Initialize(ctx context.Context) error {
+	if _, err := r.store.List(ctx, ""); err != nil {
+		return fmt.Errorf("failed to access object store %s: %w", r.store.Location(), err)
+	}
+	return nil
+}
+
+// scopeSegment returns the key segment of a document scope. Documents
+// collected at enterprise scope carry the enterprise, and may also carry the
+// organization of individual seats.
+func scopeSegment(enterprise, organization string) string {
+	if enterprise != "" {
+		return "ent-" + enterprise
+	} else if organization != "" {
+		return "org-" + organization
+	}
+	return "unscoped"
+}
+
+// documentKey returns the object key of a document
+func (r *ObjectStoreRepository) documentKey(scope, kind, date, id string) string {
+	key := fmt.Sprintf("%s/%s/%s/%s.json", scope, kind, date, id)
+	if r.gzip {
+		key += ".gz"
+	}
+	return key
+}
+
+// putDocument writes a document as JSON, compressed if enabled
+func (r *ObjectStoreRepository) putDocument(ctx context.Context, key string, document interface{}) error {
+	data, err := DataMarshaler(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+
+	contentType := "application/json"
+	if strings.HasSuffix(key, ".gz") {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		if _, err := writer.Write(data); err != nil {
+			return fmt.Errorf("failed to compress %s: %w", key, err)
+		}
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("failed to compress %s: %w", key, err)
+		}
+		data, contentType = buffer.Bytes(), "application/gzip"
+	}
+
+	if err := r.store.Put(ctx, key, data, contentType); err != nil {
+		return err
+	}
+	return r.store.Delete(ctx, otherForm(key))
+}
+
+// otherForm returns the key of a document in the other form, compressed or not
+func otherForm(key string) string {
+	if strings.HasSuffix(key, ".gz") {
+		return strings.TrimSuffix(key, ".gz")
+	}
+	return key + ".gz"
+}
+
+// decodeDocument reads a document written by putDocument
+func decodeDocument(key string, data []byte, document interface{}) error {
+	if strings.HasSuffix(key, ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", key, err)
+		}
+		defer reader.Close()
+
+		data, err = io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", key, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, document); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", key, err)
+	}
+	return nil
+}
+
+// isDocumentKey reports whether a listed key is a document rather than a
+// prefix or an unrelated object
+func isDocumentKey(key string) bool {
+	return strings.HasSuffix(key, ".json") || strings.HasSuffix(key, ".json.gz")
+}
+
+// documentDates returns the date prefixes of a kind between two dates
+// (inclusive) across all scopes, sorted by date
+func (r *ObjectStoreRepository) documentDates(ctx context.Context, kind, from, to string) ([]string, error) {
+	scopes, err := r.store.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	for _, scope := range scopes {
+		if !strings.HasSuffix(scope, "/") {
+			continue
+		}
+
+		dates, err := r.store.List(ctx, scope+kind+"/")
+		if err != nil {
+			return nil, err
+		}
+		for _, datePrefix := range dates {
+			date := strings.TrimSuffix(strings.TrimPrefix(datePrefix, scope+kind+"/"), "/")
+			if !strings.HasSuffix(datePrefix, "/") || date < from || date > to {
+				continue
+			}
+			prefixes = append(prefixes, datePrefix)
+		}
+	}
+
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return datePart(prefixes[i]) < datePart(prefixes[j])
+	})
+	return prefixes, nil
+}
+
+// datePart returns the date segment of a scope/kind/date/ prefix
+func datePart(prefix string) string {
+	segments := strings.Split(strings.TrimSuffix(prefix, "/"), "/")
+	return segments[len(segments)-1]
+}
+
+// readDocuments reads every document of a kind between two dates (inclusive),
+// ordered by date and key. A document stored both compressed and not is read
+// once.
+func readDocuments[T any](ctx context.Context, r *ObjectStoreRepository, kind, from, to string) ([]T, error) {
+	prefixes, err := r.documentDates(ctx, kind, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+
+	var documents []T
+	for _, prefix := range prefixes {
+		keys, err := r.store.List(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+
+		listed := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			listed[key] = true
+		}
+
+		for _, key := range keys {
+			if !isDocumentKey(key) {
+				continue
+			}
+			// A document saved before gzip was toggled may still have an
+			// object in the other form; read the one of the current form
+			if listed[otherForm(key)] && strings.HasSuffix(key, ".gz") != r.gzip {
+				continue
+			}
+
+			data, err := r.store.Get(ctx, key)
+			if errors.Is(err, objectstore.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			var document T
+			if err := decodeDocument(key, data, &document); err != nil {
+				return nil, err
+			}
+			documents = append(documents, document)
+		}
+	}
+
+	return documents, nil
+}
+
+// SaveMetrics stores metrics documents in the object store
+func (r *ObjectStoreRepository) SaveMetrics(ctx context.Context, metrics []models.Metrics) (*BatchResult, error) {
+	return putBatch(ctx, r, "metric", metrics, func(metric *models.Metrics) (string, string) {
+		if metric.ID == "" {
+			metric.ID = metric.GetID()
+		}
+		return metric.ID, r.documentKey(scopeSegment(metric.Enterprise, metric.Organization), kindMetrics, metric.Date, metric.ID)
+	})
+}
+
+// SaveSeats stores a seats snapshot in the object store
+func (r *ObjectStoreRepository) SaveSeats(ctx context.Context, seats *models.CopilotAssignedSeats) error {
+	if seats.ID == "" {
+		seats.ID = seats.GetID()
+	}
+
+	key := r.documentKey(scopeSegment(seats.Enterprise, seats.Organization), kindSeats, seats.Date, seats.ID)
+	if err := r.putDocument(ctx, key, seats); err != nil {
+		return fmt.Errorf("failed to save seats: %w", err)
+	}
+
+	r.logger.Info("Saved seats", zap.String("id", seats.ID), zap.Int("totalSeats", seats.TotalSeats))
+	return nil
+}
+
+// SaveUsage stores usage documents in the object store
+func (r *ObjectStoreRepository) SaveUsage(ctx context.Context, usageData []models.CopilotUsage) (*BatchResult, error) {
+	return putBatch(ctx, r, "usage data", usageData, func(usage *models.CopilotUsage) (string, string) {
+		if usage.ID == "" {
+			usage.ID = usage.GetID()
+		}
+		return usage.ID, r.documentKey(scopeSegment(usage.Enterprise, usage.Organization), kindUsage, usage.Day, usage.ID)
+	})
+}
+
+// putBatch writes a batch of documents under their keys. In best-effort mode
+// the documents that fail are left out. In all-or-nothing mode the first
+// failure stops the batch; object stores cannot roll back the documents
+// written before it, which are reported as saved.
+func putBatch[T any](ctx context.Context, r *ObjectStoreRepository, kind string, documents []T, locate func(document *T) (id, key string)) (*BatchResult, error) {
+	result := &BatchResult{}
+	for i := range documents {
+		id, key := locate(&documents[i])
+		if err := r.putDocument(ctx, key, documents[i]); err != nil {
+			r.logger.Warn("Failed to save "+kind, zap.String("id", id), zap.Error(err))
+			result.fail(id, err)
+			if r.saveMode == SaveAllOrNothing {
+				for j := i + 1; j < len(documents); j++ {
+					id, _ := locate(&documents[j])
+					result.fail(id, ErrBatchAborted)
+				}
+				return result, result.Err()
+			}
+			continue
+		}
+
+		result.succeed(id)
+		r.logger.Info("Saved "+kind, zap.String("id", id), zap.String("key", key))
+	}
+	return result, result.Err()
+}
+
+// GetMetrics returns metrics between two dates from the object store
+func (r *ObjectStoreRepository) GetMetrics(ctx context.Context, from, to string) ([]models.Metrics, error) {
+	return readDocuments[models.Metrics](ctx, r, kindMetrics, from, to)
+}
+
+// GetUsage returns usage data between two days from the object store
+func (r *ObjectStoreRepository) GetUsage(ctx context.Context, from, to string) ([]models.CopilotUsage, error) {
+	return readDocuments[models.CopilotUsage](ctx, r, kindUsage, from, to)
+}
+
+// GetSeatsHistory returns the seats snapshots between two dates from the object store
+func (r *ObjectStoreRepository) GetSeatsHistory(ctx context.Context, from, to string) ([]models.CopilotAssignedSeats, error) {
+	return readDocuments[models.CopilotAssignedSeats](ctx, r, kindSeats, from, to)
+}
+
+// GetLatestSeats returns the most recent seats snapshot from the object store
+func (r *ObjectStoreRepository) GetLatestSeats(ctx context.Context) (*models.CopilotAssignedSeats, error) {
+	prefixes, err := r.documentDates(ctx, kindSeats, "", "9999-12-31")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list seats: %w", err)
+	}
+	if len(prefixes) == 0 {
+		return nil, nil
+	}
+
+	// Only the snapshots of the latest date are candidates
+	latestDate := datePart(prefixes[len(prefixes)-1])
+	snapshots, err := readDocuments[models.CopilotAssignedSeats](ctx, r, kindSeats, latestDate, latestDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	latest := &snapshots[0]
+	for i := range snapshots {
+		if snapshots[i].LastUpdate.After(latest.LastUpdate) {
+			latest = &snapshots[i]
+		}
+	}
+	return latest, nil
+}
+
+// SaveTeamMemberships stores a team memberships snapshot in the object store
+func (r *ObjectStoreRepository) SaveTeamMemberships(ctx context.Context, memberships *models.TeamMemberships) error {
+	if memberships.ID == "" {
+		memberships.ID = memberships.GetID()
+	}
+
+	key := r.documentKey(scopeSegment(memberships.Enterprise, memberships.Organization), kindTeamMemberships, memberships.Date, memberships.ID)
+	if err := r.putDocument(ctx, key, memberships); err != nil {
+		return fmt.Errorf("failed to save team memberships: %w", err)
+	}
+
+	r.logger.Info("Saved team memberships", zap.String("id", memberships.ID), zap.Int("teams", len(memberships.Teams)))
+	return nil
+}
+
+// GetTeamMemberships returns the team memberships snapshots between two dates from the object store
+func (r *ObjectStoreRepository) GetTeamMemberships(ctx context.Context, from, to string) ([]models.TeamMemberships, error) {
+	return readDocuments[models.TeamMemberships](ctx, r, kindTeamMemberships, from, to)
+}
+
+// SaveSeatEvents stores seat events in the object store
+func (r *ObjectStoreRepository) SaveSeatEvents(ctx context.Context, events []models.SeatEvent) error {
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = event.GetID()
+		}
+
+		key := r.documentKey(scopeSegment(event.Enterprise, event.Organization), kindSeatEvents, event.Date, event.ID)
+		if err := r.putDocument(ctx, key, event); err != nil {
+			return fmt.Errorf("failed to save seat event %s: %w", event.ID, err)
+		}
+	}
+
+	r.logger.Info("Saved seat events", zap.Int("count", len(events)))
+	return nil
+}
+
+// GetSeatEvents returns seat events between two dates from the object store
+func (r *ObjectStoreRepository) GetSeatEvents(ctx context.Context, from, to string) ([]models.SeatEvent, error) {
+	return readDocuments[models.SeatEvent](ctx, r, kindSeatEvents, from, to)
+}
+
+// SaveSeatActivity stores seat activity in the object store. Activity that
+// was already recorded is kept with its original observation time.
+func (r *ObjectStoreRepository) SaveSeatActivity(ctx context.Context, activity []models.SeatActivity) error {
+	existing := make(map[string]map[string]bool)
+	saved := 0
+
+	for _, entry := range activity {
+		if entry.ID == "" {
+			entry.ID = entry.GetID()
+		}
+
+		scope := scopeSegment(entry.Enterprise, entry.Organization)
+		prefix := fmt.Sprintf("%s/%s/%s/", scope, kindSeatActivity, entry.Date)
+		if existing[prefix] == nil {
+			keys, err := r.store.List(ctx, prefix)
+			if err != nil {
+				return fmt.Errorf("failed to list seat activity: %w", err)
+			}
+			existing[prefix] = make(map[string]bool, len(keys))
+			for _, key := range keys {
+				existing[prefix][strings.TrimSuffix(strings.TrimSuffix(key, ".gz"), ".json")] = true
+			}
+		}
+		if existing[prefix][prefix+entry.ID] {
+			continue
+		}
+
+		key := r.documentKey(scope, kindSeatActivity, entry.Date, entry.ID)
+		if err := r.putDocument(ctx, key, entry); err != nil {
+			return fmt.Errorf("failed to save seat activity %s: %w", entry.ID, err)
+		}
+		existing[prefix][prefix+entry.ID] = true
+		saved++
+	}
+
+	r.logger.Info("Saved seat activity", zap.Int("count", saved))
+	return nil
+}
+
+// GetSeatActivity returns seat activity between two dates from the object store
+func (r *ObjectStoreRepository) GetSeatActivity(ctx context.Context, from, to string) ([]models.SeatActivity, error) {
+	return readDocuments[models.SeatActivity](ctx, r, kindSeatActivity, from, to)
+}
+
+// SaveRawResponse archives a raw GitHub API response in the object store
+func (r *ObjectStoreRepository) SaveRawResponse(ctx context.Context, response *models.RawResponse) error {
+	if response.ID == "" {
+		response.ID = response.GetID()
+	}
+
+	key := r.documentKey(scopeSegment(response.Enterprise, response.Organization), kindRawResponses, response.Date, response.ID)
+	if err := r.putDocument(ctx, key, response); err != nil {
+		return fmt.Errorf("failed to save raw response: %w", err)
+	}
+	return nil
+}
+
+// GetRawResponses returns the archived responses of a kind between two dates from the object store
+func (r *ObjectStoreRepository) GetRawResponses(ctx context.Context, kind models.RawResponseKind, from, to string) ([]models.RawResponse, error) {
+	archived, err := readDocuments[models.RawResponse](ctx, r, kindRawResponses, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []models.RawResponse
+	for _, response := range archived {
+		if response.Kind == kind {
+			responses = append(responses, response)
+		}
+	}
+
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].FetchedAt.Before(responses[j].FetchedAt)
+	})
+	return responses, nil
+}
+
+// SaveViolations stores validation violations in the object store
+func (r *ObjectStoreRepository) SaveViolations(ctx context.Context, violations []models.Violation) error {
+	for _, violation := range violations {
+		key := r.documentKey(scopeSegment(violation.Enterprise, violation.Organization), kindViolations, violation.Date, violation.ID)
+		if err := r.putDocument(ctx, key, violation); err != nil {
+			return fmt.Errorf("failed to save violation %s: %w", violation.ID, err)
+		}
+	}
+
+	r.logger.Info("Saved validation violations", zap.Int("count", len(violations)))
+	return nil
+}
+
+// GetViolations returns validation violations between two dates from the object store
+func (r *ObjectStoreRepository) GetViolations(ctx context.Context, from, to string) ([]models.Violation, error) {
+	return readDocuments[models.Violation](ctx, r, kindViolations, from, to)
+}
+
+// SaveQuarantinedDocument stores a quarantined document in the object store
+func (r *ObjectStoreRepository) SaveQuarantinedDocument(ctx context.Context, document *models.QuarantinedDocument) error {
+	if document.ID == "" {
+		document.ID = document.GetID()
+	}
+
+	key := r.documentKey(scopeSegment(document.Enterprise, document.Organization), kindQuarantined, document.Date, document.ID)
+	if err := r.putDocument(ctx, key, document); err != nil {
+		return fmt.Errorf("failed to save quarantined document: %w", err)
+	}
+
+	r.logger.Info("Quarantined document", zap.String("id", document.DocumentID), zap.Strings("rules", document.Rules))
+	return nil
+}
+
+// GetQuarantinedDocuments returns quarantined documents between two dates from the object store
+func (r *ObjectStoreRepository) GetQuarantinedDocuments(ctx context.Context, from, to string) ([]models.QuarantinedDocument, error) {
+	return readDocuments[models.QuarantinedDocument](ctx, r, kindQuarantined, from, to)
+}
+
+// SaveMetricsMarkers stores metrics markers in the object store
+func (r *ObjectStoreRepository) SaveMetricsMarkers(ctx context.Context, markers []models.MetricsMarker) error {
+	for _, marker := range markers {
+		if marker.ID == "" {
+			marker.ID = marker.GetID()
+		}
+
+		key := r.documentKey(scopeSegment(marker.Enterprise, marker.Organization), kindMetricsMarkers, marker.Date, marker.ID)
+		if err := r.putDocument(ctx, key, marker); err != nil {
+			return fmt.Errorf("failed to save metrics marker %s: %w", marker.ID, err)
+		}
+	}
+
+	r.logger.Info("Saved metrics markers", zap.Int("count", len(markers)))
+	return nil
+}
+
+// GetMetricsMarkers returns metrics markers between two dates from the object store
+func (r *ObjectStoreRepository) GetMetricsMarkers(ctx context.Context, from, to string) ([]models.MetricsMarker, error) {
+	return readDocuments[models.MetricsMarker](ctx, r, kindMetricsMarkers, from, to)
+}
+
+// SaveAnomalies stores anomalies in the object store
+func (r *ObjectStoreRepository) SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error {
+	for _, anomaly := range anomalies {
+		if anomaly.ID == "" {
+			anomaly.ID = anomaly.GetID()
+		}
+
+		key := r.documentKey(scopeSegment(anomaly.Enterprise, anomaly.Organization), kindAnomalies, anomaly.Date, anomaly.ID)
+		if err := r.putDocument(ctx, key, anomaly); err != nil {
+			return fmt.Errorf("failed to save anomaly %s: %w", anomaly.ID, err)
+		}
+	}
+
+	r.logger.Info("Saved anomalies", zap.Int("count", len(anomalies)))
+	return nil
+}
+
+// GetAnomalies returns anomalies between two dates from the object store
+func (r *ObjectStoreRepository) GetAnomalies(ctx context.Context, from, to string) ([]models.Anomaly, error) {
+	return readDocuments[models.Anomaly](ctx, r, kindAnomalies, from, to)
+}
+
+// SaveAlert stores an alert in the object store
+func (r *ObjectStoreRepository) SaveAlert(ctx context.Context, alert *models.Alert) error {
+	if alert.ID == "" {
+		alert.ID = alert.GetID()
+	}
+
+	key := r.documentKey(scopeSegment(alert.Enterprise, alert.Organization), kindAlerts, alert.Date, alert.ID)
+	if err := r.putDocument(ctx, key, alert); err != nil {
+		return fmt.Errorf("failed to save alert %s: %w", alert.ID, err)
+	}
+	return nil
+}
+
+// GetAlerts returns alerts between two dates from the object store
+func (r *ObjectStoreRepository) GetAlerts(ctx context.Context, from, to string) ([]models.Alert, error) {
+	return readDocuments[models.Alert](ctx, r, kindAlerts, from, to)
+}
+
+// SaveIngestionRun stores an ingestion run in the object store
+func (r *ObjectStoreRepository) SaveIngestionRun(ctx context.Context, run *models.IngestionRun) error {
+	if run.ID == "" {
+		run.ID = run.GetID()
+	}
+
+	key := r.documentKey(scopeSegment(run.Enterprise, run.Organization), kindIngestionRuns, run.Date, run.ID)
+	if err := r.putDocument(ctx, key, run); err != nil {
+		return fmt.Errorf("failed to save ingestion run %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+// GetIngestionRuns returns ingestion runs between two dates from the object store
+func (r *ObjectStoreRepository) GetIngestionRuns(ctx context.Context, from, to string) ([]models.IngestionRun, error) {
+	runs, err := readDocuments[models.IngestionRun](ctx, r, kindIngestionRuns, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+	return runs, nil
+}
+
+// Close releases the repository; object stores hold no open resources
+func (r *ObjectStoreRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore/s3test"
+	"go.uber.org/zap"
+)
+
+// testStores returns the stores the object store repository is tested on: a
+// local directory and a bucket of an in-memory S3 server
+func testStores(t *testing.T) map[string]objectstore.Store {
+	local, err := objectstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := s3test.NewServer("copilot")
+	t.Cleanup(server.Close)
+	s3, err := objectstore.NewS3Store(server.Config("copilot", "metrics"), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]objectstore.Store{"local": local, "s3": s3}
+}
+
+// listKeys returns every object key of a store below a prefix
+func listKeys(t *testing.T, store objectstore.Store, prefix string) []string {
+	t.Helper()
+	entries, err := store.List(context.Background(), prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		if entry[len(entry)-1] == '/' {
+			keys = append(keys, listKeys(t, store, entry)...)
+		} else {
+			keys = append(keys, entry)
+		}
+	}
+	return keys
+}
+
+func TestObjectStoreRepositoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	updated := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := NewObjectStoreRepository(store, false, SaveBestEffort, zap.NewNop())
+			if err := repo.Initialize(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			metrics := []models.Metrics{
+				{Date: "2024-06-01", Organization: "acme", TotalActiveUsers: 10, LastUpdate: updated},
+				{Date: "2024-06-02", Organization: "acme", TotalActiveUsers: 12, LastUpdate: updated},
+				{Date: "2024-06-02", Organization: "acme", Team: "platform", TotalActiveUsers: 5, LastUpdate: updated},
+				{Date: "2024-06-02", Enterprise: "big", TotalActiveUsers: 40, LastUpdate: updated},
+			}
+			result, err := repo.SaveMetrics(ctx, metrics)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Succeeded) != len(metrics) || len(result.Failed) != 0 {
+				t.Fatalf("SaveMetrics saved %d and failed %d documents, want %d saved", len(result.Succeeded), len(result.Failed), len(metrics))
+			}
+
+			seats := &models.CopilotAssignedSeats{Date: "2024-06-02", Organization: "acme", TotalSeats: 3, LastUpdate: updated}
+			if err := repo.SaveSeats(ctx, seats); err != nil {
+				t.Fatal(err)
+			}
+			usage := []models.CopilotUsage{{Day: "2024-06-02", Organization: "acme", TotalActiveUsers: 12, LastUpdate: updated}}
+			if _, err := repo.SaveUsage(ctx, usage); err != nil {
+				t.Fatal(err)
+			}
+
+			// Documents are stored under scope/kind/date/id.json
+			want := []string{
+				"ent-big/metrics/2024-06-02/2024-06-02-ENT-big.json",
+				"org-acme/metrics/2024-06-01/2024-06-01-ORG-acme.json",
+				"org-acme/metrics/2024-06-02/2024-06-02-ORG-acme-platform.json",
+				"org-acme/metrics/2024-06-02/2024-06-02-ORG-acme.json",
+				"org-acme/seats/2024-06-02/2024-06-02-ORG-acme.json",
+				"org-acme/usage/2024-06-02/2024-06-02-ORG-acme.json",
+			}
+			if keys := listKeys(t, store, ""); !slices.Equal(keys, want) {
+				t.Errorf("stored keys = %q, want %q", keys, want)
+			}
+
+			stored, err := repo.GetMetrics(ctx, "2024-06-02", "2024-06-30")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, metric := range stored {
+				ids = append(ids, metric.ID)
+			}
+			slices.Sort(ids)
+			if want := []string{"2024-06-02-ENT-big", "2024-06-02-ORG-acme", "2024-06-02-ORG-acme-platform"}; !slices.Equal(ids, want) {
+				t.Errorf("GetMetrics of 2024-06-02 onwards returned %q, want %q", ids, want)
+			}
+
+			latest, err := repo.GetLatestSeats(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if latest == nil || latest.ID != "2024-06-02-ORG-acme" || latest.TotalSeats != 3 || !latest.LastUpdate.Equal(updated) {
+				t.Errorf("GetLatestSeats = %+v, want the saved snapshot", latest)
+			}
+
+			storedUsage, err := repo.GetUsage(ctx, "2024-06-01", "2024-06-02")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(storedUsage) != 1 || storedUsage[0].TotalActiveUsers != 12 {
+				t.Errorf("GetUsage = %+v, want the saved usage", storedUsage)
+			}
+		})
+	}
+}
+
+func TestObjectStoreRepositoryGzipToggle(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			plain := NewObjectStoreRepository(store, false, SaveBestEffort, zap.NewNop())
+			compressed := NewObjectStoreRepository(store, true, SaveBestEffort, zap.NewNop())
+
+			metric := models.Metrics{Date: "2024-06-01", Organization: "acme", TotalActiveUsers: 1}
+			if _, err := plain.SaveMetrics(ctx, []models.Metrics{metric}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Saving compressed replaces the uncompressed object
+			metric.TotalActiveUsers = 2
+			if _, err := compressed.SaveMetrics(ctx, []models.Metrics{metric}); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"org-acme/metrics/2024-06-01/2024-06-01-ORG-acme.json.gz"}
+			if keys := listKeys(t, store, ""); !slices.Equal(keys, want) {
+				t.Fatalf("stored keys after enabling gzip = %q, want %q", keys, want)
+			}
+			for _, repo := range []*ObjectStoreRepository{plain, compressed} {
+				stored, err := repo.GetMetrics(ctx, "2024-06-01", "2024-06-01")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(stored) != 1 || stored[0].TotalActiveUsers != 2 {
+					t.Errorf("GetMetrics with gzip %v = %+v, want the compressed document", repo.gzip, stored)
+				}
+			}
+
+			// A document left in both forms, as before this cleanup existed, is
+			// read once, in the configured form
+			metric.TotalActiveUsers = 3
+			if err := plain.putDocument(ctx, "org-acme/metrics/2024-06-01/2024-06-01-ORG-acme.json", metric); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Put(ctx, "org-acme/metrics/2024-06-01/2024-06-01-ORG-acme.json.gz", gzipJSON(t, models.Metrics{
+				Date: "2024-06-01", Organization: "acme", TotalActiveUsers: 2,
+			}), "application/gzip"); err != nil {
+				t.Fatal(err)
+			}
+			for _, test := range []struct {
+				repo *ObjectStoreRepository
+				want int
+			}{{plain, 3}, {compressed, 2}} {
+				stored, err := test.repo.GetMetrics(ctx, "2024-06-01", "2024-06-01")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(stored) != 1 || stored[0].TotalActiveUsers != test.want {
+					t.Errorf("GetMetrics with gzip %v of a document in both forms = %+v, want one with %d active users",
+						test.repo.gzip, stored, test.want)
+				}
+			}
+		})
+	}
+}
+
+// gzipJSON marshals and compresses a document as a compressed repository stores it
+func gzipJSON(t *testing.T, document any) []byte {
+	t.Helper()
+	store, err := objectstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewObjectStoreRepository(store, true, SaveBestEffort, zap.NewNop())
+	if err := repo.putDocument(context.Background(), "document.json.gz", document); err != nil {
+		t.Fatal(err)
+	}
+	data, err := store.Get(context.Background(), "document.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
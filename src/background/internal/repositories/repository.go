@@ -12,14 +12,91 @@ type Repository interface {
 	// Initialize prepares the repository for use
 	Initialize(ctx context.Context) error
 
-	// SaveMetrics stores metrics data
-	SaveMetrics(ctx context.Context, metrics []models.Metrics) error
+	// SaveMetrics stores metrics data. The result lists the documents saved
+	// and those that failed; the error is a *BatchError if any failed.
+	SaveMetrics(ctx context.Context, metrics []models.Metrics) (*BatchResult, error)
 
 	// SaveSeats stores seats data
 	SaveSeats(ctx context.Context, seats *models.CopilotAssignedSeats) error
 
-	// SaveUsage stores usage data
-	SaveUsage(ctx context.Context, usage []models.CopilotUsage) error
+	// SaveUsage stores usage data, reporting the documents saved and those
+	// that failed like SaveMetrics
+	SaveUsage(ctx context.Context, usage []models.CopilotUsage) (*BatchResult, error)
+
+	// GetMetrics returns metrics between two dates (YYYY-MM-DD, inclusive)
+	GetMetrics(ctx context.Context, from, to string) ([]models.Metrics, error)
+
+	// GetUsage returns usage data between two days (YYYY-MM-DD, inclusive)
+	GetUsage(ctx context.Context, from, to string) ([]models.CopilotUsage, error)
+
+	// GetSeatsHistory returns the seats snapshots between two dates (YYYY-MM-DD, inclusive)
+	GetSeatsHistory(ctx context.Context, from, to string) ([]models.CopilotAssignedSeats, error)
+
+	// GetLatestSeats returns the most recent seats snapshot, or nil if none is stored
+	GetLatestSeats(ctx context.Context) (*models.CopilotAssignedSeats, error)
+
+	// SaveTeamMemberships stores a team memberships snapshot
+	SaveTeamMemberships(ctx context.Context, memberships *models.TeamMemberships) error
+
+	// GetTeamMemberships returns the team memberships snapshots between two dates (YYYY-MM-DD, inclusive)
+	GetTeamMemberships(ctx context.Context, from, to string) ([]models.TeamMemberships, error)
+
+	// SaveSeatEvents stores seat assignment change events
+	SaveSeatEvents(ctx context.Context, events []models.SeatEvent) error
+
+	// GetSeatEvents returns seat events between two dates (YYYY-MM-DD, inclusive)
+	GetSeatEvents(ctx context.Context, from, to string) ([]models.SeatEvent, error)
+
+	// SaveSeatActivity stores observed seat activity changes
+	SaveSeatActivity(ctx context.Context, activity []models.SeatActivity) error
+
+	// GetSeatActivity returns seat activity between two dates (YYYY-MM-DD, inclusive)
+	GetSeatActivity(ctx context.Context, from, to string) ([]models.SeatActivity, error)
+
+	// SaveRawResponse archives a raw GitHub API response
+	SaveRawResponse(ctx context.Context, response *models.RawResponse) error
+
+	// GetRawResponses returns the archived responses of a kind fetched between
+	// two dates (YYYY-MM-DD, inclusive), ordered by fetch time
+	GetRawResponses(ctx context.Context, kind models.RawResponseKind, from, to string) ([]models.RawResponse, error)
+
+	// SaveViolations stores the data-quality violations of a validation run
+	SaveViolations(ctx context.Context, violations []models.Violation) error
+
+	// GetViolations returns violations for documents dated between two dates (YYYY-MM-DD, inclusive)
+	GetViolations(ctx context.Context, from, to string) ([]models.Violation, error)
+
+	// SaveQuarantinedDocument stores a document held back by validation
+	SaveQuarantinedDocument(ctx context.Context, document *models.QuarantinedDocument) error
+
+	// GetQuarantinedDocuments returns quarantined documents dated between two dates (YYYY-MM-DD, inclusive)
+	GetQuarantinedDocuments(ctx context.Context, from, to string) ([]models.QuarantinedDocument, error)
+
+	// SaveMetricsMarkers stores markers for team metrics missing from the API
+	SaveMetricsMarkers(ctx context.Context, markers []models.MetricsMarker) error
+
+	// GetMetricsMarkers returns markers dated between two dates (YYYY-MM-DD, inclusive)
+	GetMetricsMarkers(ctx context.Context, from, to string) ([]models.MetricsMarker, error)
+
+	// SaveAnomalies stores detected anomalies, replacing earlier detections of
+	// the same series and day
+	SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error
+
+	// GetAnomalies returns anomalies dated between two dates (YYYY-MM-DD, inclusive)
+	GetAnomalies(ctx context.Context, from, to string) ([]models.Anomaly, error)
+
+	// SaveAlert stores an alert, replacing the stored state of the same episode
+	SaveAlert(ctx context.Context, alert *models.Alert) error
+
+	// GetAlerts returns alerts that started firing between two dates (YYYY-MM-DD, inclusive)
+	GetAlerts(ctx context.Context, from, to string) ([]models.Alert, error)
+
+	// SaveIngestionRun stores the record of an ingestion run
+	SaveIngestionRun(ctx context.Context, run *models.IngestionRun) error
+
+	// GetIngestionRuns returns the runs started between two dates (YYYY-MM-DD,
+	// inclusive), ordered by start time
+	GetIngestionRuns(ctx context.Context, from, to string) ([]models.IngestionRun, error)
 
 	// Close closes the repository
 	Close() error
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
 	"go.uber.org/zap"
@@ -34,17 +35,118 @@ CREATE TABLE IF NOT EXISTS usage_history (
     data TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+
+CREATE TABLE IF NOT EXISTS team_memberships_history (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS seat_events (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    type TEXT NOT NULL,
+    login TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_seat_events_date ON seat_events (date);
+
+CREATE TABLE IF NOT EXISTS seat_activity (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    login TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_seat_activity_date ON seat_activity (date);
+
+CREATE TABLE IF NOT EXISTS raw_responses (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    fetched_at TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_raw_responses_kind_date ON raw_responses (kind, date);
+
+CREATE TABLE IF NOT EXISTS validation_violations (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    run_id TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_validation_violations_date ON validation_violations (date);
+
+CREATE TABLE IF NOT EXISTS quarantined_documents (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS metrics_markers (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    status TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS anomalies (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomalies_date ON anomalies (date);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    status TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_date ON alerts (date);
+
+CREATE TABLE IF NOT EXISTS ingestion_runs (
+    id TEXT PRIMARY KEY,
+    date TEXT NOT NULL,
+    job TEXT NOT NULL,
+    status TEXT NOT NULL,
+    started_at TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingestion_runs_date ON ingestion_runs (date);
 `
 
 // SQLiteRepository implements Repository using SQLite
 type SQLiteRepository struct {
-	db     *sql.DB
-	logger *zap.Logger
-	path   string
+	db       *sql.DB
+	logger   *zap.Logger
+	path     string
+	saveMode SaveMode
 }
 
 // NewSQLiteRepository creates a new SQLite repository
-func NewSQLiteRepository(dbPath string, logger *zap.Logger) (*SQLiteRepository, error) {
+func NewSQLiteRepository(dbPath string, saveMode SaveMode, logger *zap.Logger) (*SQLiteRepository, error) {
 	if dbPath == "" {
 		return nil, fmt.Errorf("SQLite database path is not specified")
 	}
@@ -55,8 +157,10 @@ func NewSQLiteRepository(dbPath string, logger *zap.Logger) (*SQLiteRepository,
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Use a connection string with the necessary pragmas for modernc.org/sqlite
-	connString := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)", dbPath)
+	// Use a connection string with the necessary pragmas for modernc.org/sqlite.
+	// Jobs run concurrently, so writers wait for each other instead of failing
+	// with SQLITE_BUSY.
+	connString := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dbPath)
 	db, err := sql.Open("sqlite", connString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -74,9 +178,10 @@ func NewSQLiteRepository(dbPath string, logger *zap.Logger) (*SQLiteRepository,
 	}
 
 	return &SQLiteRepository{
-		db:     db,
-		logger: logger,
-		path:   dbPath,
+		db:       db,
+		logger:   logger,
+		path:     dbPath,
+		saveMode: saveMode,
 	}, nil
 }
 
@@ -90,7 +195,197 @@ func (r *SQLiteRepository) Initialize(ctx context.Context) error {
 }
 
 // SaveMetrics stores metrics data in SQLite
-func (r *SQLiteRepository) SaveMetrics(ctx context.Context, metrics []models.Metrics) error {
+func (r *SQLiteRepository) SaveMetrics(ctx context.Context, metrics []models.Metrics) (*BatchResult, error) {
+	return saveBatchSQL(ctx, r, "metric", `
+		INSERT OR REPLACE INTO metrics_history (id, date, data)
+		VALUES (?, ?, ?)
+	`, metrics, func(metric *models.Metrics) (string, string) {
+		// Set ID if not already set
+		if metric.ID == "" {
+			metric.ID = metric.GetID()
+		}
+		return metric.ID, metric.Date
+	})
+}
+
+// SaveSeats stores seats data in SQLite
+func (r *SQLiteRepository) SaveSeats(ctx context.Context, seats *models.CopilotAssignedSeats) error {
+	if seats.ID == "" {
+		seats.ID = seats.GetID()
+	}
+
+	data, err := json.Marshal(seats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal seats: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO seats_history (id, date, data)
+		VALUES (?, ?, ?)
+	`, seats.ID, seats.Date, string(data))
+
+	if err != nil {
+		return fmt.Errorf("failed to insert seats: %w", err)
+	}
+
+	r.logger.Info("Saved seats", zap.String("id", seats.ID), zap.Int("totalSeats", seats.TotalSeats))
+	return nil
+}
+
+// SaveUsage stores usage data in SQLite
+func (r *SQLiteRepository) SaveUsage(ctx context.Context, usageData []models.CopilotUsage) (*BatchResult, error) {
+	return saveBatchSQL(ctx, r, "usage", `
+		INSERT OR REPLACE INTO usage_history (id, day, data)
+		VALUES (?, ?, ?)
+	`, usageData, func(usage *models.CopilotUsage) (string, string) {
+		if usage.ID == "" {
+			usage.ID = usage.GetID()
+		}
+		return usage.ID, usage.Day
+	})
+}
+
+// saveBatchSQL writes a batch of documents in one transaction, with a
+// statement taking the ID, the date and the JSON document. In best-effort mode
+// the documents that fail are left out; in all-or-nothing mode the first
+// failure rolls the transaction back.
+func saveBatchSQL[T any](ctx context.Context, r *SQLiteRepository, kind, query string, documents []T, key func(document *T) (id, date string)) (*BatchResult, error) {
+	result := &BatchResult{}
+	ids := make([]string, len(documents))
+	for i := range documents {
+		ids[i], _ = key(&documents[i])
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		result.abort(ids, err)
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		result.abort(ids, err)
+		return result, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := range documents {
+		id, date := key(&documents[i])
+
+		data, err := json.Marshal(documents[i])
+		if err == nil {
+			_, err = stmt.ExecContext(ctx, id, date, string(data))
+		}
+		if err != nil {
+			r.logger.Warn("Failed to save "+kind, zap.String("id", id), zap.Error(err))
+			result.fail(id, err)
+			if r.saveMode == SaveAllOrNothing {
+				result.abort(ids, ErrBatchAborted)
+				return result, result.Err()
+			}
+			continue
+		}
+
+		result.succeed(id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		result.abort(ids, err)
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, id := range result.Succeeded {
+		r.logger.Info("Saved "+kind, zap.String("id", id))
+	}
+	return result, result.Err()
+}
+
+// GetMetrics returns metrics between two dates from SQLite
+func (r *SQLiteRepository) GetMetrics(ctx context.Context, from, to string) ([]models.Metrics, error) {
+	return queryJSON[models.Metrics](ctx, r.db, `
+		SELECT data FROM metrics_history
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+	`, from, to)
+}
+
+// GetUsage returns usage data between two days from SQLite
+func (r *SQLiteRepository) GetUsage(ctx context.Context, from, to string) ([]models.CopilotUsage, error) {
+	return queryJSON[models.CopilotUsage](ctx, r.db, `
+		SELECT data FROM usage_history
+		WHERE day >= ? AND day <= ?
+		ORDER BY day, id
+	`, from, to)
+}
+
+// GetSeatsHistory returns the seats snapshots between two dates from SQLite
+func (r *SQLiteRepository) GetSeatsHistory(ctx context.Context, from, to string) ([]models.CopilotAssignedSeats, error) {
+	return queryJSON[models.CopilotAssignedSeats](ctx, r.db, `
+		SELECT data FROM seats_history
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+	`, from, to)
+}
+
+// GetLatestSeats returns the most recent seats snapshot from SQLite
+func (r *SQLiteRepository) GetLatestSeats(ctx context.Context) (*models.CopilotAssignedSeats, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT data FROM seats_history
+		ORDER BY date DESC, created_at DESC
+		LIMIT 1
+	`).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest seats: %w", err)
+	}
+
+	var seats models.CopilotAssignedSeats
+	if err := json.Unmarshal([]byte(data), &seats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal seats: %w", err)
+	}
+
+	return &seats, nil
+}
+
+// SaveTeamMemberships stores a team memberships snapshot in SQLite
+func (r *SQLiteRepository) SaveTeamMemberships(ctx context.Context, memberships *models.TeamMemberships) error {
+	if memberships.ID == "" {
+		memberships.ID = memberships.GetID()
+	}
+
+	data, err := json.Marshal(memberships)
+	if err != nil {
+		return fmt.Errorf("failed to marshal team memberships: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO team_memberships_history (id, date, data)
+		VALUES (?, ?, ?)
+	`, memberships.ID, memberships.Date, string(data))
+
+	if err != nil {
+		return fmt.Errorf("failed to insert team memberships: %w", err)
+	}
+
+	r.logger.Info("Saved team memberships", zap.String("id", memberships.ID), zap.Int("teams", len(memberships.Teams)))
+	return nil
+}
+
+// GetTeamMemberships returns the team memberships snapshots between two dates from SQLite
+func (r *SQLiteRepository) GetTeamMemberships(ctx context.Context, from, to string) ([]models.TeamMemberships, error) {
+	return queryJSON[models.TeamMemberships](ctx, r.db, `
+		SELECT data FROM team_memberships_history
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+	`, from, to)
+}
+
+// SaveSeatEvents stores seat events in SQLite
+func (r *SQLiteRepository) SaveSeatEvents(ctx context.Context, events []models.SeatEvent) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -98,68 +393,236 @@ func (r *SQLiteRepository) SaveMetrics(ctx context.Context, metrics []models.Met
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO metrics_history (id, date, data)
-		VALUES (?, ?, ?)
+		INSERT OR REPLACE INTO seat_events (id, date, type, login, data)
+		VALUES (?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	for _, metric := range metrics {
-		// Set ID if not already set
-		if metric.ID == "" {
-			metric.ID = metric.GetID()
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = event.GetID()
 		}
 
-		data, err := json.Marshal(metric)
+		data, err := json.Marshal(event)
 		if err != nil {
-			r.logger.Warn("Failed to marshal metric", zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal seat event: %w", err)
+		}
+
+		if _, err := stmt.ExecContext(ctx, event.ID, event.Date, string(event.Type), event.Login, string(data)); err != nil {
+			return fmt.Errorf("failed to insert seat event %s: %w", event.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.logger.Info("Saved seat events", zap.Int("count", len(events)))
+	return nil
+}
+
+// GetSeatEvents returns seat events between two dates from SQLite
+func (r *SQLiteRepository) GetSeatEvents(ctx context.Context, from, to string) ([]models.SeatEvent, error) {
+	return queryJSON[models.SeatEvent](ctx, r.db, `
+		SELECT data FROM seat_events
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, login, type
+	`, from, to)
+}
+
+// SaveSeatActivity stores seat activity in SQLite. Activity that was already
+// recorded is kept with its original observation time.
+func (r *SQLiteRepository) SaveSeatActivity(ctx context.Context, activity []models.SeatActivity) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR IGNORE INTO seat_activity (id, date, login, data)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range activity {
+		if entry.ID == "" {
+			entry.ID = entry.GetID()
 		}
 
-		_, err = stmt.ExecContext(ctx, metric.ID, metric.Date, string(data))
+		data, err := json.Marshal(entry)
 		if err != nil {
-			r.logger.Warn("Failed to insert metric", zap.String("id", metric.ID), zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal seat activity: %w", err)
 		}
 
-		r.logger.Info("Saved metric", zap.String("id", metric.ID))
+		if _, err := stmt.ExecContext(ctx, entry.ID, entry.Date, entry.Login, string(data)); err != nil {
+			return fmt.Errorf("failed to insert seat activity %s: %w", entry.ID, err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	r.logger.Info("Saved seat activity", zap.Int("count", len(activity)))
 	return nil
 }
 
-// SaveSeats stores seats data in SQLite
-func (r *SQLiteRepository) SaveSeats(ctx context.Context, seats *models.CopilotAssignedSeats) error {
-	if seats.ID == "" {
-		seats.ID = seats.GetID()
+// GetSeatActivity returns seat activity between two dates from SQLite
+func (r *SQLiteRepository) GetSeatActivity(ctx context.Context, from, to string) ([]models.SeatActivity, error) {
+	return queryJSON[models.SeatActivity](ctx, r.db, `
+		SELECT data FROM seat_activity
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, login
+	`, from, to)
+}
+
+// SaveRawResponse archives a raw GitHub API response in SQLite
+func (r *SQLiteRepository) SaveRawResponse(ctx context.Context, response *models.RawResponse) error {
+	if response.ID == "" {
+		response.ID = response.GetID()
 	}
 
-	data, err := json.Marshal(seats)
+	data, err := json.Marshal(response)
 	if err != nil {
-		return fmt.Errorf("failed to marshal seats: %w", err)
+		return fmt.Errorf("failed to marshal raw response: %w", err)
 	}
 
 	_, err = r.db.ExecContext(ctx, `
-		INSERT OR REPLACE INTO seats_history (id, date, data)
-		VALUES (?, ?, ?)
-	`, seats.ID, seats.Date, string(data))
+		INSERT OR REPLACE INTO raw_responses (id, date, kind, fetched_at, data)
+		VALUES (?, ?, ?, ?, ?)
+	`, response.ID, response.Date, string(response.Kind), response.FetchedAt.UTC().Format(time.RFC3339Nano), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to insert raw response: %w", err)
+	}
+
+	return nil
+}
 
+// GetRawResponses returns the archived responses of a kind between two dates from SQLite
+func (r *SQLiteRepository) GetRawResponses(ctx context.Context, kind models.RawResponseKind, from, to string) ([]models.RawResponse, error) {
+	return queryJSON[models.RawResponse](ctx, r.db, `
+		SELECT data FROM raw_responses
+		WHERE kind = ? AND date >= ? AND date <= ?
+		ORDER BY fetched_at, id
+	`, string(kind), from, to)
+}
+
+// SaveViolations stores validation violations in SQLite
+func (r *SQLiteRepository) SaveViolations(ctx context.Context, violations []models.Violation) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to insert seats: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	r.logger.Info("Saved seats", zap.String("id", seats.ID), zap.Int("totalSeats", seats.TotalSeats))
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO validation_violations (id, date, run_id, rule, severity, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, violation := range violations {
+		data, err := json.Marshal(violation)
+		if err != nil {
+			return fmt.Errorf("failed to marshal violation: %w", err)
+		}
+
+		if _, err := stmt.ExecContext(ctx, violation.ID, violation.Date, violation.RunID, violation.Rule, violation.Severity, string(data)); err != nil {
+			return fmt.Errorf("failed to insert violation %s: %w", violation.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.logger.Info("Saved validation violations", zap.Int("count", len(violations)))
 	return nil
 }
 
-// SaveUsage stores usage data in SQLite
-func (r *SQLiteRepository) SaveUsage(ctx context.Context, usageData []models.CopilotUsage) error {
+// GetViolations returns validation violations between two dates from SQLite
+func (r *SQLiteRepository) GetViolations(ctx context.Context, from, to string) ([]models.Violation, error) {
+	return queryJSON[models.Violation](ctx, r.db, `
+		SELECT data FROM validation_violations
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, run_id, id
+	`, from, to)
+}
+
+// SaveQuarantinedDocument stores a quarantined document in SQLite
+func (r *SQLiteRepository) SaveQuarantinedDocument(ctx context.Context, document *models.QuarantinedDocument) error {
+	if document.ID == "" {
+		document.ID = document.GetID()
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantined document: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO quarantined_documents (id, date, kind, data)
+		VALUES (?, ?, ?, ?)
+	`, document.ID, document.Date, document.DocumentKind, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to insert quarantined document: %w", err)
+	}
+
+	r.logger.Info("Quarantined document", zap.String("id", document.DocumentID), zap.Strings("rules", document.Rules))
+	return nil
+}
+
+// GetQuarantinedDocuments returns quarantined documents between two dates from SQLite
+func (r *SQLiteRepository) GetQuarantinedDocuments(ctx context.Context, from, to string) ([]models.QuarantinedDocument, error) {
+	return queryJSON[models.QuarantinedDocument](ctx, r.db, `
+		SELECT data FROM quarantined_documents
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+	`, from, to)
+}
+
+// queryJSON runs a query selecting a single JSON data column and unmarshals every row
+func queryJSON[T any](ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		var item T
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal row: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return items, nil
+}
+
+// SaveMetricsMarkers stores metrics markers in SQLite, replacing the markers
+// of the same team and day
+func (r *SQLiteRepository) SaveMetricsMarkers(ctx context.Context, markers []models.MetricsMarker) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -167,41 +630,157 @@ func (r *SQLiteRepository) SaveUsage(ctx context.Context, usageData []models.Cop
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO usage_history (id, day, data)
-		VALUES (?, ?, ?)
+		INSERT OR REPLACE INTO metrics_markers (id, date, status, data)
+		VALUES (?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	for _, usage := range usageData {
-		if usage.ID == "" {
-			usage.ID = usage.GetID()
+	for _, marker := range markers {
+		if marker.ID == "" {
+			marker.ID = marker.GetID()
 		}
 
-		data, err := json.Marshal(usage)
+		data, err := json.Marshal(marker)
 		if err != nil {
-			r.logger.Warn("Failed to marshal usage", zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal metrics marker: %w", err)
+		}
+
+		if _, err := stmt.ExecContext(ctx, marker.ID, marker.Date, string(marker.Status), string(data)); err != nil {
+			return fmt.Errorf("failed to insert metrics marker %s: %w", marker.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.logger.Info("Saved metrics markers", zap.Int("count", len(markers)))
+	return nil
+}
+
+// GetMetricsMarkers returns metrics markers between two dates from SQLite
+func (r *SQLiteRepository) GetMetricsMarkers(ctx context.Context, from, to string) ([]models.MetricsMarker, error) {
+	return queryJSON[models.MetricsMarker](ctx, r.db, `
+		SELECT data FROM metrics_markers
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+	`, from, to)
+}
+
+// SaveAnomalies stores anomalies in SQLite
+func (r *SQLiteRepository) SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO anomalies (id, date, severity, data)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, anomaly := range anomalies {
+		if anomaly.ID == "" {
+			anomaly.ID = anomaly.GetID()
 		}
 
-		_, err = stmt.ExecContext(ctx, usage.ID, usage.Day, string(data))
+		data, err := json.Marshal(anomaly)
 		if err != nil {
-			r.logger.Warn("Failed to insert usage", zap.String("id", usage.ID), zap.Error(err))
-			continue
+			return fmt.Errorf("failed to marshal anomaly: %w", err)
 		}
 
-		r.logger.Info("Saved usage data", zap.String("id", usage.ID), zap.String("day", usage.Day))
+		if _, err := stmt.ExecContext(ctx, anomaly.ID, anomaly.Date, anomaly.Severity, string(data)); err != nil {
+			return fmt.Errorf("failed to insert anomaly %s: %w", anomaly.ID, err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	r.logger.Info("Saved anomalies", zap.Int("count", len(anomalies)))
 	return nil
 }
 
+// GetAnomalies returns anomalies between two dates from SQLite
+func (r *SQLiteRepository) GetAnomalies(ctx context.Context, from, to string) ([]models.Anomaly, error) {
+	return queryJSON[models.Anomaly](ctx, r.db, `
+		SELECT data FROM anomalies
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+	`, from, to)
+}
+
+// SaveAlert stores an alert in SQLite
+func (r *SQLiteRepository) SaveAlert(ctx context.Context, alert *models.Alert) error {
+	if alert.ID == "" {
+		alert.ID = alert.GetID()
+	}
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO alerts (id, date, rule, status, data)
+		VALUES (?, ?, ?, ?, ?)
+	`, alert.ID, alert.Date, alert.Rule, string(alert.Status), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to insert alert %s: %w", alert.ID, err)
+	}
+
+	return nil
+}
+
+// GetAlerts returns alerts between two dates from SQLite
+func (r *SQLiteRepository) GetAlerts(ctx context.Context, from, to string) ([]models.Alert, error) {
+	return queryJSON[models.Alert](ctx, r.db, `
+		SELECT data FROM alerts
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+	`, from, to)
+}
+
+// SaveIngestionRun stores an ingestion run in SQLite
+func (r *SQLiteRepository) SaveIngestionRun(ctx context.Context, run *models.IngestionRun) error {
+	if run.ID == "" {
+		run.ID = run.GetID()
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ingestion run: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO ingestion_runs (id, date, job, status, started_at, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, run.ID, run.Date, run.Job, string(run.Status), run.StartedAt.UTC().Format(time.RFC3339Nano), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to insert ingestion run %s: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+// GetIngestionRuns returns ingestion runs between two dates from SQLite
+func (r *SQLiteRepository) GetIngestionRuns(ctx context.Context, from, to string) ([]models.IngestionRun, error) {
+	return queryJSON[models.IngestionRun](ctx, r.db, `
+		SELECT data FROM ingestion_runs
+		WHERE date >= ? AND date <= ?
+		ORDER BY started_at, id
+	`, from, to)
+}
+
 // Close cleans up resources
 func (r *SQLiteRepository) Close() error {
 	return r.db.Close()
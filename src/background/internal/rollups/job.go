@@ -0,0 +1,80 @@
+package rollups
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// LookbackDays is how far back the scheduled job rolls up periods, so that
+// metrics GitHub revises or that are ingested late reach the rollups of the
+// previous week and month
+const LookbackDays = 35
+
+// Job writes the rollups of the stored metrics to an object store
+type Job struct {
+	repo   repositories.Repository
+	store  objectstore.Store
+	logger *zap.Logger
+}
+
+// NewJob creates a rollups job
+func NewJob(repo repositories.Repository, store objectstore.Store, logger *zap.Logger) *Job {
+	return &Job{repo: repo, store: store, logger: logger}
+}
+
+// Run rolls up the weeks and months of the last LookbackDays days
+func (j *Job) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -(LookbackDays - 1)).Format(dateLayout)
+	_, err := j.Write(ctx, Granularities, from, now.Format(dateLayout), now)
+	return err
+}
+
+// Write rolls up the periods overlapping two dates (YYYY-MM-DD, inclusive)
+// and stores each one under its Key, replacing an earlier rollup of the
+// period. Periods without metrics are not stored. It returns the number of
+// rollups written.
+func (j *Job) Write(ctx context.Context, granularities []Granularity, from, to string, now time.Time) (int, error) {
+	written := 0
+	for _, granularity := range granularities {
+		periods, err := Periods(granularity, from, to)
+		if err != nil {
+			return written, err
+		}
+		for _, period := range periods {
+			if err := ctx.Err(); err != nil {
+				return written, err
+			}
+
+			rollup, err := Build(ctx, j.repo, period, now)
+			if err != nil {
+				return written, fmt.Errorf("failed to roll up %s %s: %w", granularity, period.Label, err)
+			}
+			if len(rollup.Entries) == 0 {
+				continue
+			}
+
+			data, err := json.MarshalIndent(rollup, "", "  ")
+			if err != nil {
+				return written, err
+			}
+			if err := j.store.Put(ctx, period.Key(), data, "application/json"); err != nil {
+				return written, fmt.Errorf("failed to store the rollup of %s %s: %w", granularity, period.Label, err)
+			}
+			written++
+			j.logger.Debug("Wrote rollup",
+				zap.String("granularity", string(granularity)),
+				zap.String("period", period.Label),
+				zap.Int("entries", len(rollup.Entries)))
+		}
+	}
+
+	j.logger.Info("Rolled up metrics", zap.String("from", from), zap.String("to", to), zap.Int("rollups", written))
+	return written, nil
+}
@@ -0,0 +1,224 @@
+package rollups
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+)
+
+// dateLayout is the layout of the stored document dates
+const dateLayout = "2006-01-02"
+
+// Granularity defines the length of a rollup period
+type Granularity string
+
+const (
+	GranularityWeek  Granularity = "week"  // Monday to Sunday, labeled by ISO week (e.g. 2024-W23)
+	GranularityMonth Granularity = "month" // Calendar month, labeled YYYY-MM
+)
+
+// Granularities lists the granularities rolled up by the scheduled job
+var Granularities = []Granularity{GranularityWeek, GranularityMonth}
+
+// ParseGranularity converts a granularity name to a Granularity
+func ParseGranularity(name string) (Granularity, error) {
+	switch Granularity(name) {
+	case GranularityWeek, GranularityMonth:
+		return Granularity(name), nil
+	default:
+		return "", fmt.Errorf("unsupported rollup granularity: %s", name)
+	}
+}
+
+// Period is a week or month of daily metrics
+type Period struct {
+	Granularity Granularity
+	Label       string // 2024-W23 or 2024-06
+	From        string // First day (YYYY-MM-DD)
+	To          string // Last day (YYYY-MM-DD)
+}
+
+// PeriodOf returns the period of a granularity containing a day
+func PeriodOf(granularity Granularity, day time.Time) Period {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	var start, end time.Time
+	var label string
+	if granularity == GranularityMonth {
+		start = day.AddDate(0, 0, 1-day.Day())
+		end = start.AddDate(0, 1, -1)
+		label = start.Format("2006-01")
+	} else {
+		start = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		end = start.AddDate(0, 0, 6)
+		year, week := start.ISOWeek()
+		label = fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return Period{Granularity: granularity, Label: label, From: start.Format(dateLayout), To: end.Format(dateLayout)}
+}
+
+// Periods returns the periods of a granularity overlapping two dates
+// (YYYY-MM-DD, inclusive), in order
+func Periods(granularity Granularity, from, to string) ([]Period, error) {
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+	}
+
+	var periods []Period
+	for day := fromDate; !day.After(toDate); {
+		period := PeriodOf(granularity, day)
+		periods = append(periods, period)
+		end, _ := time.Parse(dateLayout, period.To)
+		day = end.AddDate(0, 0, 1)
+	}
+	return periods, nil
+}
+
+// Key returns the object key of the rollup of a period
+func (p Period) Key() string {
+	return fmt.Sprintf("rollups/%s/%s.json", p.Granularity, p.Label)
+}
+
+// Rollup aggregates the daily metrics of a period per scope and team
+type Rollup struct {
+	Granularity Granularity `json:"granularity"`
+	Period      string      `json:"period"`
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Entries     []Entry     `json:"entries"`
+}
+
+// Entry is the rollup of an organization/enterprise or a team
+type Entry struct {
+	Enterprise       string   `json:"enterprise,omitempty"`
+	Organization     string   `json:"organization,omitempty"`
+	Team             string   `json:"team,omitempty"`
+	Days             int      `json:"days"`         // Days with stored metrics
+	ActiveUsers      float64  `json:"active_users"` // Average daily active users
+	PeakActiveUsers  int      `json:"peak_active_users"`
+	EngagedUsers     float64  `json:"engaged_users"` // Average daily engaged users
+	PeakEngagedUsers int      `json:"peak_engaged_users"`
+	Suggestions      int      `json:"suggestions"`
+	Acceptances      int      `json:"acceptances"`
+	AcceptanceRate   *float64 `json:"acceptance_rate,omitempty"`
+	LinesSuggested   int      `json:"lines_suggested"`
+	LinesAccepted    int      `json:"lines_accepted"`
+	Chats            int      `json:"chats"` // IDE and github.com chats
+}
+
+// entryKey identifies the scope and team of an entry
+type entryKey struct {
+	enterprise   string
+	organization string
+	team         string
+}
+
+// entryAccumulator sums the daily metrics of an entry
+type entryAccumulator struct {
+	entry        Entry
+	dates        map[string]bool
+	activeUsers  int
+	engagedUsers int
+}
+
+// add accounts a day of metrics
+func (a *entryAccumulator) add(m *models.Metrics) {
+	a.dates[m.Date] = true
+	a.activeUsers += m.TotalActiveUsers
+	a.engagedUsers += m.TotalEngagedUsers
+	a.entry.PeakActiveUsers = max(a.entry.PeakActiveUsers, m.TotalActiveUsers)
+	a.entry.PeakEngagedUsers = max(a.entry.PeakEngagedUsers, m.TotalEngagedUsers)
+
+	if m.CopilotIdeCodeCompletions != nil {
+		for _, editor := range m.CopilotIdeCodeCompletions.Editors {
+			for _, model := range editor.Models {
+				for _, language := range model.Languages {
+					a.entry.Suggestions += language.TotalCodeSuggestions
+					a.entry.Acceptances += language.TotalCodeAcceptances
+					a.entry.LinesSuggested += language.TotalCodeLinesSuggested
+					a.entry.LinesAccepted += language.TotalCodeLinesAccepted
+				}
+			}
+		}
+	}
+	if m.IdeChat != nil {
+		for _, editor := range m.IdeChat.Editors {
+			for _, model := range editor.Models {
+				a.entry.Chats += model.TotalChats
+			}
+		}
+	}
+	if m.DotComChat != nil {
+		for _, model := range m.DotComChat.Models {
+			a.entry.Chats += model.TotalChats
+		}
+	}
+}
+
+// result computes the entry from the accumulated totals
+func (a *entryAccumulator) result() Entry {
+	entry := a.entry
+	entry.Days = len(a.dates)
+	entry.ActiveUsers = float64(a.activeUsers) / float64(entry.Days)
+	entry.EngagedUsers = float64(a.engagedUsers) / float64(entry.Days)
+	if entry.Suggestions > 0 {
+		rate := float64(entry.Acceptances) / float64(entry.Suggestions)
+		entry.AcceptanceRate = &rate
+	}
+	return entry
+}
+
+// Build rolls up the stored metrics of a period
+func Build(ctx context.Context, repo repositories.Repository, period Period, now time.Time) (*Rollup, error) {
+	metrics, err := repo.GetMetrics(ctx, period.From, period.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics: %w", err)
+	}
+
+	entries := make(map[entryKey]*entryAccumulator)
+	for i := range metrics {
+		m := &metrics[i]
+		key := entryKey{enterprise: m.Enterprise, organization: m.Organization, team: m.Team}
+		acc, exists := entries[key]
+		if !exists {
+			acc = &entryAccumulator{
+				entry: Entry{Enterprise: m.Enterprise, Organization: m.Organization, Team: m.Team},
+				dates: make(map[string]bool),
+			}
+			entries[key] = acc
+		}
+		acc.add(m)
+	}
+
+	rollup := &Rollup{
+		Granularity: period.Granularity,
+		Period:      period.Label,
+		From:        period.From,
+		To:          period.To,
+		GeneratedAt: now.UTC(),
+		Entries:     []Entry{},
+	}
+	for _, acc := range entries {
+		rollup.Entries = append(rollup.Entries, acc.result())
+	}
+	sort.Slice(rollup.Entries, func(i, j int) bool {
+		a, b := rollup.Entries[i], rollup.Entries[j]
+		if a.Enterprise != b.Enterprise {
+			return a.Enterprise < b.Enterprise
+		}
+		if a.Organization != b.Organization {
+			return a.Organization < b.Organization
+		}
+		return a.Team < b.Team
+	})
+	return rollup, nil
+}
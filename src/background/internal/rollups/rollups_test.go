@@ -0,0 +1,122 @@
+package rollups
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/objectstore"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/repositories"
+	"go.uber.org/zap"
+)
+
+func TestPeriods(t *testing.T) {
+	for _, test := range []struct {
+		granularity Granularity
+		from, to    string
+		want        []Period
+	}{
+		{GranularityWeek, "2024-06-01", "2024-06-04", []Period{
+			{GranularityWeek, "2024-W22", "2024-05-27", "2024-06-02"},
+			{GranularityWeek, "2024-W23", "2024-06-03", "2024-06-09"},
+		}},
+		// The ISO week of a week spanning two years is labeled by its Thursday
+		{GranularityWeek, "2025-01-01", "2025-01-01", []Period{
+			{GranularityWeek, "2025-W01", "2024-12-30", "2025-01-05"},
+		}},
+		{GranularityMonth, "2024-01-31", "2024-03-01", []Period{
+			{GranularityMonth, "2024-01", "2024-01-01", "2024-01-31"},
+			{GranularityMonth, "2024-02", "2024-02-01", "2024-02-29"},
+			{GranularityMonth, "2024-03", "2024-03-01", "2024-03-31"},
+		}},
+	} {
+		periods, err := Periods(test.granularity, test.from, test.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(periods, test.want) {
+			t.Errorf("Periods(%s, %s, %s) = %v, want %v", test.granularity, test.from, test.to, periods, test.want)
+		}
+	}
+}
+
+func TestJobWrite(t *testing.T) {
+	ctx := context.Background()
+	repo, err := repositories.NewSQLiteRepository(filepath.Join(t.TempDir(), "metrics.db"), repositories.SaveBestEffort, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	completions := func(suggestions, acceptances int) *models.IdeCodeCompletions {
+		return &models.IdeCodeCompletions{Editors: []models.IdeCodeCompletionEditor{{
+			Name: "vscode",
+			Models: []models.IdeCodeCompletionModel{{
+				Name:      "default",
+				Languages: []models.IdeCodeCompletionModelLanguage{{Name: "go", TotalCodeSuggestions: suggestions, TotalCodeAcceptances: acceptances}},
+			}},
+		}}}
+	}
+	metrics := []models.Metrics{
+		{Date: "2024-06-03", Organization: "acme", TotalActiveUsers: 4, TotalEngagedUsers: 2, CopilotIdeCodeCompletions: completions(10, 2)},
+		{Date: "2024-06-04", Organization: "acme", TotalActiveUsers: 6, TotalEngagedUsers: 5, CopilotIdeCodeCompletions: completions(30, 8)},
+		{Date: "2024-06-04", Organization: "acme", Team: "platform", TotalActiveUsers: 2, TotalEngagedUsers: 1},
+		{Date: "2024-06-10", Organization: "acme", TotalActiveUsers: 8, TotalEngagedUsers: 8},
+	}
+	if _, err := repo.SaveMetrics(ctx, metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := objectstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := NewJob(repo, store, zap.NewNop())
+	now := time.Date(2024, 6, 17, 7, 0, 0, 0, time.UTC)
+	written, err := job.Write(ctx, Granularities, "2024-06-03", "2024-06-16", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Weeks 23 and 24 and June; week 24 holds a single day of metrics
+	if written != 3 {
+		t.Errorf("Write wrote %d rollups, want 3", written)
+	}
+
+	data, err := store.Get(ctx, "rollups/week/2024-W23.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rollup Rollup
+	if err := json.Unmarshal(data, &rollup); err != nil {
+		t.Fatal(err)
+	}
+	if rollup.From != "2024-06-03" || rollup.To != "2024-06-09" || !rollup.GeneratedAt.Equal(now) || len(rollup.Entries) != 2 {
+		t.Fatalf("rollup of 2024-W23 = %+v", rollup)
+	}
+	organization, team := rollup.Entries[0], rollup.Entries[1]
+	if organization.Team != "" || organization.Days != 2 || organization.ActiveUsers != 5 || organization.PeakEngagedUsers != 5 ||
+		organization.Suggestions != 40 || organization.Acceptances != 10 || *organization.AcceptanceRate != 0.25 {
+		t.Errorf("organization entry = %+v", organization)
+	}
+	if team.Team != "platform" || team.Days != 1 || team.AcceptanceRate != nil {
+		t.Errorf("team entry = %+v", team)
+	}
+
+	data, err = store.Get(ctx, "rollups/month/2024-06.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &rollup); err != nil {
+		t.Fatal(err)
+	}
+	if entry := rollup.Entries[0]; entry.Days != 3 || entry.ActiveUsers != 6 || entry.PeakActiveUsers != 8 {
+		t.Errorf("organization entry of June = %+v", entry)
+	}
+}
@@ -1,16 +1,24 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
 	"go.uber.org/zap"
 )
 
+// ResponseArchive stores raw API responses for audit and reprocessing
+type ResponseArchive interface {
+	SaveRawResponse(ctx context.Context, response *models.RawResponse) error
+}
+
 // GitHubClient is a client for the GitHub API
 type GitHubClient struct {
 	client     *http.Client
@@ -18,6 +26,10 @@ type GitHubClient struct {
 	token      string
 	apiVersion string
 	logger     *zap.Logger
+	archive    ResponseArchive
+
+	mu    sync.Mutex
+	calls map[models.RawResponseKind]int64 // Requests sent, by endpoint family
 }
 
 // NewGitHubClient creates a new GitHub API client
@@ -35,6 +47,67 @@ func NewGitHubClient(baseURL, token, apiVersion string, logger *zap.Logger) *Git
 		token:      token,
 		apiVersion: apiVersion,
 		logger:     logger,
+		calls:      make(map[models.RawResponseKind]int64),
+	}
+}
+
+// SetResponseArchive makes the client archive every response body it receives
+func (g *GitHubClient) SetResponseArchive(archive ResponseArchive) {
+	g.archive = archive
+}
+
+// do sends a request and reads the response body, archiving the response
+// under the kind and scope of source. The returned response body is closed.
+func (g *GitHubClient) do(req *http.Request, source models.RawResponse) (*http.Response, []byte, error) {
+	g.mu.Lock()
+	g.calls[source.Kind]++
+	g.mu.Unlock()
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	source.Method = req.Method
+	source.URL = req.URL.String()
+	source.StatusCode = resp.StatusCode
+	source.APIVersion = g.apiVersion
+	source.FetchedAt = time.Now().UTC()
+	source.Body = string(body)
+	g.archiveResponse(&source)
+
+	return resp, body, nil
+}
+
+// APICalls returns the number of requests sent to endpoints of the given kinds
+// since the client was created
+func (g *GitHubClient) APICalls(kinds ...models.RawResponseKind) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var total int64
+	for _, kind := range kinds {
+		total += g.calls[kind]
+	}
+	return total
+}
+
+// archiveResponse saves a raw response to the archive, if one is set.
+// Archiving is best effort and never fails the fetch.
+func (g *GitHubClient) archiveResponse(response *models.RawResponse) {
+	if g.archive == nil {
+		return
+	}
+
+	response.Date = response.FetchedAt.Format("2006-01-02")
+	if err := g.archive.SaveRawResponse(context.Background(), response); err != nil {
+		g.logger.Warn("Failed to archive raw response", zap.String("url", response.URL), zap.Error(err))
 	}
 }
 
@@ -91,3 +164,55 @@ func (g *GitHubClient) createRequest(method, path string, body io.Reader) (*http
 
 	return req, nil
 }
+
+// nextPagePath returns the path of the next page from a Link header, or an
+// empty string when there are no more pages
+func (g *GitHubClient) nextPagePath(linkHeader string) string {
+	nextLink := GetNextPageURL(linkHeader)
+	if nextLink == "" || !strings.HasPrefix(nextLink, "http") {
+		return nextLink
+	}
+
+	if strings.HasPrefix(nextLink, g.baseURL) {
+		return nextLink[len(g.baseURL):]
+	}
+
+	g.logger.Warn("Next page URL doesn't match base URL",
+		zap.String("nextLink", nextLink),
+		zap.String("baseURL", g.baseURL))
+	// Try to extract the path anyway by finding the path after hostname
+	parts := strings.SplitN(nextLink, "/", 4)
+	if len(parts) >= 4 {
+		return "/" + parts[3]
+	}
+	return ""
+}
+
+// getAllPages fetches every page of a paginated GET endpoint and passes each
+// response body to handle; every page is archived under source
+func (g *GitHubClient) getAllPages(path string, source models.RawResponse, handle func(body []byte) error) error {
+	for path != "" {
+		req, err := g.createRequest("GET", path, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, body, err := g.do(req, source)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", path, err)
+		}
+
+		if resp.StatusCode != 200 {
+			return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, body)
+		}
+
+		if err := handle(body); err != nil {
+			return err
+		}
+
+		path = g.nextPagePath(resp.Header.Get("Link"))
+		g.logger.Debug("Pagination", zap.String("nextPath", path))
+	}
+
+	return nil
+}
@@ -2,8 +2,9 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"sync"
 	"time"
 
 	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
@@ -11,20 +12,35 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrTeamNotFound is returned when the metrics of a team are requested for a
+// team the API does not know. Days a known team falls below the privacy
+// threshold are omitted from its response instead.
+var ErrTeamNotFound = errors.New("team not found")
+
 // CopilotMetricsClient handles fetching Copilot metrics from GitHub API
 type CopilotMetricsClient struct {
 	githubClient *GitHubClient
 	logger       *zap.Logger
+
+	// seenExtensionPaths holds the unknown field paths already reported
+	seenExtensionPaths map[string]bool
+	mu                 sync.Mutex
 }
 
 // NewCopilotMetricsClient creates a new Copilot metrics client
 func NewCopilotMetricsClient(githubClient *GitHubClient, logger *zap.Logger) *CopilotMetricsClient {
 	return &CopilotMetricsClient{
-		githubClient: githubClient,
-		logger:       logger,
+		githubClient:       githubClient,
+		logger:             logger,
+		seenExtensionPaths: make(map[string]bool),
 	}
 }
 
+// APICalls returns the number of metrics requests sent to the GitHub API
+func (c *CopilotMetricsClient) APICalls() int64 {
+	return c.githubClient.APICalls(models.RawResponseMetrics)
+}
+
 // GetCopilotMetricsForEnterprise fetches Copilot metrics for an enterprise
 func (c *CopilotMetricsClient) GetCopilotMetricsForEnterprise(enterprise, team string) ([]models.Metrics, error) {
 	var requestURI string
@@ -34,44 +50,11 @@ func (c *CopilotMetricsClient) GetCopilotMetricsForEnterprise(enterprise, team s
 		requestURI = fmt.Sprintf("/enterprises/%s/team/%s/copilot/metrics", enterprise, team)
 	}
 
-	req, err := c.githubClient.createRequest("GET", requestURI, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.githubClient.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch metrics: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 404 {
-		c.logger.Warn("Team not found", zap.String("team", team))
-		return []models.Metrics{}, nil
-	}
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var metrics []models.Metrics
-	if err := json.Unmarshal(body, &metrics); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal metrics: %w", err)
-	}
-
-	// Add metadata
-	for i := range metrics {
-		metrics[i].Enterprise = enterprise
-		metrics[i].Team = team
-		metrics[i].LastUpdate = time.Now().UTC()
-	}
-
-	return metrics, nil
+	return c.getMetrics(requestURI, models.RawResponse{
+		Kind:       models.RawResponseMetrics,
+		Enterprise: enterprise,
+		Team:       team,
+	})
 }
 
 // GetCopilotMetricsForOrganization fetches Copilot metrics for an organization
@@ -83,19 +66,30 @@ func (c *CopilotMetricsClient) GetCopilotMetricsForOrganization(organization, te
 		requestURI = fmt.Sprintf("/orgs/%s/team/%s/copilot/metrics", organization, team)
 	}
 
+	return c.getMetrics(requestURI, models.RawResponse{
+		Kind:         models.RawResponseMetrics,
+		Organization: organization,
+		Team:         team,
+	})
+}
+
+// getMetrics fetches a metrics endpoint, archiving the response under source
+func (c *CopilotMetricsClient) getMetrics(requestURI string, source models.RawResponse) ([]models.Metrics, error) {
 	req, err := c.githubClient.createRequest("GET", requestURI, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.githubClient.client.Do(req)
+	resp, body, err := c.githubClient.do(req, source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch metrics: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 {
-		c.logger.Warn("Team not found", zap.String("team", team))
+		if source.Team != "" {
+			return nil, fmt.Errorf("%w: %s", ErrTeamNotFound, source.Team)
+		}
+		c.logger.Warn("Metrics not found", zap.String("uri", requestURI))
 		return []models.Metrics{}, nil
 	}
 
@@ -103,44 +97,87 @@ func (c *CopilotMetricsClient) GetCopilotMetricsForOrganization(organization, te
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	source.Body = string(body)
+	source.FetchedAt = time.Now().UTC()
+	metrics, err := ParseMetricsResponse(&source)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
+	c.reportSchemaDrift(metrics)
+	return metrics, nil
+}
+
+// reportSchemaDrift logs a warning listing the fields of the metrics that the
+// models do not map and that were not reported before. The fields are kept in
+// the extensions of the models, but should be added to the models.
+func (c *CopilotMetricsClient) reportSchemaDrift(metrics []models.Metrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var newPaths []string
+	for i := range metrics {
+		for _, path := range models.ExtensionPaths(&metrics[i]) {
+			if !c.seenExtensionPaths[path] {
+				c.seenExtensionPaths[path] = true
+				newPaths = append(newPaths, path)
+			}
+		}
+	}
+
+	if len(newPaths) > 0 {
+		c.logger.Warn("Schema drift: metrics response contains fields not mapped by the models",
+			zap.Strings("paths", newPaths))
+	}
+}
+
+// ParseMetricsResponse transforms a metrics response body into metrics,
+// taking the scope and team from the response and the last update from its
+// fetch time. Fields the models do not map are kept in their extensions. It
+// is used both for live fetches and to reprocess archived responses.
+func ParseMetricsResponse(response *models.RawResponse) ([]models.Metrics, error) {
 	var metrics []models.Metrics
-	if err := json.Unmarshal(body, &metrics); err != nil {
+	if err := json.Unmarshal([]byte(response.Body), &metrics); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal metrics: %w", err)
 	}
 
 	// Add metadata
 	for i := range metrics {
-		metrics[i].Organization = organization
-		metrics[i].Team = team
-		metrics[i].LastUpdate = time.Now().UTC()
+		metrics[i].Enterprise = response.Enterprise
+		metrics[i].Organization = response.Organization
+		metrics[i].Team = response.Team
+		metrics[i].LastUpdate = response.FetchedAt
 	}
 
 	return metrics, nil
 }
 
-// LoadTestMetrics loads test metrics from a file
+// LoadTestMetrics loads test metrics from a file. The test data is archived
+// like a live response so that reprocessing can be tried in test mode.
 func (c *CopilotMetricsClient) LoadTestMetrics(team string) ([]models.Metrics, error) {
 	data, err := loadTestData("metrics.json")
 	if err != nil {
 		return nil, err
 	}
 
-	var metrics []models.Metrics
-	if err := json.Unmarshal(data, &metrics); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal test metrics data: %w", err)
+	response := &models.RawResponse{
+		Kind:         models.RawResponseMetrics,
+		Method:       "GET",
+		URL:          "testdata/metrics.json",
+		StatusCode:   200,
+		APIVersion:   c.githubClient.apiVersion,
+		FetchedAt:    time.Now().UTC(),
+		Organization: "test",
+		Team:         team,
+		Body:         string(data),
 	}
+	c.githubClient.archiveResponse(response)
 
-	// Add metadata
-	for i := range metrics {
-		metrics[i].Organization = "test"
-		metrics[i].Team = team
-		metrics[i].LastUpdate = time.Now().UTC()
+	metrics, err := ParseMetricsResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal test metrics data: %w", err)
 	}
+	c.reportSchemaDrift(metrics)
 
 	return metrics, nil
 }
@@ -3,7 +3,6 @@ package services
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"strings"
 	"time"
 
@@ -25,9 +24,15 @@ func NewCopilotSeatsClient(githubClient *GitHubClient, logger *zap.Logger) *Copi
 	}
 }
 
+// APICalls returns the number of seats requests sent to the GitHub API
+func (c *CopilotSeatsClient) APICalls() int64 {
+	return c.githubClient.APICalls(models.RawResponseSeats)
+}
+
 // GetEnterpriseAssignedSeats fetches Copilot seats for an enterprise
 func (c *CopilotSeatsClient) GetEnterpriseAssignedSeats(enterprise string) (*models.CopilotAssignedSeats, error) {
 	path := fmt.Sprintf("/enterprises/%s/copilot/billing/seats", enterprise)
+	source := models.RawResponse{Kind: models.RawResponseSeats, Enterprise: enterprise}
 	allSeats := []models.Seat{}
 
 	for path != "" {
@@ -36,23 +41,15 @@ func (c *CopilotSeatsClient) GetEnterpriseAssignedSeats(enterprise string) (*mod
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
-		resp, err := c.githubClient.client.Do(req)
+		resp, body, err := c.githubClient.do(req, source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch seats: %w", err)
 		}
 
 		if resp.StatusCode != 200 {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
 			return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, body)
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", err)
-		}
-
 		var data models.CopilotAssignedSeats
 		if err := json.Unmarshal(body, &data); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal seats: %w", err)
@@ -108,6 +105,7 @@ func (c *CopilotSeatsClient) GetEnterpriseAssignedSeats(enterprise string) (*mod
 // GetOrganizationAssignedSeats fetches Copilot seats for an organization
 func (c *CopilotSeatsClient) GetOrganizationAssignedSeats(organization string) (*models.CopilotAssignedSeats, error) {
 	path := fmt.Sprintf("/orgs/%s/copilot/billing/seats", organization)
+	source := models.RawResponse{Kind: models.RawResponseSeats, Organization: organization}
 	allSeats := []models.Seat{}
 
 	for path != "" {
@@ -116,23 +114,15 @@ func (c *CopilotSeatsClient) GetOrganizationAssignedSeats(organization string) (
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
-		resp, err := c.githubClient.client.Do(req)
+		resp, body, err := c.githubClient.do(req, source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch seats: %w", err)
 		}
 
 		if resp.StatusCode != 200 {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
 			return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, body)
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", err)
-		}
-
 		var data models.CopilotAssignedSeats
 		if err := json.Unmarshal(body, &data); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal seats: %w", err)
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+	"go.uber.org/zap"
+)
+
+// TeamsClient handles fetching team memberships from GitHub API
+type TeamsClient struct {
+	githubClient *GitHubClient
+	logger       *zap.Logger
+}
+
+// NewTeamsClient creates a new teams client
+func NewTeamsClient(githubClient *GitHubClient, logger *zap.Logger) *TeamsClient {
+	return &TeamsClient{
+		githubClient: githubClient,
+		logger:       logger,
+	}
+}
+
+// APICalls returns the number of teams and team members requests sent to the
+// GitHub API
+func (c *TeamsClient) APICalls() int64 {
+	return c.githubClient.APICalls(models.RawResponseTeams, models.RawResponseTeamMembers)
+}
+
+// GetOrganizationTeamMemberships fetches the members of every team of an organization
+func (c *TeamsClient) GetOrganizationTeamMemberships(organization string) (*models.TeamMemberships, error) {
+	source := models.RawResponse{Organization: organization}
+	teams, err := c.listTeams(fmt.Sprintf("/orgs/%s/teams?per_page=100", organization), source)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range teams {
+		source.Team = teams[i].Slug
+		members, err := c.listMembers(fmt.Sprintf("/orgs/%s/teams/%s/members?per_page=100", organization, teams[i].Slug), source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch members of team %s: %w", teams[i].Slug, err)
+		}
+		teams[i].Members = members
+	}
+
+	now := time.Now().UTC()
+	return &models.TeamMemberships{
+		Date:         now.Format("2006-01-02"),
+		Organization: organization,
+		Teams:        teams,
+		LastUpdate:   now,
+	}, nil
+}
+
+// GetEnterpriseTeamMemberships fetches the members of every enterprise team
+func (c *TeamsClient) GetEnterpriseTeamMemberships(enterprise string) (*models.TeamMemberships, error) {
+	source := models.RawResponse{Enterprise: enterprise}
+	teams, err := c.listTeams(fmt.Sprintf("/enterprises/%s/teams?per_page=100", enterprise), source)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range teams {
+		source.Team = teams[i].Slug
+		members, err := c.listMembers(fmt.Sprintf("/enterprises/%s/teams/%s/memberships?per_page=100", enterprise, teams[i].Slug), source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch members of team %s: %w", teams[i].Slug, err)
+		}
+		teams[i].Members = members
+	}
+
+	now := time.Now().UTC()
+	return &models.TeamMemberships{
+		Date:       now.Format("2006-01-02"),
+		Enterprise: enterprise,
+		Teams:      teams,
+		LastUpdate: now,
+	}, nil
+}
+
+// listTeams fetches all teams from a paginated teams endpoint
+func (c *TeamsClient) listTeams(path string, source models.RawResponse) ([]models.TeamMembers, error) {
+	teams := []models.TeamMembers{}
+	source.Kind = models.RawResponseTeams
+	err := c.githubClient.getAllPages(path, source, func(body []byte) error {
+		var page []models.Team
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to unmarshal teams: %w", err)
+		}
+		for _, team := range page {
+			teams = append(teams, models.TeamMembers{ID: team.ID, Slug: team.Slug, Name: team.Name})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch teams: %w", err)
+	}
+
+	c.logger.Debug("Fetched teams", zap.Int("count", len(teams)))
+	return teams, nil
+}
+
+// listMembers fetches the logins of all members from a paginated members endpoint
+func (c *TeamsClient) listMembers(path string, source models.RawResponse) ([]string, error) {
+	members := []string{}
+	source.Kind = models.RawResponseTeamMembers
+	err := c.githubClient.getAllPages(path, source, func(body []byte) error {
+		var page []models.User
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to unmarshal members: %w", err)
+		}
+		for _, user := range page {
+			members = append(members, user.Login)
+		}
+		return nil
+	})
+	return members, err
+}
+
+// LoadTestTeamMemberships loads test team memberships from a file
+func (c *TeamsClient) LoadTestTeamMemberships(isEnterprise bool) (*models.TeamMemberships, error) {
+	data, err := loadTestData("teams.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var memberships models.TeamMemberships
+	if err := json.Unmarshal(data, &memberships); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal test teams data: %w", err)
+	}
+
+	now := time.Now().UTC()
+	memberships.LastUpdate = now
+	memberships.Date = now.Format("2006-01-02")
+
+	if isEnterprise {
+		memberships.Enterprise = "test-enterprise"
+	} else {
+		memberships.Organization = "test-organization"
+	}
+
+	return &memberships, nil
+}
@@ -0,0 +1,309 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/analysis"
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// Severity defines how serious a rule violation is
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// ParseSeverity converts a severity name to a Severity
+func ParseSeverity(name string) (Severity, error) {
+	switch Severity(strings.ToLower(name)) {
+	case SeverityInfo, SeverityWarning, SeverityError:
+		return Severity(strings.ToLower(name)), nil
+	default:
+		return "", fmt.Errorf("unsupported severity: %s", name)
+	}
+}
+
+// rank orders severities from least to most serious
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether the severity is at least as serious as other
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() >= other.rank()
+}
+
+// Document kinds that rules apply to
+const (
+	KindMetrics = "metrics"
+	KindSeats   = "seats"
+)
+
+// MetricsBatch gives metrics rules access to the other documents of the
+// fetched batch, e.g. to compare a team with its organization
+type MetricsBatch struct {
+	scopeTotals map[string]*models.Metrics
+}
+
+// newMetricsBatch indexes the organization/enterprise level metrics of a batch
+func newMetricsBatch(metrics []models.Metrics) *MetricsBatch {
+	batch := &MetricsBatch{scopeTotals: make(map[string]*models.Metrics)}
+	for i := range metrics {
+		if metrics[i].Team == "" {
+			batch.scopeTotals[scopeKey(&metrics[i])] = &metrics[i]
+		}
+	}
+	return batch
+}
+
+// ScopeTotal returns the organization/enterprise level metrics of the day and
+// scope of a team's metrics, or nil if they are not part of the batch
+func (b *MetricsBatch) ScopeTotal(m *models.Metrics) *models.Metrics {
+	return b.scopeTotals[scopeKey(m)]
+}
+
+// scopeKey identifies the day and scope of metrics
+func scopeKey(m *models.Metrics) string {
+	return m.Date + "/" + m.Enterprise + "/" + m.Organization
+}
+
+// Rule is a data-quality check. Exactly one of CheckMetrics and CheckSeats is
+// set; checks return one message per violation.
+type Rule struct {
+	Name         string
+	Kind         string
+	Severity     Severity
+	Description  string
+	CheckMetrics func(m *models.Metrics, batch *MetricsBatch) []string
+	CheckSeats   func(s *models.CopilotAssignedSeats) []string
+}
+
+// Rules lists the built-in rules with their default severity
+var Rules = []Rule{
+	{
+		Name:         "negative_count",
+		Kind:         KindMetrics,
+		Severity:     SeverityError,
+		Description:  "A count is negative",
+		CheckMetrics: checkNegativeCounts,
+	},
+	{
+		Name:         "acceptances_exceed_suggestions",
+		Kind:         KindMetrics,
+		Severity:     SeverityError,
+		Description:  "Code acceptances or accepted lines exceed suggestions or suggested lines",
+		CheckMetrics: checkAcceptancesExceedSuggestions,
+	},
+	{
+		Name:         "engaged_exceed_active",
+		Kind:         KindMetrics,
+		Severity:     SeverityWarning,
+		Description:  "Engaged users exceed active users",
+		CheckMetrics: checkEngagedExceedActive,
+	},
+	{
+		Name:         "surface_exceeds_total",
+		Kind:         KindMetrics,
+		Severity:     SeverityWarning,
+		Description:  "Engaged users of a surface exceed the total engaged users",
+		CheckMetrics: checkSurfaceExceedsTotal,
+	},
+	{
+		Name:         "team_exceeds_org",
+		Kind:         KindMetrics,
+		Severity:     SeverityWarning,
+		Description:  "Active or engaged users of a team exceed those of its organization/enterprise",
+		CheckMetrics: checkTeamExceedsOrg,
+	},
+	{
+		Name:        "seat_count_mismatch",
+		Kind:        KindSeats,
+		Severity:    SeverityWarning,
+		Description: "The reported total seats differ from the number of seats listed",
+		CheckSeats:  checkSeatCountMismatch,
+	},
+	{
+		Name:        "duplicate_seat",
+		Kind:        KindSeats,
+		Severity:    SeverityError,
+		Description: "A user holds more than one seat in the same organization",
+		CheckSeats:  checkDuplicateSeats,
+	},
+	{
+		Name:        "missing_assignee",
+		Kind:        KindSeats,
+		Severity:    SeverityError,
+		Description: "A seat has no assignee login",
+		CheckSeats:  checkMissingAssignee,
+	},
+}
+
+// checkNegativeCounts reports every negative integer of the metrics
+func checkNegativeCounts(m *models.Metrics, batch *MetricsBatch) []string {
+	var messages []string
+	walkInts(reflect.ValueOf(m).Elem(), "", func(path string, value int64) {
+		if value < 0 {
+			messages = append(messages, fmt.Sprintf("%s is %d", path, value))
+		}
+	})
+	return messages
+}
+
+// walkInts calls visit for every integer field of a value with its JSON path
+func walkInts(v reflect.Value, path string, visit func(path string, value int64)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkInts(v.Elem(), path, visit)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkInts(v.Index(i), fmt.Sprintf("%s[%d]", path, i), visit)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" || name == "" {
+				continue
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			walkInts(v.Field(i), fieldPath, visit)
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		visit(path, v.Int())
+	}
+}
+
+// checkAcceptancesExceedSuggestions compares acceptances with suggestions for
+// every editor, model and language of the code completions
+func checkAcceptancesExceedSuggestions(m *models.Metrics, batch *MetricsBatch) []string {
+	if m.CopilotIdeCodeCompletions == nil {
+		return nil
+	}
+
+	var messages []string
+	for _, editor := range m.CopilotIdeCodeCompletions.Editors {
+		for _, model := range editor.Models {
+			for _, language := range model.Languages {
+				if language.TotalCodeAcceptances > language.TotalCodeSuggestions {
+					messages = append(messages, fmt.Sprintf("%s/%s/%s: %d acceptances for %d suggestions",
+						editor.Name, model.Name, language.Name, language.TotalCodeAcceptances, language.TotalCodeSuggestions))
+				}
+				if language.TotalCodeLinesAccepted > language.TotalCodeLinesSuggested {
+					messages = append(messages, fmt.Sprintf("%s/%s/%s: %d lines accepted for %d lines suggested",
+						editor.Name, model.Name, language.Name, language.TotalCodeLinesAccepted, language.TotalCodeLinesSuggested))
+				}
+			}
+		}
+	}
+	return messages
+}
+
+// checkEngagedExceedActive compares the total engaged and active users
+func checkEngagedExceedActive(m *models.Metrics, batch *MetricsBatch) []string {
+	if m.TotalEngagedUsers > m.TotalActiveUsers {
+		return []string{fmt.Sprintf("%d engaged users for %d active users", m.TotalEngagedUsers, m.TotalActiveUsers)}
+	}
+	return nil
+}
+
+// checkSurfaceExceedsTotal compares the engaged users of each surface with the total
+func checkSurfaceExceedsTotal(m *models.Metrics, batch *MetricsBatch) []string {
+	surfaces := map[string]int{}
+	if m.CopilotIdeCodeCompletions != nil {
+		surfaces["copilot_ide_code_completions"] = m.CopilotIdeCodeCompletions.TotalEngagedUsers
+	}
+	if m.IdeChat != nil {
+		surfaces["copilot_ide_chat"] = m.IdeChat.TotalEngagedUsers
+	}
+	if m.DotComChat != nil {
+		surfaces["copilot_dotcom_chat"] = m.DotComChat.TotalEngagedUsers
+	}
+	if m.DotComPullRequests != nil {
+		surfaces["copilot_dotcom_pull_requests"] = m.DotComPullRequests.TotalEngagedUsers
+	}
+
+	var messages []string
+	for _, surface := range []string{"copilot_ide_code_completions", "copilot_ide_chat", "copilot_dotcom_chat", "copilot_dotcom_pull_requests"} {
+		if engaged, exists := surfaces[surface]; exists && engaged > m.TotalEngagedUsers {
+			messages = append(messages, fmt.Sprintf("%s has %d engaged users, total is %d", surface, engaged, m.TotalEngagedUsers))
+		}
+	}
+	return messages
+}
+
+// checkTeamExceedsOrg compares a team's users with its organization/enterprise
+func checkTeamExceedsOrg(m *models.Metrics, batch *MetricsBatch) []string {
+	if m.Team == "" {
+		return nil
+	}
+	total := batch.ScopeTotal(m)
+	if total == nil {
+		return nil
+	}
+
+	var messages []string
+	if m.TotalActiveUsers > total.TotalActiveUsers {
+		messages = append(messages, fmt.Sprintf("%d active users, organization/enterprise has %d", m.TotalActiveUsers, total.TotalActiveUsers))
+	}
+	if m.TotalEngagedUsers > total.TotalEngagedUsers {
+		messages = append(messages, fmt.Sprintf("%d engaged users, organization/enterprise has %d", m.TotalEngagedUsers, total.TotalEngagedUsers))
+	}
+	return messages
+}
+
+// checkSeatCountMismatch compares the reported and listed seats
+func checkSeatCountMismatch(s *models.CopilotAssignedSeats) []string {
+	if s.TotalSeats != len(s.Seats) {
+		return []string{fmt.Sprintf("total_seats is %d but %d seats are listed", s.TotalSeats, len(s.Seats))}
+	}
+	return nil
+}
+
+// checkDuplicateSeats reports users listed more than once per organization
+func checkDuplicateSeats(s *models.CopilotAssignedSeats) []string {
+	seen := make(map[string]int)
+	var messages []string
+	for i := range s.Seats {
+		seat := &s.Seats[i]
+		if seat.Assignee.Login == "" {
+			continue
+		}
+		key := analysis.SeatOrganization(seat, s) + "/" + seat.Assignee.Login
+		seen[key]++
+		if seen[key] == 2 {
+			messages = append(messages, fmt.Sprintf("%s holds more than one seat", key))
+		}
+	}
+	return messages
+}
+
+// checkMissingAssignee reports seats without an assignee login
+func checkMissingAssignee(s *models.CopilotAssignedSeats) []string {
+	var messages []string
+	for i := range s.Seats {
+		if s.Seats[i].Assignee.Login == "" {
+			messages = append(messages, fmt.Sprintf("seat %d has no assignee login", i))
+		}
+	}
+	return messages
+}
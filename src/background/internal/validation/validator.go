@@ -0,0 +1,134 @@
+package validation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cardonator/copilot-metrics-dashboard/internal/models"
+)
+
+// Options configures a validator
+type Options struct {
+	DisabledRules map[string]bool     // Rules that are not run
+	Severities    map[string]Severity // Severity overrides by rule name
+	// QuarantineSeverity holds back documents with a violation of at least
+	// this severity; empty disables quarantine
+	QuarantineSeverity Severity
+}
+
+// Validator runs the data-quality rules over ingested documents
+type Validator struct {
+	rules              []Rule
+	quarantineSeverity Severity
+}
+
+// NewValidator creates a validator with the enabled built-in rules
+func NewValidator(opts Options) *Validator {
+	validator := &Validator{quarantineSeverity: opts.QuarantineSeverity}
+	for _, rule := range Rules {
+		if opts.DisabledRules[rule.Name] {
+			continue
+		}
+		if severity, exists := opts.Severities[rule.Name]; exists {
+			rule.Severity = severity
+		}
+		validator.rules = append(validator.rules, rule)
+	}
+	return validator
+}
+
+// Result holds the outcome of validating a batch of documents
+type Result struct {
+	RunID       string
+	Violations  []models.Violation
+	Quarantined map[string][]string // Rules violated by each quarantined document, by document ID
+}
+
+// IsQuarantined reports whether a document is held back
+func (r *Result) IsQuarantined(documentID string) bool {
+	_, exists := r.Quarantined[documentID]
+	return exists
+}
+
+// newResult starts the result of a validation run
+func newResult(kind string, now time.Time) *Result {
+	return &Result{
+		RunID:       fmt.Sprintf("%s-%s", now.Format("20060102T150405.000Z"), kind),
+		Quarantined: make(map[string][]string),
+	}
+}
+
+// record adds the violations of a rule for a document
+func (v *Validator) record(result *Result, rule Rule, violation models.Violation, messages []string) {
+	quarantined := v.quarantineSeverity != "" && rule.Severity.AtLeast(v.quarantineSeverity)
+	for i, message := range messages {
+		entry := violation
+		entry.RunID = result.RunID
+		entry.Rule = rule.Name
+		entry.Severity = string(rule.Severity)
+		entry.Message = message
+		entry.Quarantined = quarantined
+		entry.ID = entry.GetID(i)
+		result.Violations = append(result.Violations, entry)
+	}
+	if quarantined && len(messages) > 0 {
+		result.Quarantined[violation.DocumentID] = append(result.Quarantined[violation.DocumentID], rule.Name)
+	}
+}
+
+// ValidateMetrics runs the metrics rules over a fetched batch of metrics
+func (v *Validator) ValidateMetrics(metrics []models.Metrics) *Result {
+	now := time.Now().UTC()
+	result := newResult(KindMetrics, now)
+	batch := newMetricsBatch(metrics)
+
+	for i := range metrics {
+		m := &metrics[i]
+		if m.ID == "" {
+			m.ID = m.GetID()
+		}
+
+		violation := models.Violation{
+			Date:         m.Date,
+			DocumentKind: KindMetrics,
+			DocumentID:   m.ID,
+			Enterprise:   m.Enterprise,
+			Organization: m.Organization,
+			Team:         m.Team,
+			ObservedAt:   now,
+		}
+		for _, rule := range v.rules {
+			if rule.CheckMetrics != nil {
+				v.record(result, rule, violation, rule.CheckMetrics(m, batch))
+			}
+		}
+	}
+
+	return result
+}
+
+// ValidateSeats runs the seats rules over a seats snapshot
+func (v *Validator) ValidateSeats(seats *models.CopilotAssignedSeats) *Result {
+	now := time.Now().UTC()
+	result := newResult(KindSeats, now)
+
+	if seats.ID == "" {
+		seats.ID = seats.GetID()
+	}
+
+	violation := models.Violation{
+		Date:         seats.Date,
+		DocumentKind: KindSeats,
+		DocumentID:   seats.ID,
+		Enterprise:   seats.Enterprise,
+		Organization: seats.Organization,
+		ObservedAt:   now,
+	}
+	for _, rule := range v.rules {
+		if rule.CheckSeats != nil {
+			v.record(result, rule, violation, rule.CheckSeats(seats))
+		}
+	}
+
+	return result
+}
@@ -9,7 +9,7 @@ import {
 import { ServerActionResponse } from "@/features/common/server-action-response";
 import { SqlQuerySpec } from "@azure/cosmos";
 import { format } from "date-fns";
-import { cosmosClient, cosmosConfiguration, getDatabaseType } from "./cosmos-db-service";
+import { cosmosClient, cosmosConfiguration, cosmosContainerName, getDatabaseType } from "./cosmos-db-service";
 import { ensureGitHubEnvConfig } from "./env-service";
 import { stringIsNullOrEmpty, applyTimeFrameLabel } from "../utils/helpers";
 import { sampleData } from "./sample-data";
@@ -182,7 +182,7 @@ export const getCopilotMetricsFromDatabase = async (
 ): Promise<ServerActionResponse<CopilotUsageOutput[]>> => {
   const client = cosmosClient();
   const database = client.database("platform-engineering");
-  const container = database.container("metrics_history");
+  const container = database.container(cosmosContainerName("metrics_history"));
 
   let start = "";
   let end = "";
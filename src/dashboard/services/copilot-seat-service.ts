@@ -15,7 +15,7 @@ import {
   CopilotSeatManagementData,
   CopilotSeatsWithRaw,
 } from "@/features/common/models";
-import { cosmosClient, cosmosConfiguration } from "./cosmos-db-service";
+import { cosmosClient, cosmosConfiguration, cosmosContainerName } from "./cosmos-db-service";
 import { format } from "date-fns";
 import { SqlQuerySpec } from "@azure/cosmos";
 import { queryDb } from "./sqlite-db-service";
@@ -145,7 +145,7 @@ const getCopilotSeatsFromDatabase = async (
 ): Promise<ServerActionResponse<CopilotSeatsData>> => {
   const client = cosmosClient();
   const database = client.database("platform-engineering");
-  const container = database.container("seats_history");
+  const container = database.container(cosmosContainerName("seats_history"));
 
   let date = "";
   const maxDays = 365 * 2; // maximum 2 years of data
@@ -14,6 +14,18 @@ export const cosmosClient = () => {
   return new CosmosClient({ endpoint, aadCredentials: credential });
 };
 
+// Returns the name of a container as mapped by COSMOS_CONTAINER_NAMES, e.g.
+// "metrics_history=metrics_history_v2", like the ingestion service does
+export const cosmosContainerName = (name: string): string => {
+  for (const entry of (process.env.COSMOS_CONTAINER_NAMES || "").split(",")) {
+    const [container, mapped] = entry.split("=").map((part) => part.trim());
+    if (container === name && !stringIsNullOrEmpty(mapped)) {
+      return mapped;
+    }
+  }
+  return name;
+};
+
 export const cosmosConfiguration = (): boolean => {
   const endpoint = process.env.AZURE_COSMOSDB_ENDPOINT;
 